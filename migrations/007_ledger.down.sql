@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS postings_balanced ON postings;
+DROP FUNCTION IF EXISTS check_journal_entry_balanced();
+DROP TABLE IF EXISTS postings;
+DROP TABLE IF EXISTS journal_entries;
+ALTER TABLE transactions DROP COLUMN IF EXISTS type;
+DROP TABLE IF EXISTS system_accounts;
@@ -0,0 +1,81 @@
+CREATE TABLE system_accounts (
+    code VARCHAR(50) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    balance DECIMAL(15,2) DEFAULT 0.00,
+    currency VARCHAR(3) DEFAULT 'RUB'
+);
+
+INSERT INTO system_accounts (code, name) VALUES
+    ('cash_in', 'Поступления наличных'),
+    ('bank_revenue', 'Доходы банка'),
+    ('penalties', 'Штрафы и пени'),
+    ('credit_disbursement', 'Выдача кредитов');
+
+ALTER TABLE transactions ALTER COLUMN to_account_id DROP NOT NULL;
+ALTER TABLE transactions ADD COLUMN type VARCHAR(30) NOT NULL DEFAULT 'transfer';
+
+CREATE TABLE journal_entries (
+    id SERIAL PRIMARY KEY,
+    transaction_id INTEGER REFERENCES transactions(id),
+    kind VARCHAR(30) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE postings (
+    id SERIAL PRIMARY KEY,
+    entry_id INTEGER REFERENCES journal_entries(id) ON DELETE CASCADE NOT NULL,
+    account_id INTEGER REFERENCES accounts(id),
+    system_account VARCHAR(50) REFERENCES system_accounts(code),
+    direction VARCHAR(6) NOT NULL CHECK (direction IN ('debit', 'credit')),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    currency VARCHAR(3) NOT NULL DEFAULT 'RUB',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((account_id IS NULL) <> (system_account IS NULL))
+);
+
+CREATE INDEX idx_journal_entries_transaction ON journal_entries(transaction_id);
+CREATE INDEX idx_postings_entry ON postings(entry_id);
+CREATE INDEX idx_postings_account ON postings(account_id);
+CREATE INDEX idx_postings_system_account ON postings(system_account);
+
+-- Проводка должна быть сбалансирована по каждой валюте к моменту коммита
+CREATE FUNCTION check_journal_entry_balanced() RETURNS trigger AS $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM postings
+        WHERE entry_id = NEW.entry_id
+        GROUP BY currency
+        HAVING SUM(CASE WHEN direction = 'credit' THEN amount ELSE -amount END) <> 0
+    ) THEN
+        RAISE EXCEPTION 'journal entry % is not balanced', NEW.entry_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER postings_balanced
+    AFTER INSERT ON postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION check_journal_entry_balanced();
+
+-- Входящие остатки: существующие балансы объясняем вводной проводкой
+DO $$
+DECLARE
+    acc RECORD;
+    entry_id INTEGER;
+BEGIN
+    FOR acc IN SELECT id, balance, currency FROM accounts WHERE balance > 0 LOOP
+        INSERT INTO journal_entries (kind, description)
+        VALUES ('opening_balance', 'Входящий остаток по счету ' || acc.id)
+        RETURNING id INTO entry_id;
+
+        INSERT INTO postings (entry_id, system_account, direction, amount, currency)
+        VALUES (entry_id, 'cash_in', 'debit', acc.balance, acc.currency);
+        INSERT INTO postings (entry_id, account_id, direction, amount, currency)
+        VALUES (entry_id, acc.id, 'credit', acc.balance, acc.currency);
+
+        UPDATE system_accounts SET balance = balance - acc.balance WHERE code = 'cash_in';
+    END LOOP;
+END;
+$$;
@@ -0,0 +1 @@
+ALTER TABLE credits ADD COLUMN disbursement_transaction_id INTEGER REFERENCES transactions(id);
@@ -0,0 +1 @@
+ALTER TABLE credits DROP COLUMN IF EXISTS outstanding_principal;
@@ -0,0 +1,3 @@
+ALTER TABLE credits ADD COLUMN outstanding_principal DECIMAL(15,2);
+UPDATE credits SET outstanding_principal = amount;
+ALTER TABLE credits ALTER COLUMN outstanding_principal SET NOT NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE payment_schedules DROP COLUMN IF EXISTS remaining_balance;
+ALTER TABLE payment_schedules DROP COLUMN IF EXISTS interest;
+ALTER TABLE payment_schedules DROP COLUMN IF EXISTS principal;
+ALTER TABLE credits DROP COLUMN IF EXISTS schedule_type;
@@ -0,0 +1,6 @@
+ALTER TABLE credits ADD COLUMN schedule_type VARCHAR(20) NOT NULL DEFAULT 'annuity'
+    CHECK (schedule_type IN ('annuity', 'differentiated'));
+
+ALTER TABLE payment_schedules ADD COLUMN principal DECIMAL(15,2) NOT NULL DEFAULT 0;
+ALTER TABLE payment_schedules ADD COLUMN interest DECIMAL(15,2) NOT NULL DEFAULT 0;
+ALTER TABLE payment_schedules ADD COLUMN remaining_balance DECIMAL(15,2) NOT NULL DEFAULT 0;
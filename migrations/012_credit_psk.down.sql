@@ -0,0 +1,4 @@
+DELETE FROM system_accounts WHERE code = 'insurance';
+ALTER TABLE credits DROP COLUMN IF EXISTS psk;
+ALTER TABLE credits DROP COLUMN IF EXISTS insurance;
+ALTER TABLE credits DROP COLUMN IF EXISTS issue_fee;
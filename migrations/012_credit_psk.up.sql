@@ -0,0 +1,5 @@
+ALTER TABLE credits ADD COLUMN issue_fee DECIMAL(15,2) NOT NULL DEFAULT 0;
+ALTER TABLE credits ADD COLUMN insurance DECIMAL(15,2) NOT NULL DEFAULT 0;
+ALTER TABLE credits ADD COLUMN psk DECIMAL(7,3);
+
+INSERT INTO system_accounts (code, name) VALUES ('insurance', 'Страховые премии к перечислению');
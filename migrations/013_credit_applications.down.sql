@@ -0,0 +1,2 @@
+ALTER TABLE payment_schedules DROP COLUMN IF EXISTS paid_at;
+DROP TABLE IF EXISTS credit_applications;
@@ -0,0 +1,23 @@
+CREATE TABLE credit_applications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) NOT NULL,
+    account_id INTEGER REFERENCES accounts(id) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    rate DECIMAL(5,2) NOT NULL,
+    period INTEGER NOT NULL,
+    schedule_type VARCHAR(20) NOT NULL,
+    issue_fee DECIMAL(15,2) NOT NULL DEFAULT 0,
+    insurance DECIMAL(15,2) NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('submitted', 'scoring', 'approved', 'rejected', 'signed', 'active')),
+    score INTEGER,
+    reject_reasons TEXT[] NOT NULL DEFAULT '{}',
+    credit_id INTEGER REFERENCES credits(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_credit_applications_user_id ON credit_applications(user_id);
+
+-- Дата фактической оплаты нужна для истории просрочек при скоринге
+ALTER TABLE payment_schedules ADD COLUMN paid_at TIMESTAMP;
+UPDATE payment_schedules SET paid_at = due_date WHERE paid;
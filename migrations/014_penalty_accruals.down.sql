@@ -0,0 +1,4 @@
+ALTER TABLE payment_schedules DROP COLUMN IF EXISTS penalty_paid;
+ALTER TABLE payment_schedules DROP COLUMN IF EXISTS interest_paid;
+ALTER TABLE payment_schedules DROP COLUMN IF EXISTS principal_paid;
+DROP TABLE IF EXISTS penalty_accruals;
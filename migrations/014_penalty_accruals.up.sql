@@ -0,0 +1,17 @@
+CREATE TABLE penalty_accruals (
+    id SERIAL PRIMARY KEY,
+    schedule_id INTEGER REFERENCES payment_schedules(id) ON DELETE CASCADE NOT NULL,
+    accrual_date DATE NOT NULL,
+    base_amount DECIMAL(15,2) NOT NULL CHECK (base_amount > 0),
+    rate DECIMAL(7,4) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (schedule_id, accrual_date)
+);
+
+-- Частичное погашение строки графика: неустойка, проценты, основной долг
+ALTER TABLE payment_schedules ADD COLUMN principal_paid DECIMAL(15,2) NOT NULL DEFAULT 0;
+ALTER TABLE payment_schedules ADD COLUMN interest_paid DECIMAL(15,2) NOT NULL DEFAULT 0;
+ALTER TABLE payment_schedules ADD COLUMN penalty_paid DECIMAL(15,2) NOT NULL DEFAULT 0;
+
+UPDATE payment_schedules SET principal_paid = principal, interest_paid = interest WHERE paid;
@@ -0,0 +1,12 @@
+ALTER TABLE transactions DROP COLUMN IF EXISTS exchange_rate;
+ALTER TABLE transactions DROP COLUMN IF EXISTS to_currency;
+ALTER TABLE transactions DROP COLUMN IF EXISTS to_amount;
+
+DROP TABLE IF EXISTS exchange_rates;
+
+ALTER TABLE postings DROP CONSTRAINT IF EXISTS postings_system_account_fkey;
+DELETE FROM system_accounts WHERE currency <> 'RUB' OR code = 'fx_position';
+ALTER TABLE system_accounts DROP CONSTRAINT system_accounts_pkey;
+ALTER TABLE system_accounts ADD PRIMARY KEY (code);
+ALTER TABLE postings ADD CONSTRAINT postings_system_account_fkey
+    FOREIGN KEY (system_account) REFERENCES system_accounts(code);
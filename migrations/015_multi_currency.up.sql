@@ -0,0 +1,33 @@
+-- Системные счета ведутся отдельно по каждой валюте
+ALTER TABLE postings DROP CONSTRAINT IF EXISTS postings_system_account_fkey;
+ALTER TABLE system_accounts DROP CONSTRAINT system_accounts_pkey;
+ALTER TABLE system_accounts ALTER COLUMN currency SET NOT NULL;
+ALTER TABLE system_accounts ADD PRIMARY KEY (code, currency);
+ALTER TABLE postings ADD CONSTRAINT postings_system_account_fkey
+    FOREIGN KEY (system_account, currency) REFERENCES system_accounts(code, currency);
+
+INSERT INTO system_accounts (code, name, currency)
+SELECT 'cash_in', 'Поступления наличных', c
+FROM unnest(ARRAY['USD', 'EUR', 'CNY', 'GBP', 'CHF', 'KZT']) AS c;
+
+-- Валютная позиция банка: через нее проходят переводы между счетами в разных валютах
+INSERT INTO system_accounts (code, name, currency)
+SELECT 'fx_position', 'Валютная позиция', c
+FROM unnest(ARRAY['RUB', 'USD', 'EUR', 'CNY', 'GBP', 'CHF', 'KZT']) AS c;
+
+-- Официальные курсы ЦБ: стоимость nominal единиц валюты в рублях
+CREATE TABLE exchange_rates (
+    currency VARCHAR(3) NOT NULL,
+    rate_date DATE NOT NULL,
+    nominal INTEGER NOT NULL CHECK (nominal > 0),
+    value DECIMAL(15,4) NOT NULL CHECK (value > 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (currency, rate_date)
+);
+
+-- Сумма зачисления и курс для переводов между валютами
+ALTER TABLE transactions ADD COLUMN to_amount DECIMAL(15,2);
+ALTER TABLE transactions ADD COLUMN to_currency VARCHAR(3);
+ALTER TABLE transactions ADD COLUMN exchange_rate DECIMAL(20,6);
+
+UPDATE transactions SET to_amount = amount, to_currency = currency WHERE to_account_id IS NOT NULL;
@@ -0,0 +1,19 @@
+CREATE TABLE fx_quotes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id INTEGER REFERENCES users(id) NOT NULL,
+    from_account_id INTEGER REFERENCES accounts(id) NOT NULL,
+    to_account_id INTEGER REFERENCES accounts(id) NOT NULL,
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency VARCHAR(3) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    to_amount DECIMAL(15,2) NOT NULL CHECK (to_amount > 0),
+    cbr_rate DECIMAL(20,6) NOT NULL,
+    rate DECIMAL(20,6) NOT NULL,
+    spread DECIMAL(5,2) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    transaction_id INTEGER REFERENCES transactions(id),
+    executed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_fx_quotes_user_id ON fx_quotes(user_id);
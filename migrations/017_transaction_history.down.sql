@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_transactions_to_id;
+DROP INDEX IF EXISTS idx_transactions_from_id;
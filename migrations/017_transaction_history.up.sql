@@ -0,0 +1,3 @@
+-- Выборка истории по счету с курсором по id
+CREATE INDEX idx_transactions_from_id ON transactions(from_account_id, id);
+CREATE INDEX idx_transactions_to_id ON transactions(to_account_id, id);
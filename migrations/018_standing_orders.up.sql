@@ -0,0 +1,36 @@
+CREATE TABLE standing_orders (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) NOT NULL,
+    from_account_id INTEGER REFERENCES accounts(id) NOT NULL,
+    to_account_id INTEGER REFERENCES accounts(id) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    frequency VARCHAR(10) NOT NULL CHECK (frequency IN ('once', 'weekly', 'monthly')),
+    day_of_month INTEGER CHECK (day_of_month BETWEEN 1 AND 31),
+    start_date DATE NOT NULL,
+    end_date DATE,
+    max_runs INTEGER CHECK (max_runs > 0),
+    runs_count INTEGER NOT NULL DEFAULT 0,
+    -- Текущее исполнение: плановая дата, номер попытки и время следующей попытки
+    next_run_date DATE,
+    attempt INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('active', 'completed', 'cancelled')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_standing_orders_user_id ON standing_orders(user_id);
+CREATE INDEX idx_standing_orders_due ON standing_orders(next_attempt_at) WHERE status = 'active';
+
+CREATE TABLE standing_order_runs (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER REFERENCES standing_orders(id) ON DELETE CASCADE NOT NULL,
+    scheduled_for DATE NOT NULL,
+    attempt INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('succeeded', 'failed')),
+    error TEXT,
+    transaction_id INTEGER REFERENCES transactions(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_standing_order_runs_order_id ON standing_order_runs(order_id);
@@ -0,0 +1,3 @@
+-- Замороженный счет не участвует в переводах
+ALTER TABLE accounts ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'frozen'));
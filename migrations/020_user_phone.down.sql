@@ -0,0 +1,2 @@
+COMMENT ON COLUMN cards.hmac IS NULL;
+ALTER TABLE users DROP COLUMN IF EXISTS phone;
@@ -0,0 +1,6 @@
+-- Телефон в формате E.164 для поиска получателя перевода
+ALTER TABLE users ADD COLUMN phone VARCHAR(16) UNIQUE;
+
+-- cards.hmac теперь считается от номера карты, а не от шифротекста,
+-- чтобы карту можно было найти по номеру без расшифровки всех карт
+COMMENT ON COLUMN cards.hmac IS 'HMAC-SHA256 of the card number (blind index)';
@@ -0,0 +1,4 @@
+ALTER TABLE users DROP COLUMN IF EXISTS role;
+DROP TABLE IF EXISTS transaction_reversals;
+DROP INDEX IF EXISTS idx_transactions_reversal_of;
+ALTER TABLE transactions DROP COLUMN IF EXISTS reversal_of;
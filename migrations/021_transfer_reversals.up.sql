@@ -0,0 +1,15 @@
+-- Компенсирующая операция ссылается на исходный перевод
+ALTER TABLE transactions ADD COLUMN reversal_of INTEGER REFERENCES transactions(id);
+CREATE INDEX idx_transactions_reversal_of ON transactions(reversal_of) WHERE reversal_of IS NOT NULL;
+
+-- Кто и почему выполнил возврат
+CREATE TABLE transaction_reversals (
+    transaction_id INTEGER PRIMARY KEY REFERENCES transactions(id),
+    original_id INTEGER REFERENCES transactions(id) NOT NULL,
+    staff_id INTEGER REFERENCES users(id) NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'customer'
+    CHECK (role IN ('customer', 'staff'));
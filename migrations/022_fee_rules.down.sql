@@ -0,0 +1,3 @@
+DELETE FROM system_accounts WHERE code = 'bank_revenue' AND currency <> 'RUB';
+DROP TABLE IF EXISTS fee_charges;
+DROP TABLE IF EXISTS fee_rules;
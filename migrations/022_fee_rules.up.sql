@@ -0,0 +1,48 @@
+-- Правила комиссий. Суммы правил - в рублях; для операции действует строка
+-- с наибольшим min_volume, не превышающим оборот клиента по операции за месяц.
+CREATE TABLE fee_rules (
+    id SERIAL PRIMARY KEY,
+    operation VARCHAR(30) NOT NULL
+        CHECK (operation IN ('transfer', 'cross_currency_transfer', 'card_issuance', 'early_repayment')),
+    min_volume DECIMAL(15,2) NOT NULL DEFAULT 0,
+    flat DECIMAL(15,2) NOT NULL DEFAULT 0,
+    percent DECIMAL(7,4) NOT NULL DEFAULT 0,
+    min_fee DECIMAL(15,2) NOT NULL DEFAULT 0,
+    max_fee DECIMAL(15,2),                     -- NULL - без ограничения
+    free_count INTEGER NOT NULL DEFAULT 0,     -- бесплатных операций в месяц
+    free_amount DECIMAL(15,2) NOT NULL DEFAULT 0, -- оборот в месяц без комиссии
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (operation, min_volume)
+);
+
+INSERT INTO fee_rules (operation, min_volume, flat, percent, min_fee, max_fee, free_count, free_amount) VALUES
+    ('transfer', 0, 0, 1, 30, 3000, 0, 100000),
+    ('transfer', 1000000, 0, 0.5, 30, 3000, 0, 0),
+    ('cross_currency_transfer', 0, 0, 1, 100, 5000, 0, 0),
+    ('card_issuance', 0, 300, 0, 0, NULL, 1, 0),
+    -- Комиссия за досрочное погашение потребительского кредита запрещена (353-ФЗ),
+    -- правило заведено с нулевыми значениями
+    ('early_repayment', 0, 0, 0, 0, NULL, 0, 0);
+
+-- Каждая тарифицируемая операция, в том числе бесплатная: по этим записям
+-- считаются месячный оборот и использованные бесплатные операции
+CREATE TABLE fee_charges (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) NOT NULL,
+    operation VARCHAR(30) NOT NULL,
+    rule_id INTEGER REFERENCES fee_rules(id),
+    amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL,
+    volume_rub DECIMAL(15,2) NOT NULL DEFAULT 0,
+    fee DECIMAL(15,2) NOT NULL DEFAULT 0,
+    transaction_id INTEGER REFERENCES transactions(id),
+    fee_transaction_id INTEGER REFERENCES transactions(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_fee_charges_user_month ON fee_charges(user_id, operation, created_at);
+
+-- Комиссия списывается в валюте счета клиента
+INSERT INTO system_accounts (code, name, currency)
+SELECT 'bank_revenue', 'Доходы банка', c
+FROM unnest(ARRAY['USD', 'EUR', 'CNY', 'GBP', 'CHF', 'KZT']) AS c;
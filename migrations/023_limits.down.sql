@@ -0,0 +1,4 @@
+ALTER TABLE transactions DROP COLUMN IF EXISTS card_id;
+DROP TABLE IF EXISTS limit_usage;
+DROP TABLE IF EXISTS user_limits;
+DROP TABLE IF EXISTS limit_defaults;
@@ -0,0 +1,43 @@
+-- Максимальные лимиты продукта (в рублях): клиент может только понизить их
+CREATE TABLE limit_defaults (
+    scope VARCHAR(10) PRIMARY KEY CHECK (scope IN ('user', 'card')),
+    single_max DECIMAL(15,2) NOT NULL,
+    daily_max DECIMAL(15,2) NOT NULL,
+    monthly_max DECIMAL(15,2) NOT NULL,
+    daily_count INTEGER NOT NULL
+);
+
+INSERT INTO limit_defaults (scope, single_max, daily_max, monthly_max, daily_count) VALUES
+    ('user', 600000, 1000000, 5000000, 50),
+    ('card', 150000, 300000, 1000000, 30);
+
+-- Лимиты, установленные клиентом: на все операции (card_id IS NULL) или на карту
+CREATE TABLE user_limits (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) NOT NULL,
+    card_id INTEGER REFERENCES cards(id) ON DELETE CASCADE,
+    single_max DECIMAL(15,2) NOT NULL CHECK (single_max >= 0),
+    daily_max DECIMAL(15,2) NOT NULL CHECK (daily_max >= 0),
+    monthly_max DECIMAL(15,2) NOT NULL CHECK (monthly_max >= 0),
+    daily_count INTEGER NOT NULL CHECK (daily_count >= 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_user_limits_user ON user_limits(user_id) WHERE card_id IS NULL;
+CREATE UNIQUE INDEX idx_user_limits_card ON user_limits(user_id, card_id) WHERE card_id IS NOT NULL;
+
+-- Расход лимитов: сумма каждой операции в рублях
+CREATE TABLE limit_usage (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) NOT NULL,
+    card_id INTEGER REFERENCES cards(id) ON DELETE CASCADE,
+    amount_rub DECIMAL(15,2) NOT NULL,
+    transaction_id INTEGER REFERENCES transactions(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_limit_usage_user ON limit_usage(user_id, created_at);
+CREATE INDEX idx_limit_usage_card ON limit_usage(card_id, created_at) WHERE card_id IS NOT NULL;
+
+-- Карта, по которой совершена операция
+ALTER TABLE transactions ADD COLUMN card_id INTEGER REFERENCES cards(id);
@@ -0,0 +1,11 @@
+-- Показ полного номера карты. jti одноразового токена повторного ввода
+-- пароля служит ключом: один токен - один показ.
+CREATE TABLE card_reveals (
+    jti VARCHAR(64) PRIMARY KEY,
+    card_id INTEGER REFERENCES cards(id) ON DELETE CASCADE NOT NULL,
+    user_id INTEGER REFERENCES users(id) NOT NULL,
+    token_expires_at TIMESTAMP NOT NULL,
+    revealed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_card_reveals_card_id ON card_reveals(card_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_cards_key_id;
+ALTER TABLE cards DROP COLUMN IF EXISTS key_id;
+DROP TABLE IF EXISTS pgp_keys;
@@ -0,0 +1,19 @@
+-- Хранилище ключей PGP. Закрытый ключ хранится зашифрованным мастер-ключом
+-- из конфигурации (AES-256-GCM). Новые карты шифруются активным ключом,
+-- старые ключи остаются для расшифровки до перешифрования карт.
+CREATE TABLE pgp_keys (
+    id SERIAL PRIMARY KEY,
+    key_id VARCHAR(16) UNIQUE NOT NULL,
+    sealed_key BYTEA NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'retired')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    retired_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_pgp_keys_active ON pgp_keys(status) WHERE status = 'active';
+
+-- Карты без key_id зашифрованы ключом, сгенерированным при старте процесса,
+-- и расшифрованы быть не могут
+ALTER TABLE cards ADD COLUMN key_id INTEGER REFERENCES pgp_keys(id);
+
+CREATE INDEX idx_cards_key_id ON cards(key_id);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS card_status_events;
+DROP INDEX IF EXISTS idx_cards_expiry;
+ALTER TABLE cards
+    DROP COLUMN IF EXISTS reissued_from,
+    DROP COLUMN IF EXISTS expires_at,
+    DROP COLUMN IF EXISTS status_reason,
+    DROP COLUMN IF EXISTS status;
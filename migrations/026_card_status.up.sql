@@ -0,0 +1,30 @@
+-- Жизненный цикл карты: active -> blocked (временно, можно разблокировать),
+-- lost (заблокирована навсегда), expired (истек срок), closed.
+ALTER TABLE cards
+    ADD COLUMN status VARCHAR(10) NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'blocked', 'lost', 'expired', 'closed')),
+    ADD COLUMN status_reason TEXT,
+    ADD COLUMN expires_at DATE,
+    ADD COLUMN reissued_from INTEGER REFERENCES cards(id);
+
+-- Срок действия хранится только в зашифрованных данных; карты выпускались
+-- на 5 лет и действуют до конца месяца, указанного на карте
+UPDATE cards
+SET expires_at = (date_trunc('month', created_at + INTERVAL '5 years') + INTERVAL '1 month' - INTERVAL '1 day')::date;
+
+ALTER TABLE cards ALTER COLUMN expires_at SET NOT NULL;
+
+CREATE INDEX idx_cards_expiry ON cards(expires_at) WHERE status IN ('active', 'blocked');
+
+-- История смены статусов с причинами
+CREATE TABLE card_status_events (
+    id SERIAL PRIMARY KEY,
+    card_id INTEGER REFERENCES cards(id) NOT NULL,
+    from_status VARCHAR(10) NOT NULL,
+    to_status VARCHAR(10) NOT NULL,
+    reason TEXT NOT NULL,
+    user_id INTEGER REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_card_status_events_card_id ON card_status_events(card_id);
@@ -0,0 +1,6 @@
+DELETE FROM system_accounts WHERE code = 'card_settlement';
+DROP INDEX IF EXISTS idx_limit_usage_hold;
+ALTER TABLE limit_usage DROP COLUMN IF EXISTS hold_id;
+DROP TABLE IF EXISTS card_holds;
+DROP TABLE IF EXISTS acquirers;
+ALTER TABLE accounts DROP COLUMN IF EXISTS hold_amount;
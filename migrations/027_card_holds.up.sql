@@ -0,0 +1,49 @@
+-- Сумма, заблокированная авторизациями по картам. Доступный остаток
+-- счета = balance - hold_amount; balance меняется только проводками.
+ALTER TABLE accounts ADD COLUMN hold_amount DECIMAL(15,2) NOT NULL DEFAULT 0.00 CHECK (hold_amount >= 0);
+
+-- Эквайреры (мерчанты или тестовый эквайер), отправляющие авторизации.
+-- Хранится только SHA-256 ключа API.
+CREATE TABLE acquirers (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    api_key_hash CHAR(64) UNIQUE NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Авторизация по карте: сумма заблокирована на счете до списания
+-- (capture), отмены (void) или истечения срока
+CREATE TABLE card_holds (
+    id SERIAL PRIMARY KEY,
+    acquirer_id INTEGER REFERENCES acquirers(id) NOT NULL,
+    card_id INTEGER REFERENCES cards(id) NOT NULL,
+    account_id INTEGER REFERENCES accounts(id) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    currency VARCHAR(3) NOT NULL,
+    merchant VARCHAR(100),
+    reference VARCHAR(64),
+    auth_code CHAR(6) NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'authorized'
+        CHECK (status IN ('authorized', 'captured', 'voided', 'expired')),
+    captured_amount DECIMAL(15,2),
+    transaction_id INTEGER REFERENCES transactions(id),
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_card_holds_expiry ON card_holds(expires_at) WHERE status = 'authorized';
+CREATE INDEX idx_card_holds_card_id ON card_holds(card_id);
+CREATE UNIQUE INDEX idx_card_holds_reference ON card_holds(acquirer_id, reference) WHERE reference IS NOT NULL;
+
+-- Расход лимитов по авторизации: удаляется при отмене, при списании
+-- уменьшается до списанной суммы
+ALTER TABLE limit_usage ADD COLUMN hold_id INTEGER REFERENCES card_holds(id);
+
+CREATE INDEX idx_limit_usage_hold ON limit_usage(hold_id) WHERE hold_id IS NOT NULL;
+
+-- Расчеты с эквайерами по списанным авторизациям
+INSERT INTO system_accounts (code, name, currency)
+SELECT 'card_settlement', 'Расчеты по картам', c
+FROM unnest(ARRAY['RUB', 'USD', 'EUR', 'CNY', 'GBP', 'CHF', 'KZT']) AS c;
@@ -0,0 +1,4 @@
+ALTER TABLE card_holds DROP COLUMN IF EXISTS reversal_transaction_id;
+ALTER TABLE card_holds DROP CONSTRAINT card_holds_status_check;
+ALTER TABLE card_holds ADD CONSTRAINT card_holds_status_check
+    CHECK (status IN ('authorized', 'captured', 'voided', 'expired'));
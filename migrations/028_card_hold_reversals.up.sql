@@ -0,0 +1,6 @@
+-- Реверсал (ISO 8583 0400) списанной авторизации возвращает деньги на счет
+ALTER TABLE card_holds DROP CONSTRAINT card_holds_status_check;
+ALTER TABLE card_holds ADD CONSTRAINT card_holds_status_check
+    CHECK (status IN ('authorized', 'captured', 'voided', 'expired', 'reversed'));
+
+ALTER TABLE card_holds ADD COLUMN reversal_transaction_id INTEGER REFERENCES transactions(id);
@@ -0,0 +1 @@
+ALTER TABLE credits ALTER COLUMN psk TYPE DECIMAL(7,3);
@@ -0,0 +1,2 @@
+-- ПСК до 9 999 999.999%: при крупных комиссиях значение не помещалось в DECIMAL(7,3)
+ALTER TABLE credits ALTER COLUMN psk TYPE DECIMAL(10,3);
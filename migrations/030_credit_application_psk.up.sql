@@ -0,0 +1,2 @@
+-- ПСК, раскрытая заемщику при подаче заявки
+ALTER TABLE credit_applications ADD COLUMN psk DECIMAL(10,3);
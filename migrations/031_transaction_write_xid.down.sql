@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_transactions_write_xid;
+ALTER TABLE transactions DROP COLUMN IF EXISTS write_xid;
@@ -0,0 +1,6 @@
+-- Транзакция БД, записавшая операцию. Курсор синхронизации истории идет
+-- по (write_xid, id) и выдает только операции завершенных транзакций:
+-- id выделяются при вставке, а не при фиксации, и операция с меньшим id
+-- может стать видимой позже уже выданных клиенту.
+ALTER TABLE transactions ADD COLUMN write_xid xid8 NOT NULL DEFAULT pg_current_xact_id();
+CREATE INDEX idx_transactions_write_xid ON transactions(write_xid, id);
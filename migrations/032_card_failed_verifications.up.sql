@@ -0,0 +1,3 @@
+-- Неудачные проверки реквизитов подряд: после нескольких карта блокируется,
+-- чтобы CVV нельзя было подобрать перебором
+ALTER TABLE cards ADD COLUMN failed_verifications INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,205 @@
+// Тестовый клиент ISO 8583: отправляет сообщения из файлов-фикстур на
+// листенер сервиса и сверяет ответы с ожидаемыми.
+//
+//	go run ./src/cmd/iso8583-replay -addr localhost:8583 \
+//	    -var PAN=4000001234567899 -var EXPIRY=3012 -var CVV=123 fixtures/iso8583/*.json
+//
+// В значениях полей подставляются ${NAME} из -var и встроенные переменные:
+// RUN - шестизначный номер запуска (для уникальных RRN), STAN - номер
+// сообщения, DATETIME (MMDDhhmmss), DATE (MMDD) и TIME (hhmmss).
+package main
+
+import (
+	"bank-service/src/iso8583"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type fixture struct {
+	Name     string    `json:"name"`
+	Messages []message `json:"messages"`
+}
+
+type message struct {
+	MTI    string            `json:"mti"`
+	Fields map[string]string `json:"fields"`
+	Expect struct {
+		MTI    string            `json:"mti"`
+		Fields map[string]string `json:"fields"` // "*" - поле должно присутствовать
+	} `json:"expect"`
+}
+
+type vars map[string]string
+
+func (v vars) String() string { return "" }
+
+func (v vars) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("expected NAME=VALUE, got %q", s)
+	}
+	v[name] = value
+	return nil
+}
+
+func main() {
+	addr := flag.String("addr", "localhost:8583", "ISO 8583 listener address")
+	specPath := flag.String("spec", "", "field spec JSON (default: built-in spec)")
+	timeout := flag.Duration("timeout", 10*time.Second, "response timeout")
+	variables := vars{}
+	flag.Var(variables, "var", "fixture variable NAME=VALUE (repeatable)")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: iso8583-replay [flags] fixture.json...")
+		os.Exit(2)
+	}
+
+	spec := iso8583.DefaultSpec()
+	if *specPath != "" {
+		var err error
+		if spec, err = iso8583.LoadSpec(*specPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	client, err := iso8583.Dial(*addr, spec, *timeout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	r := &replayer{client: client, vars: variables, run: time.Now().Unix() % 1000000}
+	failed := 0
+	for _, path := range flag.Args() {
+		ok, err := r.replayFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			os.Exit(1)
+		}
+		if !ok {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d fixture(s) failed\n", failed)
+		os.Exit(1)
+	}
+	fmt.Println("all fixtures passed")
+}
+
+type replayer struct {
+	client *iso8583.Client
+	vars   vars
+	run    int64
+	stan   int
+}
+
+func (r *replayer) replayFile(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	var f fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return false, err
+	}
+
+	fmt.Printf("== %s (%s)\n", f.Name, path)
+	passed := true
+	for i, m := range f.Messages {
+		req, err := r.build(m)
+		if err != nil {
+			return false, fmt.Errorf("message %d: %w", i+1, err)
+		}
+		resp, err := r.client.Send(req)
+		if err != nil {
+			return false, fmt.Errorf("message %d: %w", i+1, err)
+		}
+
+		mismatches := check(m, resp)
+		status := "ok"
+		if len(mismatches) > 0 {
+			status = "FAIL " + strings.Join(mismatches, "; ")
+			passed = false
+		}
+		fmt.Printf("  %s STAN %s -> %s 39=%s: %s\n", req.MTI, req.Get(11), resp.MTI, resp.Get(39), status)
+	}
+	return passed, nil
+}
+
+func (r *replayer) build(m message) (*iso8583.Message, error) {
+	r.stan++
+	now := time.Now()
+	builtin := map[string]string{
+		"RUN":      fmt.Sprintf("%06d", r.run),
+		"STAN":     fmt.Sprintf("%06d", r.stan),
+		"DATETIME": now.Format("0102150405"),
+		"DATE":     now.Format("0102"),
+		"TIME":     now.Format("150405"),
+	}
+
+	var missing []string
+	expand := func(s string) string {
+		return os.Expand(s, func(name string) string {
+			if v, ok := r.vars[name]; ok {
+				return v
+			}
+			if v, ok := builtin[name]; ok {
+				return v
+			}
+			missing = append(missing, name)
+			return ""
+		})
+	}
+
+	req := iso8583.NewMessage(m.MTI)
+	for key, value := range m.Fields {
+		n, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid field number %q", key)
+		}
+		req.Set(n, expand(value))
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("undefined variables: %s", strings.Join(missing, ", "))
+	}
+	return req, nil
+}
+
+func check(m message, resp *iso8583.Message) []string {
+	var mismatches []string
+	if m.Expect.MTI != "" && resp.MTI != m.Expect.MTI {
+		mismatches = append(mismatches, fmt.Sprintf("MTI %s, expected %s", resp.MTI, m.Expect.MTI))
+	}
+
+	keys := make([]string, 0, len(m.Expect.Fields))
+	for key := range m.Expect.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		n, err := strconv.Atoi(key)
+		if err != nil {
+			mismatches = append(mismatches, fmt.Sprintf("invalid field number %q", key))
+			continue
+		}
+		want := m.Expect.Fields[key]
+		switch {
+		case !resp.Has(n):
+			mismatches = append(mismatches, fmt.Sprintf("field %d missing", n))
+		case want != "*" && resp.Get(n) != want:
+			mismatches = append(mismatches, fmt.Sprintf("field %d = %q, expected %q", n, resp.Get(n), want))
+		}
+	}
+	return mismatches
+}
@@ -0,0 +1,77 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+var ErrSealedDataCorrupted = errors.New("sealed data is corrupted or the master key is wrong")
+
+// Шифрование данных мастер-ключом (AES-256-GCM). aad привязывает
+// шифротекст к записи, например к идентификатору ключа PGP.
+func Seal(masterKey, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// Обратная операция к Seal
+func Open(masterKey, sealed, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrSealedDataCorrupted
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, ErrSealedDataCorrupted
+	}
+	return plaintext, nil
+}
+
+func newGCM(masterKey []byte) (cipher.AEAD, error) {
+	if len(masterKey) != 32 {
+		return nil, errors.New("master key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Сериализация закрытого ключа; подписи идентичностей уже созданы в InitPGP
+func SerializePrivateKey(entity *openpgp.Entity) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := entity.SerializePrivateWithoutSigning(buf, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func ReadPrivateKey(data []byte) (*openpgp.Entity, error) {
+	entity, err := openpgp.ReadEntity(packet.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return nil, err
+	}
+	if entity.PrivateKey == nil {
+		return nil, errors.New("key has no private part")
+	}
+	return entity, nil
+}
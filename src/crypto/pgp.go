@@ -2,6 +2,9 @@ package crypto
 
 import (
 	"bytes"
+	"io"
+	"strings"
+
 	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/ProtonMail/go-crypto/openpgp/armor"
 )
@@ -59,3 +62,22 @@ func EncryptPGP(data string, entity *openpgp.Entity) (string, error) {
 	writer.Close()
 	return buf.String(), nil
 }
+
+// Расшифровка сообщения, зашифрованного EncryptPGP, закрытым ключом entity
+func DecryptPGP(armored string, entity *openpgp.Entity) (string, error) {
+	block, err := armor.Decode(strings.NewReader(armored))
+	if err != nil {
+		return "", err
+	}
+
+	md, err := openpgp.ReadMessage(block.Body, openpgp.EntityList{entity}, nil, nil)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := io.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -1,10 +1,20 @@
 package handlers
 
 import (
+	"bank-service/src/money"
+	"bank-service/src/repositories"
 	"bank-service/src/services"
+	"bank-service/src/statement"
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
@@ -26,8 +36,23 @@ func NewAccountHandler(accountService *services.AccountService, analyticsService
 
 func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(uint)
-	
-	account, err := h.accountService.CreateAccount(userID)
+
+	// Тело запроса необязательно: без валюты открывается рублевый счет
+	var req struct {
+		Currency string `json:"currency"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "invalid request")
+			return
+		}
+	}
+
+	account, err := h.accountService.CreateAccount(userID, strings.ToUpper(req.Currency))
+	if errors.Is(err, services.ErrUnsupportedCurrency) {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		h.logger.WithError(err).Error("failed to create account")
 		respondWithError(w, http.StatusInternalServerError, err.Error())
@@ -68,7 +93,7 @@ func (h *AccountHandler) Deposit(w http.ResponseWriter, r *http.Request) {
     accountID, _ := strconv.ParseUint(vars["accountId"], 10, 64)
     
     var req struct {
-        Amount float64 `json:"amount"`
+        Amount money.Amount `json:"amount"`
     }
     
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -83,13 +108,17 @@ func (h *AccountHandler) Deposit(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    if err := h.accountService.Deposit(uint(accountID), req.Amount); err != nil {
+    transaction, err := h.accountService.Deposit(uint(accountID), req.Amount)
+    if err != nil {
         h.logger.WithError(err).Error("deposit failed")
         respondWithError(w, http.StatusInternalServerError, err.Error())
         return
     }
 
-    respondWithJSON(w, http.StatusOK, map[string]string{"status": "deposit successful"})
+    respondWithJSON(w, http.StatusOK, map[string]interface{}{
+        "status":         "deposit successful",
+        "transaction_id": transaction.ID,
+    })
 }
 
 func (h *AccountHandler) PredictBalance(w http.ResponseWriter, r *http.Request) {
@@ -108,5 +137,169 @@ func (h *AccountHandler) PredictBalance(w http.ResponseWriter, r *http.Request)
         return
     }
     
-    respondWithJSON(w, http.StatusOK, map[string]float64{"predicted_balance": balance})
-}
\ No newline at end of file
+    respondWithJSON(w, http.StatusOK, map[string]money.Amount{"predicted_balance": balance})
+}
+// История операций по счету. Параметры запроса:
+// cursor, limit, order=asc|desc, from/to (RFC3339 или YYYY-MM-DD, to - включительно),
+// direction=in|out, min_amount, max_amount, counterparty (ID счета)
+func (h *AccountHandler) GetTransactions(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseUint(mux.Vars(r)["accountId"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid account ID")
+		return
+	}
+
+	filter, err := parseTransactionFilter(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.AccountID = uint(accountID)
+
+	page, err := h.accountService.GetTransactions(filter, r.URL.Query().Get("cursor"))
+	if errors.Is(err, services.ErrInvalidCursor) {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get transactions")
+		respondWithError(w, http.StatusInternalServerError, "failed to get transactions")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+func parseTransactionFilter(q url.Values) (repositories.TransactionFilter, error) {
+	var f repositories.TransactionFilter
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return f, errors.New("invalid limit")
+		}
+		f.Limit = limit
+	}
+
+	switch q.Get("order") {
+	case "", "desc":
+	case "asc":
+		f.Ascending = true
+	default:
+		return f, errors.New("order must be asc or desc")
+	}
+
+	switch d := q.Get("direction"); d {
+	case "", repositories.DirectionIn, repositories.DirectionOut:
+		f.Direction = d
+	default:
+		return f, errors.New("direction must be in or out")
+	}
+
+	var err error
+	if f.From, err = parseTimeParam(q.Get("from"), false); err != nil {
+		return f, errors.New("invalid from")
+	}
+	if f.To, err = parseTimeParam(q.Get("to"), true); err != nil {
+		return f, errors.New("invalid to")
+	}
+
+	if v := q.Get("min_amount"); v != "" {
+		if f.MinAmount, err = money.Parse(v); err != nil || f.MinAmount < 0 {
+			return f, errors.New("invalid min_amount")
+		}
+	}
+	if v := q.Get("max_amount"); v != "" {
+		if f.MaxAmount, err = money.Parse(v); err != nil || f.MaxAmount < 0 {
+			return f, errors.New("invalid max_amount")
+		}
+	}
+
+	if v := q.Get("counterparty"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return f, errors.New("invalid counterparty")
+		}
+		f.CounterpartyID = uint(id)
+	}
+	return f, nil
+}
+
+// Дата без времени в конце диапазона включает весь день
+func parseTimeParam(v string, endOfRange bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfRange {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// Выписка по счету: from и to - даты (to включительно),
+// format=csv|pdf|camt053
+func (h *AccountHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseUint(mux.Vars(r)["accountId"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid account ID")
+		return
+	}
+
+	q := r.URL.Query()
+	if q.Get("from") == "" || q.Get("to") == "" {
+		respondWithError(w, http.StatusBadRequest, "from and to must be provided")
+		return
+	}
+	from, err := parseTimeParam(q.Get("from"), false)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid from")
+		return
+	}
+	to, err := parseTimeParam(q.Get("to"), true)
+	if err != nil || !from.Before(to) {
+		respondWithError(w, http.StatusBadRequest, "invalid to")
+		return
+	}
+
+	var write func(io.Writer, *statement.Statement) error
+	var contentType, ext string
+	switch q.Get("format") {
+	case "", "csv":
+		write, contentType, ext = statement.WriteCSV, "text/csv; charset=utf-8", "csv"
+	case "pdf":
+		write, contentType, ext = statement.WritePDF, "application/pdf", "pdf"
+	case "camt053":
+		write, contentType, ext = statement.WriteCamt053, "application/xml", "xml"
+	default:
+		respondWithError(w, http.StatusBadRequest, "format must be csv, pdf or camt053")
+		return
+	}
+
+	st, err := h.accountService.GetStatement(uint(accountID), from, to)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to build statement")
+		respondWithError(w, http.StatusInternalServerError, "failed to build statement")
+		return
+	}
+
+	// Документ формируется целиком до отправки, чтобы ошибка не оборвала ответ
+	var buf bytes.Buffer
+	if err := write(&buf, st); err != nil {
+		h.logger.WithError(err).Error("failed to render statement")
+		respondWithError(w, http.StatusInternalServerError, "failed to render statement")
+		return
+	}
+
+	filename := fmt.Sprintf("statement-%d-%s-%s.%s", accountID, st.From.Format("20060102"), st.LastDay().Format("20060102"), ext)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	buf.WriteTo(w)
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"bank-service/src/models"
+	"bank-service/src/money"
+	"bank-service/src/repositories"
+	"bank-service/src/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+type AcquiringHandler struct {
+	acquiringService *services.AcquiringService
+	logger           *logrus.Logger
+}
+
+func NewAcquiringHandler(acquiringService *services.AcquiringService, logger *logrus.Logger) *AcquiringHandler {
+	return &AcquiringHandler{
+		acquiringService: acquiringService,
+		logger:           logger,
+	}
+}
+
+// Регистрация эквайрера сотрудником; ключ API показывается один раз
+func (h *AcquiringHandler) CreateAcquirer(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	acquirer, apiKey, err := h.acquiringService.CreateAcquirer(req.Name)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to create acquirer")
+		respondWithError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"acquirer": acquirer,
+		"api_key":  apiKey,
+	})
+}
+
+// Авторизация платежа по карте. Отказ эмитента - 402 с причиной.
+func (h *AcquiringHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	acquirerID := r.Context().Value("acquirerID").(uint)
+
+	var req services.AuthorizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	if req.CardNumber == "" || req.Expiry == "" || req.CVV == "" {
+		respondWithError(w, http.StatusBadRequest, "card_number, expiry and cvv are required")
+		return
+	}
+	if req.Amount <= 0 {
+		respondWithError(w, http.StatusBadRequest, "amount must be positive")
+		return
+	}
+
+	hold, err := h.acquiringService.Authorize(acquirerID, req)
+	switch {
+	case errors.Is(err, services.ErrCardDataMismatch),
+		errors.Is(err, services.ErrCardExpired),
+		errors.Is(err, services.ErrCardNotActive),
+		errors.Is(err, services.ErrAccountFrozen),
+		errors.Is(err, services.ErrInsufficientFunds),
+		errors.Is(err, services.ErrLimitExceeded):
+		respondWithError(w, http.StatusPaymentRequired, err.Error())
+		return
+	case errors.Is(err, services.ErrHoldCurrencyMismatch):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, repositories.ErrHoldReferenceExists):
+		respondWithError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		h.logger.WithError(err).Error("authorization failed")
+		respondWithError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, hold)
+}
+
+func (h *AcquiringHandler) GetHold(w http.ResponseWriter, r *http.Request) {
+	acquirerID := r.Context().Value("acquirerID").(uint)
+
+	holdID, ok := parseHoldID(w, r)
+	if !ok {
+		return
+	}
+
+	hold, err := h.acquiringService.GetHold(acquirerID, holdID)
+	if errors.Is(err, repositories.ErrHoldNotFound) {
+		respondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization")
+		respondWithError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, hold)
+}
+
+// Списание по авторизации; без amount списывается вся сумма
+func (h *AcquiringHandler) Capture(w http.ResponseWriter, r *http.Request) {
+	acquirerID := r.Context().Value("acquirerID").(uint)
+
+	holdID, ok := parseHoldID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Amount money.Amount `json:"amount"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "invalid request")
+			return
+		}
+	}
+	if req.Amount < 0 {
+		respondWithError(w, http.StatusBadRequest, "amount must not be negative")
+		return
+	}
+
+	hold, err := h.acquiringService.Capture(acquirerID, holdID, req.Amount)
+	h.respondHold(w, hold, err)
+}
+
+func (h *AcquiringHandler) Void(w http.ResponseWriter, r *http.Request) {
+	acquirerID := r.Context().Value("acquirerID").(uint)
+
+	holdID, ok := parseHoldID(w, r)
+	if !ok {
+		return
+	}
+
+	hold, err := h.acquiringService.Void(acquirerID, holdID)
+	h.respondHold(w, hold, err)
+}
+
+func (h *AcquiringHandler) respondHold(w http.ResponseWriter, hold *models.CardHold, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrHoldNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrCaptureExceedsHold):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrHoldNotAuthorized):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		h.logger.WithError(err).Error("authorization update failed")
+		respondWithError(w, http.StatusInternalServerError, "internal error")
+	default:
+		respondWithJSON(w, http.StatusOK, hold)
+	}
+}
+
+func parseHoldID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	holdID, err := strconv.ParseUint(mux.Vars(r)["holdId"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid authorization ID")
+		return 0, false
+	}
+	return uint(holdID), true
+}
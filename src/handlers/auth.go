@@ -28,6 +28,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Username string `json:"username"`
 		Email    string `json:"email"`
+		Phone    string `json:"phone"`
 		Password string `json:"password"`
 	}
 
@@ -40,6 +41,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	user := &models.User{
 		Username:     req.Username,
 		Email:        req.Email,
+		Phone:        req.Phone,
 		PasswordHash: req.Password,
 	}
 
@@ -54,6 +56,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		"id":       user.ID,
 		"username": user.Username,
 		"email":    user.Email,
+		"phone":    user.Phone,
 	})
 }
 
@@ -80,4 +83,31 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{
 		"token": token,
 	})
-}
\ No newline at end of file
+}
+// Повторный ввод пароля перед просмотром реквизитов карты
+func (h *AuthHandler) StepUp(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "invalid user ID in context")
+		return
+	}
+
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	token, expiresAt, err := h.authService.StepUp(userID, req.Password)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "invalid credentials")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"token":      token,
+		"expires_at": expiresAt,
+	})
+}
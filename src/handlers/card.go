@@ -1,21 +1,28 @@
 package handlers
 
 import (
+	"bank-service/src/models"
+	"bank-service/src/repositories"
 	"bank-service/src/services"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 	
+	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
 
 type CardHandler struct {
 	cardService *services.CardService
+	authService *services.AuthService
 	logger      *logrus.Logger
 }
 
-func NewCardHandler(service *services.CardService, logger *logrus.Logger) *CardHandler {
+func NewCardHandler(service *services.CardService, authService *services.AuthService, logger *logrus.Logger) *CardHandler {
 	return &CardHandler{
 		cardService: service,
+		authService: authService,
 		logger:      logger,
 	}
 }
@@ -43,24 +50,33 @@ func (h *CardHandler) CreateCard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(request.CVV) < 3 || len(request.CVV) > 4 {
-		respondWithError(w, http.StatusBadRequest, "CVV must be 3 or 4 digits")
-		return
-	}
-
-	if !isDigits(request.CVV) {
-		respondWithError(w, http.StatusBadRequest, "CVV must contain only digits")
+	if msg := validateCVV(request.CVV); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
 		return
 	}
 	
 	card, err := h.cardService.GenerateCard(userID, request.AccountID, request.CVV)
+	if errors.Is(err, services.ErrInsufficientFunds) {
+		respondWithError(w, http.StatusConflict, "insufficient funds for the card issuance fee")
+		return
+	}
 	if err != nil {
 		h.logger.WithError(err).Error("failed to create card")
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	respondWithJSON(w, http.StatusCreated, card)
+	respondWithJSON(w, http.StatusCreated, h.cardService.Summarize(card))
+}
+
+func validateCVV(cvv string) string {
+	if len(cvv) < 3 || len(cvv) > 4 {
+		return "CVV must be 3 or 4 digits"
+	}
+	if !isDigits(cvv) {
+		return "CVV must contain only digits"
+	}
+	return ""
 }
 
 func isDigits(s string) bool {
@@ -83,4 +99,156 @@ func (h *CardHandler) GetCards(w http.ResponseWriter, r *http.Request) {
 	}
 
 	respondWithJSON(w, http.StatusOK, cards)
-}
\ No newline at end of file
+}
+// Полный номер карты. Требует одноразовый step-up токен в X-Step-Up-Token.
+func (h *CardHandler) RevealCard(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+
+	cardID, ok := parseCardID(w, r)
+	if !ok {
+		return
+	}
+
+	jti, expiresAt, err := h.authService.VerifyStepUp(userID, r.Header.Get("X-Step-Up-Token"))
+	if err != nil {
+		respondWithError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	details, err := h.cardService.RevealCard(userID, cardID, jti, expiresAt)
+	switch {
+	case errors.Is(err, repositories.ErrCardNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, services.ErrStepUpTokenUsed):
+		respondWithError(w, http.StatusForbidden, err.Error())
+		return
+	case errors.Is(err, services.ErrCardKeyMissing):
+		respondWithError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		h.logger.WithError(err).Error("failed to reveal card")
+		respondWithError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, details)
+}
+
+// Блокировка карты: {"reason": "...", "permanent": true} - окончательная
+// блокировка при утере или краже
+func (h *CardHandler) BlockCard(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+
+	cardID, ok := parseCardID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Reason    string `json:"reason"`
+		Permanent bool   `json:"permanent"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	card, err := h.cardService.BlockCard(userID, cardID, req.Reason, req.Permanent)
+	h.respondStatusChange(w, card, err, http.StatusOK)
+}
+
+func (h *CardHandler) UnblockCard(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+
+	cardID, ok := parseCardID(w, r)
+	if !ok {
+		return
+	}
+
+	// Тело запроса необязательно
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "invalid request")
+			return
+		}
+	}
+
+	card, err := h.cardService.UnblockCard(userID, cardID, req.Reason)
+	h.respondStatusChange(w, card, err, http.StatusOK)
+}
+
+// Перевыпуск карты с новым номером; возвращает новую карту
+func (h *CardHandler) ReissueCard(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+
+	cardID, ok := parseCardID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		CVV    string `json:"cvv"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	if msg := validateCVV(req.CVV); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	card, err := h.cardService.ReissueCard(userID, cardID, req.CVV, req.Reason)
+	h.respondStatusChange(w, card, err, http.StatusCreated)
+}
+
+func (h *CardHandler) CloseCard(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+
+	cardID, ok := parseCardID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	card, err := h.cardService.CloseCard(userID, cardID, req.Reason)
+	h.respondStatusChange(w, card, err, http.StatusOK)
+}
+
+func (h *CardHandler) respondStatusChange(w http.ResponseWriter, card *models.Card, err error, status int) {
+	switch {
+	case errors.Is(err, repositories.ErrCardNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrCardReasonRequired):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrCardStatusTransition):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		h.logger.WithError(err).Error("failed to change card status")
+		respondWithError(w, http.StatusInternalServerError, "internal error")
+	default:
+		respondWithJSON(w, status, h.cardService.Summarize(card))
+	}
+}
+
+func parseCardID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	cardID, err := strconv.ParseUint(mux.Vars(r)["cardId"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid card ID")
+		return 0, false
+	}
+	return uint(cardID), true
+}
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"bank-service/src/repositories"
 	"bank-service/src/services"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -22,36 +24,6 @@ func NewCreditHandler(creditService *services.CreditService, logger *logrus.Logg
 	}
 }
 
-func (h *CreditHandler) CreateCredit(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(uint)
-
-	var req struct {
-		AccountID uint    `json:"account_id"`
-		Amount    float64 `json:"amount"`
-		Rate      float64 `json:"rate"`
-		Period    int     `json:"period"` // в месяцах
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "invalid request")
-		return
-	}
-
-	if req.AccountID == 0 || req.Amount <= 0 || req.Rate <= 0 || req.Period <= 0 {
-		respondWithError(w, http.StatusBadRequest, "missing or invalid fields")
-		return
-	}
-
-	credit, err := h.creditService.CreateCredit(userID, req.AccountID, req.Amount, req.Rate, req.Period)
-	if err != nil {
-		h.logger.WithError(err).Error("failed to create credit")
-		respondWithError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	respondWithJSON(w, http.StatusCreated, credit)
-}
-
 func (h *CreditHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(uint)
 	vars := mux.Vars(r)
@@ -96,3 +68,70 @@ func (h *CreditHandler) GetCreditsByAccount(w http.ResponseWriter, r *http.Reque
 
 	respondWithJSON(w, http.StatusOK, credits)
 }
+
+func (h *CreditHandler) MakePayment(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+	vars := mux.Vars(r)
+	creditID, err := strconv.ParseUint(vars["creditId"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid credit ID")
+		return
+	}
+
+	var req services.CreditPayment
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	result, err := h.creditService.MakePayment(userID, uint(creditID), req)
+	switch {
+	case errors.Is(err, repositories.ErrCreditNotFound):
+		respondWithError(w, http.StatusNotFound, "credit not found")
+		return
+	case errors.Is(err, services.ErrInvalidPaymentType),
+		errors.Is(err, services.ErrInvalidPaymentAmount),
+		errors.Is(err, services.ErrInvalidStrategy):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, services.ErrCreditNotActive),
+		errors.Is(err, services.ErrNothingToPay),
+		errors.Is(err, services.ErrOverdueInstallments),
+		errors.Is(err, services.ErrInsufficientFunds):
+		respondWithError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		h.logger.WithError(err).Error("credit payment failed")
+		respondWithError(w, http.StatusInternalServerError, "credit payment failed")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+func (h *CreditHandler) Quote(w http.ResponseWriter, r *http.Request) {
+	// Ставка необязательна: по умолчанию ключевая ставка ЦБ
+	var req services.CreditTerms
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	if req.Amount <= 0 || req.Rate < 0 || req.Period <= 0 {
+		respondWithError(w, http.StatusBadRequest, "missing or invalid fields")
+		return
+	}
+
+	quote, err := h.creditService.Quote(req)
+	switch {
+	case errors.Is(err, services.ErrPSKExceedsCap):
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	case err != nil:
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, quote)
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"bank-service/src/repositories"
+	"bank-service/src/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+type CreditApplicationHandler struct {
+	applicationService *services.CreditApplicationService
+	logger             *logrus.Logger
+}
+
+func NewCreditApplicationHandler(applicationService *services.CreditApplicationService, logger *logrus.Logger) *CreditApplicationHandler {
+	return &CreditApplicationHandler{
+		applicationService: applicationService,
+		logger:             logger,
+	}
+}
+
+// Подача заявки на кредит; в ответе - решение скоринга
+func (h *CreditApplicationHandler) Submit(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+
+	var req struct {
+		AccountID uint `json:"account_id"`
+		services.CreditTerms
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	if req.AccountID == 0 || req.Amount <= 0 || req.Rate <= 0 || req.Period <= 0 {
+		respondWithError(w, http.StatusBadRequest, "missing or invalid fields")
+		return
+	}
+
+	app, err := h.applicationService.Submit(userID, req.AccountID, req.CreditTerms)
+	switch {
+	case errors.Is(err, repositories.ErrAccountNotFound):
+		respondWithError(w, http.StatusForbidden, "access denied")
+		return
+	case errors.Is(err, services.ErrInvalidScheduleType),
+		errors.Is(err, services.ErrInvalidCreditTerms),
+		errors.Is(err, services.ErrCreditCurrency):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, services.ErrPSKExceedsCap):
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	case err != nil:
+		h.logger.WithError(err).Error("failed to submit credit application")
+		respondWithError(w, http.StatusInternalServerError, "failed to submit credit application")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, app)
+}
+
+func (h *CreditApplicationHandler) GetApplication(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+	applicationID, err := strconv.ParseUint(mux.Vars(r)["applicationId"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid application ID")
+		return
+	}
+
+	app, err := h.applicationService.GetApplication(userID, uint(applicationID))
+	if errors.Is(err, repositories.ErrApplicationNotFound) {
+		respondWithError(w, http.StatusNotFound, "application not found")
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get credit application")
+		respondWithError(w, http.StatusInternalServerError, "failed to get application")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, app)
+}
+
+func (h *CreditApplicationHandler) GetApplications(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+
+	apps, err := h.applicationService.GetApplications(userID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get credit applications")
+		respondWithError(w, http.StatusInternalServerError, "failed to get applications")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, apps)
+}
+
+// Подписание одобренной заявки клиентом и выдача кредита
+func (h *CreditApplicationHandler) Sign(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+	applicationID, err := strconv.ParseUint(mux.Vars(r)["applicationId"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid application ID")
+		return
+	}
+
+	credit, err := h.applicationService.Sign(userID, uint(applicationID))
+	switch {
+	case errors.Is(err, repositories.ErrApplicationNotFound):
+		respondWithError(w, http.StatusNotFound, "application not found")
+		return
+	case errors.Is(err, services.ErrApplicationNotApproved):
+		respondWithError(w, http.StatusConflict, err.Error())
+		return
+	case errors.Is(err, services.ErrPSKExceedsCap):
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	case err != nil:
+		h.logger.WithError(err).Error("failed to issue credit")
+		respondWithError(w, http.StatusInternalServerError, "failed to issue credit")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, credit)
+}
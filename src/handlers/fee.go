@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"bank-service/src/money"
+	"bank-service/src/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+type FeeHandler struct {
+	feeService     *services.FeeService
+	accountService *services.AccountService
+	logger         *logrus.Logger
+}
+
+func NewFeeHandler(feeService *services.FeeService, accountService *services.AccountService, logger *logrus.Logger) *FeeHandler {
+	return &FeeHandler{
+		feeService:     feeService,
+		accountService: accountService,
+		logger:         logger,
+	}
+}
+
+// Предварительный расчет комиссии: operation - transfer,
+// cross_currency_transfer, card_issuance или early_repayment;
+// сумма и комиссия - в валюте счета account_id
+func (h *FeeHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+
+	var req struct {
+		Operation string       `json:"operation"`
+		AccountID uint         `json:"account_id"`
+		Amount    money.Amount `json:"amount"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	if req.Operation == "" || req.AccountID == 0 || req.Amount < 0 {
+		respondWithError(w, http.StatusBadRequest, "missing or invalid fields")
+		return
+	}
+
+	account, err := h.accountService.GetByIDAndUser(req.AccountID, userID)
+	if err != nil {
+		respondWithError(w, http.StatusForbidden, "access denied")
+		return
+	}
+
+	fee, err := h.feeService.Preview(userID, req.Operation, req.Amount, account.Currency)
+	switch {
+	case errors.Is(err, services.ErrUnknownFeeOperation):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		h.logger.WithError(err).Error("fee preview failed")
+		respondWithError(w, http.StatusInternalServerError, "fee preview failed")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, fee)
+}
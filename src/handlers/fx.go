@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"bank-service/src/money"
+	"bank-service/src/repositories"
+	"bank-service/src/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+type FXHandler struct {
+	fxService *services.FXService
+	logger    *logrus.Logger
+}
+
+func NewFXHandler(fxService *services.FXService, logger *logrus.Logger) *FXHandler {
+	return &FXHandler{
+		fxService: fxService,
+		logger:    logger,
+	}
+}
+
+func (h *FXHandler) CreateQuote(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+
+	var req struct {
+		FromAccountID uint         `json:"from_account_id"`
+		ToAccountID   uint         `json:"to_account_id"`
+		Amount        money.Amount `json:"amount"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	if req.FromAccountID == 0 || req.ToAccountID == 0 || req.Amount <= 0 {
+		respondWithError(w, http.StatusBadRequest, "missing or invalid fields")
+		return
+	}
+
+	quote, err := h.fxService.Quote(userID, req.FromAccountID, req.ToAccountID, req.Amount)
+	switch {
+	case errors.Is(err, repositories.ErrAccountNotFound):
+		respondWithError(w, http.StatusForbidden, "access denied")
+		return
+	case errors.Is(err, services.ErrSameCurrency),
+		errors.Is(err, services.ErrUnsupportedCurrency),
+		errors.Is(err, services.ErrAmountTooSmall):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		h.logger.WithError(err).Error("failed to create fx quote")
+		respondWithError(w, http.StatusInternalServerError, "failed to create quote")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, quote)
+}
+
+func (h *FXHandler) Exchange(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+
+	var req struct {
+		QuoteID string `json:"quote_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	if req.QuoteID == "" {
+		respondWithError(w, http.StatusBadRequest, "quote_id must be provided")
+		return
+	}
+
+	transaction, err := h.fxService.Exchange(userID, req.QuoteID)
+	switch {
+	case errors.Is(err, repositories.ErrQuoteNotFound):
+		respondWithError(w, http.StatusNotFound, "quote not found")
+		return
+	case errors.Is(err, services.ErrQuoteExpired):
+		respondWithError(w, http.StatusGone, err.Error())
+		return
+	case errors.Is(err, services.ErrQuoteAlreadyUsed),
+		errors.Is(err, services.ErrInsufficientFunds),
+		errors.Is(err, services.ErrAccountFrozen):
+		respondWithError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		h.logger.WithError(err).Error("currency exchange failed")
+		respondWithError(w, http.StatusInternalServerError, "currency exchange failed")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, transaction)
+}
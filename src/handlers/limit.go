@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"bank-service/src/repositories"
+	"bank-service/src/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+type LimitHandler struct {
+	limitService *services.LimitService
+	logger       *logrus.Logger
+}
+
+func NewLimitHandler(limitService *services.LimitService, logger *logrus.Logger) *LimitHandler {
+	return &LimitHandler{
+		limitService: limitService,
+		logger:       logger,
+	}
+}
+
+func (h *LimitHandler) GetLimits(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+
+	limits, err := h.limitService.GetLimits(userID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get limits")
+		respondWithError(w, http.StatusInternalServerError, "failed to get limits")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, limits)
+}
+
+// Изменение общих лимитов клиента или, если указан card_id, лимитов карты
+func (h *LimitHandler) UpdateLimits(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+
+	var req services.LimitsUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	limits, err := h.limitService.UpdateLimits(userID, req)
+	switch {
+	case errors.Is(err, repositories.ErrCardNotFound):
+		respondWithError(w, http.StatusNotFound, "card not found")
+		return
+	case errors.Is(err, services.ErrLimitAboveMaximum):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		h.logger.WithError(err).Error("failed to update limits")
+		respondWithError(w, http.StatusInternalServerError, "failed to update limits")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, limits)
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"bank-service/src/services"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+type PGPKeyHandler struct {
+	keyService  *services.KeyService
+	cardService *services.CardService
+	logger      *logrus.Logger
+}
+
+func NewPGPKeyHandler(keyService *services.KeyService, cardService *services.CardService, logger *logrus.Logger) *PGPKeyHandler {
+	return &PGPKeyHandler{
+		keyService:  keyService,
+		cardService: cardService,
+		logger:      logger,
+	}
+}
+
+// Версии ключа и число карт на каждой
+func (h *PGPKeyHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.keyService.ListKeys()
+	if err != nil {
+		h.logger.WithError(err).Error("failed to list pgp keys")
+		respondWithError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, keys)
+}
+
+// Число незакрытых карт, выпущенных до хранилища ключей: их данные
+// не расшифровать, карты нужно перевыпустить
+func (h *PGPKeyHandler) CardsWithoutKey(w http.ResponseWriter, r *http.Request) {
+	n, err := h.cardService.CountCardsWithoutKey()
+	if err != nil {
+		h.logger.WithError(err).Error("failed to count cards without key")
+		respondWithError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]int{"cards": n})
+}
+
+// Ротация ключа; карты перешифровываются в фоне
+func (h *PGPKeyHandler) Rotate(w http.ResponseWriter, r *http.Request) {
+	key, err := h.keyService.Rotate()
+	if err != nil {
+		h.logger.WithError(err).Error("failed to rotate pgp key")
+		respondWithError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	go func() {
+		migrated, err := h.cardService.ReencryptCards()
+		if err != nil {
+			h.logger.WithError(err).Error("Card re-encryption failed")
+		}
+		h.logger.Infof("Re-encrypted %d cards with key %d", migrated, key.ID)
+	}()
+
+	respondWithJSON(w, http.StatusCreated, key)
+}
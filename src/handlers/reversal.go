@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"bank-service/src/money"
+	"bank-service/src/repositories"
+	"bank-service/src/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+type ReversalHandler struct {
+	reversalService *services.ReversalService
+	logger          *logrus.Logger
+}
+
+func NewReversalHandler(reversalService *services.ReversalService, logger *logrus.Logger) *ReversalHandler {
+	return &ReversalHandler{
+		reversalService: reversalService,
+		logger:          logger,
+	}
+}
+
+// Возврат перевода сотрудником; amount не указан - возврат всего остатка
+func (h *ReversalHandler) Reverse(w http.ResponseWriter, r *http.Request) {
+	staffID := r.Context().Value("userID").(uint)
+
+	transactionID, err := strconv.ParseUint(mux.Vars(r)["transactionId"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid transaction ID")
+		return
+	}
+
+	var req struct {
+		Amount money.Amount `json:"amount"`
+		Reason string       `json:"reason"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Amount < 0 || req.Reason == "" {
+		respondWithError(w, http.StatusBadRequest, "reason must be provided and amount must not be negative")
+		return
+	}
+
+	reversal, err := h.reversalService.Reverse(staffID, uint(transactionID), req.Amount, req.Reason)
+	switch {
+	case errors.Is(err, repositories.ErrTransactionNotFound):
+		respondWithError(w, http.StatusNotFound, "transaction not found")
+		return
+	case errors.Is(err, services.ErrNotReversible),
+		errors.Is(err, services.ErrReversalExceedsAmount),
+		errors.Is(err, services.ErrAmountTooSmall):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, services.ErrAlreadyReversed),
+		errors.Is(err, services.ErrReversalInsufficientFunds),
+		errors.Is(err, services.ErrAccountFrozen):
+		respondWithError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		h.logger.WithError(err).Error("transaction reversal failed")
+		respondWithError(w, http.StatusInternalServerError, "transaction reversal failed")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, reversal)
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"bank-service/src/models"
+	"bank-service/src/money"
+	"bank-service/src/repositories"
+	"bank-service/src/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+type StandingOrderHandler struct {
+	orderService *services.StandingOrderService
+	logger       *logrus.Logger
+}
+
+func NewStandingOrderHandler(orderService *services.StandingOrderService, logger *logrus.Logger) *StandingOrderHandler {
+	return &StandingOrderHandler{
+		orderService: orderService,
+		logger:       logger,
+	}
+}
+
+// Создание регулярного перевода; даты в формате YYYY-MM-DD
+func (h *StandingOrderHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+
+	var req struct {
+		FromAccountID uint         `json:"from_account_id"`
+		ToAccountID   uint         `json:"to_account_id"`
+		Amount        money.Amount `json:"amount"`
+		Frequency     string       `json:"frequency"`
+		DayOfMonth    int          `json:"day_of_month"`
+		StartDate     string       `json:"start_date"`
+		EndDate       string       `json:"end_date"`
+		MaxRuns       int          `json:"max_runs"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	if req.FromAccountID == 0 || req.ToAccountID == 0 || req.Amount <= 0 || req.StartDate == "" {
+		respondWithError(w, http.StatusBadRequest, "missing or invalid fields")
+		return
+	}
+
+	order := &models.StandingOrder{
+		UserID:        userID,
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		Frequency:     req.Frequency,
+		DayOfMonth:    req.DayOfMonth,
+		MaxRuns:       req.MaxRuns,
+	}
+	var err error
+	if order.StartDate, err = time.Parse("2006-01-02", req.StartDate); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid start_date")
+		return
+	}
+	if req.EndDate != "" {
+		endDate, err := time.Parse("2006-01-02", req.EndDate)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "invalid end_date")
+			return
+		}
+		order.EndDate = &endDate
+	}
+
+	err = h.orderService.Create(order)
+	switch {
+	case errors.Is(err, repositories.ErrAccountNotFound):
+		respondWithError(w, http.StatusForbidden, "access denied")
+		return
+	case errors.Is(err, services.ErrInvalidFrequency),
+		errors.Is(err, services.ErrInvalidSchedule):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		h.logger.WithError(err).Error("failed to create standing order")
+		respondWithError(w, http.StatusInternalServerError, "failed to create standing order")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, order)
+}
+
+func (h *StandingOrderHandler) GetOrders(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+
+	orders, err := h.orderService.GetOrders(userID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get standing orders")
+		respondWithError(w, http.StatusInternalServerError, "failed to get standing orders")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, orders)
+}
+
+// Поручение и история его исполнений
+func (h *StandingOrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+
+	orderID, err := strconv.ParseUint(mux.Vars(r)["orderId"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid order ID")
+		return
+	}
+
+	order, runs, err := h.orderService.GetOrder(userID, uint(orderID))
+	switch {
+	case errors.Is(err, repositories.ErrStandingOrderNotFound):
+		respondWithError(w, http.StatusNotFound, "standing order not found")
+		return
+	case err != nil:
+		h.logger.WithError(err).Error("failed to get standing order")
+		respondWithError(w, http.StatusInternalServerError, "failed to get standing order")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"order": order,
+		"runs":  runs,
+	})
+}
+
+func (h *StandingOrderHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+
+	orderID, err := strconv.ParseUint(mux.Vars(r)["orderId"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid order ID")
+		return
+	}
+
+	err = h.orderService.Cancel(userID, uint(orderID))
+	switch {
+	case errors.Is(err, repositories.ErrStandingOrderNotFound):
+		respondWithError(w, http.StatusNotFound, "active standing order not found")
+		return
+	case err != nil:
+		h.logger.WithError(err).Error("failed to cancel standing order")
+		respondWithError(w, http.StatusInternalServerError, "failed to cancel standing order")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": models.StandingOrderCancelled})
+}
@@ -2,31 +2,43 @@ package handlers
 
 import (
     "encoding/json"
+    "errors"
     "net/http"
     
+    "bank-service/src/models"
+    "bank-service/src/money"
+    "bank-service/src/repositories"
     "bank-service/src/services"
     "github.com/sirupsen/logrus"
 )
 
 type TransferHandler struct {
-    accountService *services.AccountService
-    logger         *logrus.Logger
+    accountService   *services.AccountService
+    recipientService *services.RecipientService
+    cardService      *services.CardService
+    logger           *logrus.Logger
 }
 
-func NewTransferHandler(service *services.AccountService, logger *logrus.Logger) *TransferHandler {
+func NewTransferHandler(service *services.AccountService, recipientService *services.RecipientService, cardService *services.CardService, logger *logrus.Logger) *TransferHandler {
     return &TransferHandler{
-        accountService: service,
-        logger:         logger,
+        accountService:   service,
+        recipientService: recipientService,
+        cardService:      cardService,
+        logger:           logger,
     }
 }
 
+// Получатель указывается одним из полей: to_account_id, to_card_number,
+// to_phone или to_email. Вместо from_account_id можно указать from_card_id -
+// тогда списание идет со счета карты и действуют лимиты карты.
 func (h *TransferHandler) Transfer(w http.ResponseWriter, r *http.Request) {
     userID := r.Context().Value("userID").(uint)
     
     var req struct {
-        FromAccountID uint    `json:"from_account_id"`
-        ToAccountID   uint    `json:"to_account_id"`
-        Amount        float64 `json:"amount"`
+        FromAccountID uint         `json:"from_account_id"`
+        FromCardID    uint         `json:"from_card_id"`
+        Amount        money.Amount `json:"amount"`
+        services.RecipientQuery
     }
 
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -35,13 +47,27 @@ func (h *TransferHandler) Transfer(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    if req.FromAccountID == 0 || req.ToAccountID == 0 {
+    if req.FromCardID != 0 {
+        card, err := h.cardService.GetCard(userID, req.FromCardID)
+        if err != nil {
+            respondWithError(w, http.StatusForbidden, "access denied")
+            return
+        }
+        if req.FromAccountID != 0 && req.FromAccountID != card.AccountID {
+            respondWithError(w, http.StatusBadRequest, "from_account_id does not match the card")
+            return
+        }
+        req.FromAccountID = card.AccountID
+    }
+
+    if req.FromAccountID == 0 {
         respondWithError(w, http.StatusBadRequest, "account IDs must be provided")
         return
     }
 
     // Проверка владения счетом
-    if _, err := h.accountService.GetByIDAndUser(req.FromAccountID, userID); err != nil {
+    from, err := h.accountService.GetByIDAndUser(req.FromAccountID, userID)
+    if err != nil {
         respondWithError(w, http.StatusForbidden, "access denied")
         return
     }
@@ -50,12 +76,95 @@ func (h *TransferHandler) Transfer(w http.ResponseWriter, r *http.Request) {
         respondWithError(w, http.StatusBadRequest, "amount must be positive")
         return
     }
+
+    recipient, ok := h.resolve(w, req.RecipientQuery, from.Currency)
+    if !ok {
+        return
+    }
 	
-    if err := h.accountService.Transfer(req.FromAccountID, req.ToAccountID, req.Amount); err != nil {
-        h.logger.WithError(err).Error("transfer failed")
+    var transaction *models.Transaction
+    if req.FromCardID != 0 {
+        transaction, err = h.accountService.TransferByCard(req.FromCardID, req.FromAccountID, recipient.AccountID, req.Amount)
+    } else {
+        transaction, err = h.accountService.Transfer(req.FromAccountID, recipient.AccountID, req.Amount)
+    }
+    switch {
+    case errors.Is(err, repositories.ErrAccountNotFound):
+        respondWithError(w, http.StatusNotFound, "recipient account not found")
+        return
+    case errors.Is(err, services.ErrSameAccount),
+        errors.Is(err, services.ErrUnsupportedCurrency),
+        errors.Is(err, services.ErrAmountTooSmall):
         respondWithError(w, http.StatusBadRequest, err.Error())
         return
+    case errors.Is(err, services.ErrInsufficientFunds),
+        errors.Is(err, services.ErrAccountFrozen),
+        errors.Is(err, services.ErrCardNotActive):
+        respondWithError(w, http.StatusConflict, err.Error())
+        return
+    case errors.Is(err, services.ErrLimitExceeded):
+        respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+        return
+    case err != nil:
+        h.logger.WithError(err).Error("transfer failed")
+        respondWithError(w, http.StatusInternalServerError, "transfer failed")
+        return
     }
 
-    respondWithJSON(w, http.StatusOK, map[string]string{"status": "success"})
-}
\ No newline at end of file
+    respondWithJSON(w, http.StatusOK, map[string]interface{}{
+        "status":         "success",
+        "transaction_id": transaction.ID,
+        "transaction":    transaction,
+        "recipient":      recipient,
+    })
+}
+
+// Предварительная проверка получателя: отправитель видит маскированное
+// имя и валюту зачисления до отправки перевода
+func (h *TransferHandler) CheckRecipient(w http.ResponseWriter, r *http.Request) {
+    userID := r.Context().Value("userID").(uint)
+
+    var req struct {
+        FromAccountID uint `json:"from_account_id"`
+        services.RecipientQuery
+    }
+
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        respondWithError(w, http.StatusBadRequest, "invalid request format")
+        return
+    }
+
+    currency := ""
+    if req.FromAccountID != 0 {
+        from, err := h.accountService.GetByIDAndUser(req.FromAccountID, userID)
+        if err != nil {
+            respondWithError(w, http.StatusForbidden, "access denied")
+            return
+        }
+        currency = from.Currency
+    }
+
+    recipient, ok := h.resolve(w, req.RecipientQuery, currency)
+    if !ok {
+        return
+    }
+    respondWithJSON(w, http.StatusOK, recipient)
+}
+
+func (h *TransferHandler) resolve(w http.ResponseWriter, q services.RecipientQuery, currency string) (*services.Recipient, bool) {
+    recipient, err := h.recipientService.Resolve(q, currency)
+    switch {
+    case errors.Is(err, services.ErrRecipientNotFound):
+        respondWithError(w, http.StatusNotFound, err.Error())
+        return nil, false
+    case errors.Is(err, services.ErrInvalidRecipient),
+        errors.Is(err, services.ErrInvalidPhone):
+        respondWithError(w, http.StatusBadRequest, err.Error())
+        return nil, false
+    case err != nil:
+        h.logger.WithError(err).Error("recipient resolution failed")
+        respondWithError(w, http.StatusInternalServerError, "recipient resolution failed")
+        return nil, false
+    }
+    return recipient, true
+}
@@ -0,0 +1,190 @@
+package iso8583
+
+import (
+	"bank-service/src/models"
+	"bank-service/src/money"
+	"bank-service/src/repositories"
+	"bank-service/src/services"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Коды ответа (поле 39)
+const (
+	RespApproved          = "00"
+	RespDoNotHonor        = "05"
+	RespInvalidTxn        = "12"
+	RespInvalidAmount     = "13"
+	RespNoOriginal        = "25"
+	RespFormatError       = "30"
+	RespInsufficientFunds = "51"
+	RespExpiredCard       = "54"
+	RespLimitExceeded     = "61"
+	RespRestrictedCard    = "62"
+	RespDuplicate         = "94"
+	RespSystemError       = "96"
+)
+
+// Числовые коды валют ISO 4217 (поле 49)
+var currencyCodes = map[string]string{
+	"643": "RUB",
+	"840": "USD",
+	"978": "EUR",
+	"156": "CNY",
+	"826": "GBP",
+	"756": "CHF",
+	"398": "KZT",
+}
+
+// Поля запроса, которые возвращаются в ответе без изменений
+var echoFields = []int{2, 3, 4, 7, 11, 12, 13, 32, 37, 41, 42, 49, 90}
+
+// Обработка авторизаций партнера: 0100 - авторизация с блокировкой суммы,
+// 0200 - авторизация со списанием, 0400/0401 - реверсал по RRN (поле 37),
+// 0800 - эхо-тест. Все запросы соединения выполняются от имени
+// эквайрера acquirerID.
+type AuthorizationHandler struct {
+	acquiringService *services.AcquiringService
+	acquirerID       uint
+	logger           *logrus.Logger
+}
+
+func NewAuthorizationHandler(acquiringService *services.AcquiringService, acquirerID uint, logger *logrus.Logger) *AuthorizationHandler {
+	return &AuthorizationHandler{
+		acquiringService: acquiringService,
+		acquirerID:       acquirerID,
+		logger:           logger,
+	}
+}
+
+func (h *AuthorizationHandler) Handle(req *Message) *Message {
+	resp := NewMessage(ResponseMTI(req.MTI))
+	for _, n := range echoFields {
+		if req.Has(n) {
+			resp.Set(n, req.Get(n))
+		}
+	}
+
+	switch req.MTI {
+	case "0100", "0200":
+		h.authorize(req, resp)
+	case "0400", "0401":
+		h.reverse(req, resp)
+	case "0800":
+		if req.Has(70) {
+			resp.Set(70, req.Get(70))
+		}
+		resp.Set(39, RespApproved)
+	default:
+		resp.Set(39, RespInvalidTxn)
+	}
+
+	h.logger.Infof("ISO 8583 %s STAN %s RRN %s -> %s %s",
+		req.MTI, req.Get(11), strings.TrimSpace(req.Get(37)), resp.MTI, resp.Get(39))
+	return resp
+}
+
+func (h *AuthorizationHandler) authorize(req, resp *Message) {
+	authReq, code := toAuthorizationRequest(req)
+	if code != "" {
+		resp.Set(39, code)
+		return
+	}
+
+	var hold *models.CardHold
+	var err error
+	if req.MTI == "0200" {
+		hold, err = h.acquiringService.Purchase(h.acquirerID, authReq)
+	} else {
+		hold, err = h.acquiringService.Authorize(h.acquirerID, authReq)
+	}
+	if err != nil {
+		resp.Set(39, h.responseCode(err))
+		return
+	}
+
+	resp.Set(38, hold.AuthCode)
+	resp.Set(39, RespApproved)
+}
+
+func (h *AuthorizationHandler) reverse(req, resp *Message) {
+	reference := strings.TrimSpace(req.Get(37))
+	if reference == "" {
+		resp.Set(39, RespFormatError)
+		return
+	}
+
+	if _, err := h.acquiringService.Reverse(h.acquirerID, reference); err != nil {
+		resp.Set(39, h.responseCode(err))
+		return
+	}
+	resp.Set(39, RespApproved)
+}
+
+// Запрос авторизации из полей сообщения; при ошибке формата
+// возвращает код ответа
+func toAuthorizationRequest(req *Message) (services.AuthorizationRequest, string) {
+	expiry := req.Get(14) // YYMM
+	if len(expiry) != 4 || !req.Has(2) || !req.Has(4) {
+		return services.AuthorizationRequest{}, RespFormatError
+	}
+
+	amount, err := strconv.ParseInt(req.Get(4), 10, 64)
+	if err != nil {
+		return services.AuthorizationRequest{}, RespFormatError
+	}
+	if amount <= 0 {
+		return services.AuthorizationRequest{}, RespInvalidAmount
+	}
+
+	currency := ""
+	if req.Has(49) {
+		var ok bool
+		if currency, ok = currencyCodes[req.Get(49)]; !ok {
+			return services.AuthorizationRequest{}, RespInvalidTxn
+		}
+	}
+
+	merchant := strings.TrimSpace(req.Get(43))
+	if merchant == "" {
+		merchant = strings.TrimSpace(req.Get(42))
+	}
+
+	return services.AuthorizationRequest{
+		CardNumber: req.Get(2),
+		Expiry:     expiry[2:] + "/" + expiry[:2],
+		CVV:        strings.TrimSpace(req.Get(48)),
+		Amount:     money.Amount(amount),
+		Currency:   currency,
+		Merchant:   merchant,
+		Reference:  strings.TrimSpace(req.Get(37)),
+	}, ""
+}
+
+func (h *AuthorizationHandler) responseCode(err error) string {
+	switch {
+	case errors.Is(err, services.ErrCardDataMismatch):
+		return RespDoNotHonor
+	case errors.Is(err, services.ErrCardExpired):
+		return RespExpiredCard
+	case errors.Is(err, services.ErrCardNotActive),
+		errors.Is(err, services.ErrAccountFrozen):
+		return RespRestrictedCard
+	case errors.Is(err, services.ErrInsufficientFunds):
+		return RespInsufficientFunds
+	case errors.Is(err, services.ErrLimitExceeded):
+		return RespLimitExceeded
+	case errors.Is(err, services.ErrHoldCurrencyMismatch):
+		return RespInvalidTxn
+	case errors.Is(err, repositories.ErrHoldReferenceExists):
+		return RespDuplicate
+	case errors.Is(err, repositories.ErrHoldNotFound):
+		return RespNoOriginal
+	default:
+		h.logger.WithError(err).Error("ISO 8583 request failed")
+		return RespSystemError
+	}
+}
@@ -0,0 +1,256 @@
+package iso8583
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidMessage = errors.New("invalid iso8583 message")
+
+// Сообщение ISO 8583: MTI и значения полей по номерам. Значения
+// двоичных полей хранятся в hex.
+type Message struct {
+	MTI    string
+	Fields map[int]string
+}
+
+func NewMessage(mti string) *Message {
+	return &Message{MTI: mti, Fields: make(map[int]string)}
+}
+
+func (m *Message) Get(n int) string {
+	return m.Fields[n]
+}
+
+func (m *Message) Set(n int, value string) {
+	m.Fields[n] = value
+}
+
+func (m *Message) Has(n int) bool {
+	_, ok := m.Fields[n]
+	return ok
+}
+
+// Номера заполненных полей по возрастанию
+func (m *Message) FieldNumbers() []int {
+	numbers := make([]int, 0, len(m.Fields))
+	for n := range m.Fields {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	return numbers
+}
+
+// MTI ответа: 0100 -> 0110, 0401 -> 0410
+func ResponseMTI(mti string) string {
+	if len(mti) != 4 {
+		return mti
+	}
+	return mti[:2] + string(mti[2]+1) + "0"
+}
+
+func (s *Spec) Pack(m *Message) ([]byte, error) {
+	if len(m.MTI) != 4 || !isDigits(m.MTI) {
+		return nil, fmt.Errorf("%w: MTI %q", ErrInvalidMessage, m.MTI)
+	}
+
+	numbers := m.FieldNumbers()
+	secondary := len(numbers) > 0 && numbers[len(numbers)-1] > 64
+	bitmap := make([]byte, 8)
+	if secondary {
+		bitmap = make([]byte, 16)
+		bitmap[0] |= 0x80
+	}
+
+	var body []byte
+	for _, n := range numbers {
+		f, ok := s.Fields[n]
+		if !ok || n < 2 {
+			return nil, fmt.Errorf("%w: field %d is not in the spec", ErrInvalidMessage, n)
+		}
+		packed, err := packField(f, m.Fields[n])
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %d: %v", ErrInvalidMessage, n, err)
+		}
+		bitmap[(n-1)/8] |= 0x80 >> ((n - 1) % 8)
+		body = append(body, packed...)
+	}
+
+	out := []byte(m.MTI)
+	if s.Bitmap == BitmapHex {
+		out = append(out, strings.ToUpper(hex.EncodeToString(bitmap))...)
+	} else {
+		out = append(out, bitmap...)
+	}
+	return append(out, body...), nil
+}
+
+func (s *Spec) Unpack(data []byte) (*Message, error) {
+	if len(data) < 4 || !isDigits(string(data[:4])) {
+		return nil, fmt.Errorf("%w: missing MTI", ErrInvalidMessage)
+	}
+	m := NewMessage(string(data[:4]))
+
+	bitmap, rest, err := s.readBitmap(data[4:])
+	if err != nil {
+		return m, err
+	}
+
+	for n := 2; n <= len(bitmap)*8; n++ {
+		if bitmap[(n-1)/8]&(0x80>>((n-1)%8)) == 0 {
+			continue
+		}
+		f, ok := s.Fields[n]
+		if !ok {
+			return m, fmt.Errorf("%w: field %d is not in the spec", ErrInvalidMessage, n)
+		}
+		var value string
+		value, rest, err = unpackField(f, rest)
+		if err != nil {
+			return m, fmt.Errorf("%w: field %d: %v", ErrInvalidMessage, n, err)
+		}
+		m.Fields[n] = value
+	}
+	if len(rest) != 0 {
+		return m, fmt.Errorf("%w: %d trailing bytes", ErrInvalidMessage, len(rest))
+	}
+	return m, nil
+}
+
+func (s *Spec) readBitmap(data []byte) (bitmap, rest []byte, err error) {
+	read := func(data []byte) ([]byte, []byte, error) {
+		if s.Bitmap == BitmapHex {
+			if len(data) < 16 {
+				return nil, nil, fmt.Errorf("%w: short bitmap", ErrInvalidMessage)
+			}
+			b, err := hex.DecodeString(string(data[:16]))
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: bitmap: %v", ErrInvalidMessage, err)
+			}
+			return b, data[16:], nil
+		}
+		if len(data) < 8 {
+			return nil, nil, fmt.Errorf("%w: short bitmap", ErrInvalidMessage)
+		}
+		return data[:8], data[8:], nil
+	}
+
+	bitmap, rest, err = read(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bitmap[0]&0x80 != 0 {
+		var secondary []byte
+		secondary, rest, err = read(rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		bitmap = append(append([]byte{}, bitmap...), secondary...)
+	}
+	return bitmap, rest, nil
+}
+
+func packField(f FieldSpec, value string) ([]byte, error) {
+	raw := []byte(value)
+	if f.Format == Binary {
+		var err error
+		if raw, err = hex.DecodeString(value); err != nil {
+			return nil, err
+		}
+	} else if err := checkFormat(f.Format, value); err != nil {
+		return nil, err
+	}
+
+	if len(raw) > f.Length {
+		return nil, fmt.Errorf("length %d exceeds %d", len(raw), f.Length)
+	}
+
+	switch f.Type {
+	case Fixed:
+		if pad := f.Length - len(raw); pad > 0 {
+			switch f.Format {
+			case Numeric:
+				raw = append([]byte(strings.Repeat("0", pad)), raw...)
+			case Binary:
+				return nil, fmt.Errorf("length %d, expected %d", len(raw), f.Length)
+			default:
+				raw = append(raw, strings.Repeat(" ", pad)...)
+			}
+		}
+		return raw, nil
+	case LLVar:
+		return append([]byte(fmt.Sprintf("%02d", len(raw))), raw...), nil
+	default:
+		return append([]byte(fmt.Sprintf("%03d", len(raw))), raw...), nil
+	}
+}
+
+func unpackField(f FieldSpec, data []byte) (string, []byte, error) {
+	length := f.Length
+	if f.Type != Fixed {
+		digits := 2
+		if f.Type == LLLVar {
+			digits = 3
+		}
+		if len(data) < digits {
+			return "", nil, errors.New("missing length")
+		}
+		// Atoi принимает знак ("-1", "+5"), поэтому сначала проверяем цифры
+		prefix := string(data[:digits])
+		if !isDigits(prefix) {
+			return "", nil, fmt.Errorf("invalid length %q", prefix)
+		}
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n < 0 || n > f.Length {
+			return "", nil, fmt.Errorf("invalid length %q", data[:digits])
+		}
+		length, data = n, data[digits:]
+	}
+	if len(data) < length {
+		return "", nil, fmt.Errorf("need %d bytes, have %d", length, len(data))
+	}
+
+	raw, rest := data[:length], data[length:]
+	if f.Format == Binary {
+		return strings.ToUpper(hex.EncodeToString(raw)), rest, nil
+	}
+	if err := checkFormat(f.Format, string(raw)); err != nil {
+		return "", nil, err
+	}
+	return string(raw), rest, nil
+}
+
+func checkFormat(format, value string) error {
+	switch format {
+	case Numeric:
+		if !isDigits(value) {
+			return errors.New("must contain only digits")
+		}
+	case Alpha:
+		for _, r := range value {
+			if !(r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r == ' ') {
+				return errors.New("must contain only letters and digits")
+			}
+		}
+	case AlphaSpecial:
+		for _, r := range value {
+			if r < 0x20 || r > 0x7e {
+				return errors.New("must contain only printable ASCII")
+			}
+		}
+	}
+	return nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
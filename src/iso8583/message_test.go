@@ -0,0 +1,102 @@
+package iso8583
+
+import (
+	"errors"
+	"testing"
+)
+
+// Битовая карта с одним полем n (первичная, 8 байт)
+func bitmapWith(n int) string {
+	bitmap := make([]byte, 8)
+	bitmap[(n-1)/8] |= 0x80 >> ((n - 1) % 8)
+	return string(bitmap)
+}
+
+func TestUnpackRejectsMalformed(t *testing.T) {
+	spec := DefaultSpec()
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"short MTI", "010"},
+		{"non-numeric MTI", "01A0" + bitmapWith(2)},
+		{"short bitmap", "0100\x40\x00"},
+		{"negative llvar length", "0100" + bitmapWith(2) + "-1" + "4111"},
+		{"signed llvar length", "0100" + bitmapWith(2) + "+5" + "41111"},
+		{"spaced llvar length", "0100" + bitmapWith(2) + " 5" + "41111"},
+		{"llvar over max", "0100" + bitmapWith(2) + "20" + "41111111111111111111"},
+		{"llvar truncated", "0100" + bitmapWith(2) + "16" + "4111"},
+		{"negative lllvar length", "0100" + bitmapWith(48) + "-01" + "x"},
+		{"fixed truncated", "0100" + bitmapWith(3) + "000"},
+		{"non-numeric value", "0100" + bitmapWith(3) + "00A000"},
+		{"field not in spec", "0100" + bitmapWith(5) + "000000000000"},
+		{"trailing bytes", "0100" + bitmapWith(3) + "000000" + "x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := spec.Unpack([]byte(tt.data))
+			if !errors.Is(err, ErrInvalidMessage) {
+				t.Fatalf("Unpack(%q) error = %v, want ErrInvalidMessage", tt.data, err)
+			}
+		})
+	}
+}
+
+func TestPackUnpackRoundTrip(t *testing.T) {
+	for _, bitmap := range []string{BitmapBinary, BitmapHex} {
+		spec := DefaultSpec()
+		spec.Bitmap = bitmap
+
+		m := NewMessage("0100")
+		m.Set(2, "4111111111111111")
+		m.Set(3, "000000")
+		m.Set(4, "000000001050")
+		m.Set(37, "ABC123456789")
+		m.Set(48, "123")
+		m.Set(70, "301")
+
+		packed, err := spec.Pack(m)
+		if err != nil {
+			t.Fatalf("%s: Pack: %v", bitmap, err)
+		}
+		got, err := spec.Unpack(packed)
+		if err != nil {
+			t.Fatalf("%s: Unpack: %v", bitmap, err)
+		}
+		if got.MTI != m.MTI || len(got.Fields) != len(m.Fields) {
+			t.Fatalf("%s: got %+v, want %+v", bitmap, got, m)
+		}
+		for n, v := range m.Fields {
+			if got.Get(n) != v {
+				t.Errorf("%s: field %d = %q, want %q", bitmap, n, got.Get(n), v)
+			}
+		}
+	}
+}
+
+func FuzzUnpack(f *testing.F) {
+	spec := DefaultSpec()
+	m := NewMessage("0200")
+	m.Set(2, "4111111111111111")
+	m.Set(4, "000000001050")
+	m.Set(48, "123")
+	packed, err := spec.Pack(m)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(packed)
+	f.Add([]byte("0100" + bitmapWith(2) + "-1"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		got, err := spec.Unpack(data)
+		if err != nil {
+			return
+		}
+		// Разобранное сообщение должно упаковываться обратно без ошибок
+		if _, err := spec.Pack(got); err != nil {
+			t.Fatalf("Pack after Unpack(%q): %v", data, err)
+		}
+	})
+}
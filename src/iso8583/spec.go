@@ -0,0 +1,115 @@
+package iso8583
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Кодирование длины поля
+const (
+	Fixed  = "fixed"  // длина задана спецификацией
+	LLVar  = "llvar"  // 2 цифры длины перед значением
+	LLLVar = "lllvar" // 3 цифры длины перед значением
+)
+
+// Формат значения поля
+const (
+	Numeric      = "n"   // цифры; fixed-поля дополняются нулями слева
+	Alpha        = "an"  // буквы и цифры; fixed-поля дополняются пробелами справа
+	AlphaSpecial = "ans" // любые печатные символы
+	Binary       = "b"   // байты; в Message хранятся в hex, Length - в байтах
+)
+
+// Кодирование битовой карты
+const (
+	BitmapBinary = "binary" // 8 байт на каждую карту
+	BitmapHex    = "hex"    // 16 символов hex на каждую карту
+)
+
+type FieldSpec struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Format string `json:"format"`
+	Length int    `json:"length"` // точная длина для fixed, максимальная для llvar/lllvar
+}
+
+// Спецификация сообщений: кодирование битовой карты и описание полей 2-128.
+// MTI и длины передаются в ASCII. Загружается из JSON вида
+// {"bitmap": "binary", "fields": {"2": {"name": "PAN", "type": "llvar", "format": "n", "length": 19}}}.
+type Spec struct {
+	Bitmap string            `json:"bitmap"`
+	Fields map[int]FieldSpec `json:"fields"`
+}
+
+func LoadSpec(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	spec := &Spec{}
+	if err := json.Unmarshal(data, spec); err != nil {
+		return nil, fmt.Errorf("iso8583 spec %s: %w", path, err)
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, fmt.Errorf("iso8583 spec %s: %w", path, err)
+	}
+	return spec, nil
+}
+
+func (s *Spec) Validate() error {
+	if s.Bitmap != BitmapBinary && s.Bitmap != BitmapHex {
+		return fmt.Errorf("unknown bitmap encoding %q", s.Bitmap)
+	}
+	for n, f := range s.Fields {
+		if n < 2 || n > 128 {
+			return fmt.Errorf("field %d: number must be between 2 and 128", n)
+		}
+		switch f.Type {
+		case Fixed, LLVar, LLLVar:
+		default:
+			return fmt.Errorf("field %d: unknown type %q", n, f.Type)
+		}
+		switch f.Format {
+		case Numeric, Alpha, AlphaSpecial, Binary:
+		default:
+			return fmt.Errorf("field %d: unknown format %q", n, f.Format)
+		}
+		if f.Length <= 0 || (f.Type == LLVar && f.Length > 99) || (f.Type == LLLVar && f.Length > 999) {
+			return fmt.Errorf("field %d: invalid length %d", n, f.Length)
+		}
+	}
+	return nil
+}
+
+// Спецификация по умолчанию: поля ISO 8583:1987, используемые
+// в авторизациях, ASCII-кодирование и двоичная битовая карта.
+// CVV2 передается в поле 48 (дополнительные данные).
+func DefaultSpec() *Spec {
+	return &Spec{
+		Bitmap: BitmapBinary,
+		Fields: map[int]FieldSpec{
+			2:  {Name: "Primary account number", Type: LLVar, Format: Numeric, Length: 19},
+			3:  {Name: "Processing code", Type: Fixed, Format: Numeric, Length: 6},
+			4:  {Name: "Amount, transaction", Type: Fixed, Format: Numeric, Length: 12},
+			7:  {Name: "Transmission date and time", Type: Fixed, Format: Numeric, Length: 10},
+			11: {Name: "System trace audit number", Type: Fixed, Format: Numeric, Length: 6},
+			12: {Name: "Time, local transaction", Type: Fixed, Format: Numeric, Length: 6},
+			13: {Name: "Date, local transaction", Type: Fixed, Format: Numeric, Length: 4},
+			14: {Name: "Date, expiration", Type: Fixed, Format: Numeric, Length: 4},
+			18: {Name: "Merchant type", Type: Fixed, Format: Numeric, Length: 4},
+			22: {Name: "POS entry mode", Type: Fixed, Format: Numeric, Length: 3},
+			32: {Name: "Acquiring institution identification code", Type: LLVar, Format: Numeric, Length: 11},
+			37: {Name: "Retrieval reference number", Type: Fixed, Format: Alpha, Length: 12},
+			38: {Name: "Authorization identification response", Type: Fixed, Format: Alpha, Length: 6},
+			39: {Name: "Response code", Type: Fixed, Format: Alpha, Length: 2},
+			41: {Name: "Card acceptor terminal identification", Type: Fixed, Format: AlphaSpecial, Length: 8},
+			42: {Name: "Card acceptor identification code", Type: Fixed, Format: AlphaSpecial, Length: 15},
+			43: {Name: "Card acceptor name/location", Type: Fixed, Format: AlphaSpecial, Length: 40},
+			48: {Name: "Additional data - private", Type: LLLVar, Format: AlphaSpecial, Length: 999},
+			49: {Name: "Currency code, transaction", Type: Fixed, Format: Numeric, Length: 3},
+			70: {Name: "Network management information code", Type: Fixed, Format: Numeric, Length: 3},
+			90: {Name: "Original data elements", Type: Fixed, Format: Numeric, Length: 42},
+		},
+	}
+}
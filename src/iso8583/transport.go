@@ -0,0 +1,241 @@
+package iso8583
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Сообщения передаются с 2-байтным заголовком длины (big-endian)
+const maxFrameLength = 1<<16 - 1
+
+// Простой на соединении, после которого оно закрывается. Партнеры
+// поддерживают соединение эхо-запросами 0800.
+const idleTimeout = 5 * time.Minute
+
+func ReadFrame(r io.Reader) ([]byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	data := make([]byte, binary.BigEndian.Uint16(header[:]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func WriteFrame(w io.Writer, data []byte) error {
+	if len(data) > maxFrameLength {
+		return fmt.Errorf("%w: message too long", ErrInvalidMessage)
+	}
+	out := make([]byte, 2, 2+len(data))
+	binary.BigEndian.PutUint16(out, uint16(len(data)))
+	_, err := w.Write(append(out, data...))
+	return err
+}
+
+// Обработчик запроса; возвращает ответ или nil, если ответ не нужен
+type Handler interface {
+	Handle(req *Message) *Message
+}
+
+// TCP-сервер ISO 8583. Запросы одного соединения обрабатываются
+// параллельно, ответы сопоставляются партнером по STAN (поле 11).
+// Протокол не аутентифицирует партнера, поэтому соединения принимаются
+// только с адресов из allowed; без списка - только с loopback.
+type Server struct {
+	addr    string
+	spec    *Spec
+	handler Handler
+	allowed []*net.IPNet
+	logger  *logrus.Logger
+}
+
+func NewServer(addr string, spec *Spec, handler Handler, allowed []*net.IPNet, logger *logrus.Logger) *Server {
+	return &Server{addr: addr, spec: spec, handler: handler, allowed: allowed, logger: logger}
+}
+
+// Разбор списка адресов партнеров через запятую: IP или подсети CIDR
+func ParseAllowlist(list string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", item)
+			}
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q", item)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func (s *Server) isAllowed(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	if len(s.allowed) == 0 {
+		return tcp.IP.IsLoopback()
+	}
+	for _, n := range s.allowed {
+		if n.Contains(tcp.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	s.logger.Infof("ISO 8583 listener on %s", s.addr)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		if !s.isAllowed(conn.RemoteAddr()) {
+			s.logger.Warnf("ISO 8583 connection from %s rejected: address is not allowed", conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
+		go s.serve(conn)
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+	remote := conn.RemoteAddr().String()
+	s.logger.Infof("ISO 8583 connection from %s", remote)
+
+	var writeMu sync.Mutex
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		data, err := ReadFrame(conn)
+		if err != nil {
+			if err != io.EOF {
+				s.logger.Warnf("ISO 8583 connection %s closed: %v", remote, err)
+			}
+			return
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := s.safeProcess(data, remote)
+			if resp == nil {
+				return
+			}
+			packed, err := s.spec.Pack(resp)
+			if err != nil {
+				s.logger.WithError(err).Errorf("Failed to pack ISO 8583 %s response", resp.MTI)
+				return
+			}
+
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			if err := WriteFrame(conn, packed); err != nil {
+				s.logger.Warnf("Failed to write ISO 8583 response to %s: %v", remote, err)
+			}
+		}()
+	}
+}
+
+// Паника в разборе или обработчике не должна ронять весь процесс
+func (s *Server) safeProcess(data []byte, remote string) (resp *Message) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Errorf("Panic while processing ISO 8583 message from %s: %v", remote, r)
+			resp = nil
+			if len(data) >= 4 && isDigits(string(data[:4])) {
+				resp = NewMessage(ResponseMTI(string(data[:4])))
+				resp.Set(39, RespSystemError)
+			}
+		}
+	}()
+	return s.process(data, remote)
+}
+
+func (s *Server) process(data []byte, remote string) *Message {
+	req, err := s.spec.Unpack(data)
+	if err != nil {
+		s.logger.Warnf("Malformed ISO 8583 message from %s: %v", remote, err)
+		// Без MTI ответить нельзя
+		if req == nil {
+			return nil
+		}
+		resp := NewMessage(ResponseMTI(req.MTI))
+		for _, n := range []int{11, 37} {
+			if req.Has(n) {
+				resp.Set(n, req.Get(n))
+			}
+		}
+		resp.Set(39, RespFormatError)
+		return resp
+	}
+	return s.handler.Handle(req)
+}
+
+// Клиент для отправки запросов по одному и чтения ответа на каждый
+type Client struct {
+	conn    net.Conn
+	spec    *Spec
+	timeout time.Duration
+}
+
+func Dial(addr string, spec *Spec, timeout time.Duration) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, spec: spec, timeout: timeout}, nil
+}
+
+func (c *Client) Send(req *Message) (*Message, error) {
+	packed, err := c.spec.Pack(req)
+	if err != nil {
+		return nil, err
+	}
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	if err := WriteFrame(c.conn, packed); err != nil {
+		return nil, err
+	}
+	data, err := ReadFrame(c.conn)
+	if err != nil {
+		return nil, err
+	}
+	return c.spec.Unpack(data)
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
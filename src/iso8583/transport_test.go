@@ -0,0 +1,41 @@
+package iso8583
+
+import (
+	"net"
+	"testing"
+)
+
+func TestServerAllowlist(t *testing.T) {
+	tests := []struct {
+		list    string
+		remote  string
+		allowed bool
+	}{
+		{"", "127.0.0.1", true},
+		{"", "::1", true},
+		{"", "10.0.0.5", false},
+		{"10.0.0.5", "10.0.0.5", true},
+		{"10.0.0.5", "10.0.0.6", false},
+		{"10.0.0.5", "127.0.0.1", false},
+		{"192.168.1.0/24, 10.0.0.5", "192.168.1.77", true},
+		{"192.168.1.0/24", "192.168.2.1", false},
+	}
+
+	for _, tt := range tests {
+		allowed, err := ParseAllowlist(tt.list)
+		if err != nil {
+			t.Fatalf("ParseAllowlist(%q): %v", tt.list, err)
+		}
+		s := &Server{allowed: allowed}
+		addr := &net.TCPAddr{IP: net.ParseIP(tt.remote), Port: 40000}
+		if got := s.isAllowed(addr); got != tt.allowed {
+			t.Errorf("list %q, remote %s: allowed = %v, want %v", tt.list, tt.remote, got, tt.allowed)
+		}
+	}
+
+	for _, list := range []string{"10.0.0", "10.0.0.0/33", "host.example"} {
+		if _, err := ParseAllowlist(list); err == nil {
+			t.Errorf("ParseAllowlist(%q) accepted invalid input", list)
+		}
+	}
+}
@@ -3,11 +3,13 @@ package main
 import (
 	"bank-service/src/config"
 	"bank-service/src/handlers"
+	"bank-service/src/iso8583"
 	"bank-service/src/middleware"
+	"bank-service/src/models"
 	"bank-service/src/repositories"
 	"bank-service/src/services"
-	"bank-service/src/crypto"
 	"database/sql"
+	"encoding/hex"
 	"net/http"
 	"time"
 	
@@ -34,24 +36,54 @@ func main() {
 	transactionRepo := repositories.NewTransactionRepository(db, logger)
 	creditRepo := repositories.NewCreditRepository(db, logger)
 	paymentScheduleRepo := repositories.NewPaymentScheduleRepository(db, logger)
+	ledgerRepo := repositories.NewLedgerRepository(db, logger)
+	idempotencyRepo := repositories.NewIdempotencyRepository(db, logger)
+	exchangeRateRepo := repositories.NewExchangeRateRepository(db, logger)
+	fxQuoteRepo := repositories.NewFXQuoteRepository(db, logger)
+	standingOrderRepo := repositories.NewStandingOrderRepository(db, logger)
+	feeRepo := repositories.NewFeeRepository(db, logger)
+	limitRepo := repositories.NewLimitRepository(db, logger)
+	applicationRepo := repositories.NewCreditApplicationRepository(db, logger)
+	penaltyRepo := repositories.NewPenaltyRepository(db, logger)
+	pgpKeyRepo := repositories.NewPGPKeyRepository(db, logger)
+	acquirerRepo := repositories.NewAcquirerRepository(db, logger)
+	cardHoldRepo := repositories.NewCardHoldRepository(db, logger)
 	
 
-	// Инициализация PGP
-	pgpEntity, err := crypto.InitPGP()
-	if err != nil {
-		logger.Fatal("Failed to initialize PGP: ", err)
+	// Хранилище ключей PGP и ключ слепого индекса номеров карт
+	// Ключи не имеют значений по умолчанию: общий для всех установок ключ
+	// открыл бы ключи PGP и индекс номеров карт любой из них
+	if cfg.KeyStoreMasterKey == "" || cfg.CardIndexKey == "" {
+		logger.Fatal("KEYSTORE_MASTER_KEY and CARD_INDEX_KEY must be set, generate them with: openssl rand -hex 32")
+	}
+	masterKey, err := hex.DecodeString(cfg.KeyStoreMasterKey)
+	if err != nil || len(masterKey) != 32 {
+		logger.Fatal("KEYSTORE_MASTER_KEY must be 32 bytes in hex")
+	}
+	cardIndexKey, err := hex.DecodeString(cfg.CardIndexKey)
+	if err != nil || len(cardIndexKey) < 32 {
+		logger.Fatal("CARD_INDEX_KEY must be at least 32 bytes in hex")
+	}
+	keyService := services.NewKeyService(pgpKeyRepo, masterKey, logger)
+	if err := keyService.Load(); err != nil {
+		logger.Fatal("Failed to load PGP keys: ", err)
 	}
 
 	// Инициализация сервисов
 	authService := services.NewAuthService(userRepo, cfg.JWTSecret, logger)
-	accountService := services.NewAccountService(accountRepo, transactionRepo, logger)
+	cbrService := services.NewCBRService()
+	exchangeRateService := services.NewExchangeRateService(exchangeRateRepo, cbrService, logger)
+	feeService := services.NewFeeService(feeRepo, userRepo, exchangeRateService, logger)
+	limitService := services.NewLimitService(limitRepo, userRepo, cardRepo, exchangeRateService, logger)
+	accountService := services.NewAccountService(accountRepo, transactionRepo, ledgerRepo, logger, exchangeRateService, feeService, limitService, cardRepo)
 	cardService := services.NewCardService(
 		cardRepo, 
 		accountRepo, 
-		pgpEntity,
+		keyService,
+		cardIndexKey,
 		logger,
+		accountService,
 	)
-	cbrService := services.NewCBRService()
 	emailService := services.NewEmailService(
 		cfg.EmailHost,
 		cfg.EmailPort,
@@ -68,6 +100,13 @@ func main() {
 		logger,
 		userRepo,
 		emailService,
+		cfg.MaxPSK,
+		penaltyRepo,
+		services.PenaltyPolicy{
+			DailyRate:        cfg.PenaltyDailyRate,
+			OverdueAfterDays: cfg.OverdueAfterDays,
+			DefaultAfterDays: cfg.DefaultAfterDays,
+		},
 	)
 	analyticsService := services.NewAnalyticsService(
 		transactionRepo, 
@@ -75,6 +114,27 @@ func main() {
 		accountRepo,
 		paymentScheduleRepo,
 	)
+	fxService := services.NewFXService(
+		fxQuoteRepo,
+		accountService,
+		exchangeRateService,
+		cfg.FXSpread,
+		cfg.FXQuoteTTL,
+		logger,
+	)
+	applicationService := services.NewCreditApplicationService(
+		applicationRepo,
+		transactionRepo,
+		paymentScheduleRepo,
+		creditService,
+		analyticsService,
+		services.NewRuleBasedScoring(),
+		logger,
+	)
+	standingOrderService := services.NewStandingOrderService(standingOrderRepo, accountService, logger)
+	recipientService := services.NewRecipientService(userRepo, accountRepo, cardService, logger)
+	reversalService := services.NewReversalService(accountService, transactionRepo, logger)
+	acquiringService := services.NewAcquiringService(cardHoldRepo, acquirerRepo, cardRepo, cardService, accountService, limitService, logger)
 
     go func() {
         ticker := time.NewTicker(12 * time.Hour)
@@ -85,18 +145,123 @@ func main() {
         }
     }()
 
+	// Исполнение регулярных переводов и повторных попыток по ним
+	go func() {
+		ticker := time.NewTicker(10 * time.Minute)
+		for range ticker.C {
+			if err := standingOrderService.ExecuteDue(); err != nil {
+				logger.Errorf("Standing orders execution failed: %v", err)
+			}
+		}
+	}()
+
+	// Ежедневная загрузка официальных курсов ЦБ
+	go func() {
+		if err := exchangeRateService.RefreshRates(time.Now()); err != nil {
+			logger.Errorf("Exchange rates refresh failed: %v", err)
+		}
+		ticker := time.NewTicker(6 * time.Hour)
+		for range ticker.C {
+			if err := exchangeRateService.RefreshRates(time.Now()); err != nil {
+				logger.Errorf("Exchange rates refresh failed: %v", err)
+			}
+		}
+	}()
+
+	// Карты, выпущенные до хранилища ключей, перешифровать нельзя - только перевыпустить
+	if n, err := cardService.CountCardsWithoutKey(); err != nil {
+		logger.Errorf("Failed to count cards without key: %v", err)
+	} else if n > 0 {
+		logger.Warnf("%d cards were issued before the key store and need reissue", n)
+	}
+
+	// Перешифрование карт последней версией ключа PGP
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		for range ticker.C {
+			if err := keyService.Load(); err != nil {
+				logger.Errorf("PGP keys reload failed: %v", err)
+				continue
+			}
+			if _, err := cardService.ReencryptCards(); err != nil {
+				logger.Errorf("Card re-encryption failed: %v", err)
+			}
+		}
+	}()
+
+	// Истечение срока действия карт
+	go func() {
+		ticker := time.NewTicker(6 * time.Hour)
+		for range ticker.C {
+			if _, err := cardService.ExpireCards(); err != nil {
+				logger.Errorf("Card expiry failed: %v", err)
+			}
+		}
+	}()
+
+	// Разблокировка несписанных авторизаций по картам
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		for range ticker.C {
+			if err := acquiringService.ExpireHolds(); err != nil {
+				logger.Errorf("Card holds expiry failed: %v", err)
+			}
+		}
+	}()
+
+	// Очистка устаревших ключей идемпотентности
+	go func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		for range ticker.C {
+			if _, err := idempotencyRepo.DeleteOlderThan(time.Now().Add(-24 * time.Hour)); err != nil {
+				logger.Errorf("Idempotency keys cleanup failed: %v", err)
+			}
+		}
+	}()
+
+	// Листенер ISO 8583 для процессинговых партнеров; все запросы
+	// выполняются от имени эквайрера ISO8583AcquirerID, поэтому принимаются
+	// только соединения с адресов ISO8583AllowedClients (без списка - с loopback)
+	if cfg.ISO8583Addr != "" {
+		allowed, err := iso8583.ParseAllowlist(cfg.ISO8583AllowedClients)
+		if err != nil {
+			logger.Fatal("Invalid ISO8583_ALLOWED_CLIENTS: ", err)
+		}
+		spec := iso8583.DefaultSpec()
+		if cfg.ISO8583SpecFile != "" {
+			if spec, err = iso8583.LoadSpec(cfg.ISO8583SpecFile); err != nil {
+				logger.Fatal("Failed to load ISO 8583 spec: ", err)
+			}
+		}
+		isoHandler := iso8583.NewAuthorizationHandler(acquiringService, cfg.ISO8583AcquirerID, logger)
+		go func() {
+			if err := iso8583.NewServer(cfg.ISO8583Addr, spec, isoHandler, allowed, logger).ListenAndServe(); err != nil {
+				logger.Fatal("ISO 8583 listener failed: ", err)
+			}
+		}()
+	}
+
 	// Инициализация обработчиков
 	authHandler := handlers.NewAuthHandler(authService, logger)
 	accountHandler := handlers.NewAccountHandler(accountService, analyticsService, logger)
-	transferHandler := handlers.NewTransferHandler(accountService, logger)
+	transferHandler := handlers.NewTransferHandler(accountService, recipientService, cardService, logger)
 	creditHandler := handlers.NewCreditHandler(creditService, logger)
+	applicationHandler := handlers.NewCreditApplicationHandler(applicationService, logger)
 	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService, logger)
+	fxHandler := handlers.NewFXHandler(fxService, logger)
+	standingOrderHandler := handlers.NewStandingOrderHandler(standingOrderService, logger)
+	reversalHandler := handlers.NewReversalHandler(reversalService, logger)
+	feeHandler := handlers.NewFeeHandler(feeService, accountService, logger)
+	limitHandler := handlers.NewLimitHandler(limitService, logger)
+	pgpKeyHandler := handlers.NewPGPKeyHandler(keyService, cardService, logger)
+	acquiringHandler := handlers.NewAcquiringHandler(acquiringService, logger)
 
 	// Инициализация middleware
 	authMiddleware := middleware.NewAuthMiddleware(cfg.JWTSecret, logger)
+	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(idempotencyRepo, logger)
 
 	// Инициализация сервиса карт
-	cardHandler := handlers.NewCardHandler(cardService, logger)
+	cardHandler := handlers.NewCardHandler(cardService, authService, logger)
 
 	// Настройка маршрутизатора
 	router := mux.NewRouter()
@@ -115,19 +280,71 @@ func main() {
 	protected.HandleFunc("/accounts/{accountId}", accountHandler.GetAccount).Methods("GET")
 	protected.HandleFunc("/accounts/{accountId}/predict", accountHandler.PredictBalance).Methods("GET")
 
+	// Маршруты, доступные только владельцу счета
+	ownAccount := protected.PathPrefix("/accounts/{accountId}").Subrouter()
+	ownAccount.Use(middleware.AccountOwnershipMiddleware(accountRepo, logger))
+	ownAccount.HandleFunc("/transactions", accountHandler.GetTransactions).Methods("GET")
+	ownAccount.HandleFunc("/statement", accountHandler.GetStatement).Methods("GET")
+
 	// Для карт
 	protected.HandleFunc("/cards", cardHandler.CreateCard).Methods("POST")
 	protected.HandleFunc("/cards", cardHandler.GetCards).Methods("GET")
+	protected.HandleFunc("/cards/{cardId}/reveal", cardHandler.RevealCard).Methods("GET")
+	protected.HandleFunc("/cards/{cardId}/block", cardHandler.BlockCard).Methods("POST")
+	protected.HandleFunc("/cards/{cardId}/unblock", cardHandler.UnblockCard).Methods("POST")
+	protected.Handle("/cards/{cardId}/reissue", idempotencyMiddleware.Handle(http.HandlerFunc(cardHandler.ReissueCard))).Methods("POST")
+	protected.HandleFunc("/cards/{cardId}/close", cardHandler.CloseCard).Methods("POST")
+	protected.HandleFunc("/auth/step-up", authHandler.StepUp).Methods("POST")
 
 	// Трансферы
-	protected.HandleFunc("/transfer", transferHandler.Transfer).Methods("POST")
-	protected.HandleFunc("/accounts/{accountId}/deposit", accountHandler.Deposit).Methods("PUT")
+	protected.Handle("/transfer", idempotencyMiddleware.Handle(http.HandlerFunc(transferHandler.Transfer))).Methods("POST")
+	protected.HandleFunc("/transfer/recipient", transferHandler.CheckRecipient).Methods("POST")
+	protected.Handle("/accounts/{accountId}/deposit", idempotencyMiddleware.Handle(http.HandlerFunc(accountHandler.Deposit))).Methods("PUT")
+
+	// Регулярные переводы
+	protected.Handle("/standing-orders", idempotencyMiddleware.Handle(http.HandlerFunc(standingOrderHandler.Create))).Methods("POST")
+	protected.HandleFunc("/standing-orders", standingOrderHandler.GetOrders).Methods("GET")
+	protected.HandleFunc("/standing-orders/{orderId}", standingOrderHandler.GetOrder).Methods("GET")
+	protected.HandleFunc("/standing-orders/{orderId}", standingOrderHandler.Cancel).Methods("DELETE")
 
 	// Кредиты
-	protected.HandleFunc("/credits", creditHandler.CreateCredit).Methods("POST")
+	protected.Handle("/credits", idempotencyMiddleware.Handle(http.HandlerFunc(applicationHandler.Submit))).Methods("POST")
+	protected.HandleFunc("/credits/applications", applicationHandler.GetApplications).Methods("GET")
+	protected.HandleFunc("/credits/applications/{applicationId}", applicationHandler.GetApplication).Methods("GET")
+	protected.Handle("/credits/applications/{applicationId}/sign", idempotencyMiddleware.Handle(http.HandlerFunc(applicationHandler.Sign))).Methods("POST")
+	protected.HandleFunc("/credits/quote", creditHandler.Quote).Methods("POST")
 	protected.HandleFunc("/credits/{creditId}/schedule", creditHandler.GetSchedule).Methods("GET")
+	protected.Handle("/credits/{creditId}/payments", idempotencyMiddleware.Handle(http.HandlerFunc(creditHandler.MakePayment))).Methods("POST")
 	protected.HandleFunc("/credits/{accountId}/credits", creditHandler.GetCreditsByAccount).Methods("GET")
 
+	// Обмен валюты
+	protected.HandleFunc("/fx/quotes", fxHandler.CreateQuote).Methods("POST")
+	protected.Handle("/fx/exchanges", idempotencyMiddleware.Handle(http.HandlerFunc(fxHandler.Exchange))).Methods("POST")
+
+	// Операции сотрудников поддержки
+	staff := protected.PathPrefix("/staff").Subrouter()
+	staff.Use(middleware.RequireRole(userRepo, models.RoleStaff, logger))
+	staff.Handle("/transactions/{transactionId}/reversals", idempotencyMiddleware.Handle(http.HandlerFunc(reversalHandler.Reverse))).Methods("POST")
+	staff.HandleFunc("/pgp-keys", pgpKeyHandler.ListKeys).Methods("GET")
+	staff.HandleFunc("/pgp-keys/rotate", pgpKeyHandler.Rotate).Methods("POST")
+	staff.HandleFunc("/pgp-keys/cards-without-key", pgpKeyHandler.CardsWithoutKey).Methods("GET")
+	staff.HandleFunc("/acquirers", acquiringHandler.CreateAcquirer).Methods("POST")
+
+	// Эквайринг: запросы эквайреров по ключу API
+	acquiring := router.PathPrefix("/acquiring").Subrouter()
+	acquiring.Use(middleware.AcquirerAuth(acquirerRepo, logger))
+	acquiring.HandleFunc("/authorizations", acquiringHandler.Authorize).Methods("POST")
+	acquiring.HandleFunc("/authorizations/{holdId}", acquiringHandler.GetHold).Methods("GET")
+	acquiring.HandleFunc("/authorizations/{holdId}/capture", acquiringHandler.Capture).Methods("POST")
+	acquiring.HandleFunc("/authorizations/{holdId}/void", acquiringHandler.Void).Methods("POST")
+
+	// Лимиты
+	protected.HandleFunc("/limits", limitHandler.GetLimits).Methods("GET")
+	protected.HandleFunc("/limits", limitHandler.UpdateLimits).Methods("PUT")
+
+	// Комиссии
+	protected.HandleFunc("/fees/preview", feeHandler.Preview).Methods("POST")
+
 	// Аналитика
 	protected.HandleFunc("/analytics", analyticsHandler.GetAnalytics).Methods("GET")
 
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"bank-service/src/crypto"
+	"bank-service/src/repositories"
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const AcquirerKeyHeader = "X-API-Key"
+
+// Аутентификация эквайрера по ключу API; id эквайрера кладется
+// в контекст под ключом "acquirerID"
+func AcquirerAuth(repo *repositories.AcquirerRepository, logger *logrus.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(AcquirerKeyHeader)
+			if key == "" {
+				respondWithError(w, http.StatusUnauthorized, "API key required")
+				return
+			}
+
+			acquirer, err := repo.GetByAPIKeyHash(crypto.HashAPIKey(key))
+			if err != nil {
+				logger.Warnf("Acquirer authentication failed from %s: %v", r.RemoteAddr, err)
+				respondWithError(w, http.StatusUnauthorized, "invalid API key")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), "acquirerID", acquirer.ID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
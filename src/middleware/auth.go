@@ -48,6 +48,13 @@ func (m *AuthMiddleware) Handle(next http.Handler) http.Handler {
 			return
 		}
 
+		// Токены с ограниченной областью действия (step-up) не заменяют сессию
+		if _, scoped := claims["scope"]; scoped {
+			m.logger.Warn("Scoped token used as session token")
+			http.Error(w, `{"error":"invalid token"}`, http.StatusUnauthorized)
+			return
+		}
+
 		userID := claims["sub"].(float64)
 		ctx := context.WithValue(r.Context(), "userID", uint(userID))
 		next.ServeHTTP(w, r.WithContext(ctx))
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"bank-service/src/models"
+	"bank-service/src/repositories"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// Повторный запрос с тем же Idempotency-Key не выполняется заново:
+// клиент получает сохраненный ответ исходного запроса.
+type IdempotencyMiddleware struct {
+	repo   *repositories.IdempotencyRepository
+	logger *logrus.Logger
+}
+
+func NewIdempotencyMiddleware(repo *repositories.IdempotencyRepository, logger *logrus.Logger) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (m *IdempotencyMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			respondWithError(w, http.StatusBadRequest, "idempotency key is too long")
+			return
+		}
+
+		userID := r.Context().Value("userID").(uint)
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "invalid request")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		record := &models.IdempotencyKey{
+			UserID:      userID,
+			Key:         key,
+			Method:      r.Method,
+			Path:        r.URL.Path,
+			RequestHash: fingerprint(r.Method, r.URL.Path, body),
+		}
+
+		reserved, err := m.repo.Reserve(record)
+		if err != nil {
+			m.logger.WithError(err).Error("failed to reserve idempotency key")
+			respondWithError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		if !reserved {
+			m.replay(w, record)
+			return
+		}
+
+		// Если ответ не сохранен (серверная ошибка, паника обработчика, сбой
+		// записи), ключ освобождается: иначе повтор получал бы 409 до истечения TTL
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := m.repo.Delete(userID, key); err != nil {
+				m.logger.WithError(err).Errorf("failed to release idempotency key %q", key)
+			}
+		}()
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		// Серверную ошибку не запоминаем, чтобы клиент мог повторить запрос
+		if rec.status >= http.StatusInternalServerError {
+			return
+		}
+		if err := m.repo.Complete(userID, key, rec.status, rec.body.Bytes()); err != nil {
+			m.logger.WithError(err).Errorf("failed to store response for idempotency key %q", key)
+			return
+		}
+		completed = true
+	})
+}
+
+func (m *IdempotencyMiddleware) replay(w http.ResponseWriter, record *models.IdempotencyKey) {
+	stored, err := m.repo.Get(record.UserID, record.Key)
+	if err != nil {
+		m.logger.WithError(err).Error("failed to load idempotency key")
+		respondWithError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	if stored.RequestHash != record.RequestHash {
+		respondWithError(w, http.StatusUnprocessableEntity, "idempotency key was already used with a different request")
+		return
+	}
+	if stored.StatusCode == 0 {
+		respondWithError(w, http.StatusConflict, "request with this idempotency key is still in progress")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(idempotentReplayedHeader, "true")
+	w.WriteHeader(stored.StatusCode)
+	w.Write(stored.ResponseBody)
+}
+
+func fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + "\n" + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Пропускает ответ клиенту и одновременно запоминает его
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
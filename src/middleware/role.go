@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"bank-service/src/repositories"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// Доступ только пользователям с ролью role; роль читается из БД на каждый
+// запрос, чтобы отзыв роли действовал сразу, без перевыпуска токена
+func RequireRole(repo *repositories.UserRepository, role string, logger *logrus.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := r.Context().Value("userID").(uint)
+
+			user, err := repo.GetByID(userID)
+			if err != nil || user == nil || user.Role != role {
+				logger.Warnf("User %d denied access to %s: role %s required", userID, r.URL.Path, role)
+				respondWithError(w, http.StatusForbidden, "Access denied")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -1,11 +1,25 @@
 package models
 
-import "time"
+import (
+    "bank-service/src/money"
+    "time"
+)
+
+const (
+    AccountActive = "active"
+    AccountFrozen = "frozen"
+)
 
 type Account struct {
-    ID        uint      `json:"id"`
-    UserID    uint      `json:"user_id" validate:"required"`
-    Balance   float64   `json:"balance" validate:"gte=0"`
-    Currency  string    `json:"currency" validate:"required,eq=RUB"`
-    CreatedAt time.Time `json:"created_at"`
-}
\ No newline at end of file
+    ID        uint         `json:"id"`
+    UserID    uint         `json:"user_id" validate:"required"`
+    Balance   money.Amount `json:"balance" validate:"gte=0"` // учетный остаток по проводкам
+    Currency  string       `json:"currency" validate:"required,oneof=RUB USD EUR CNY GBP CHF KZT"`
+    Status    string       `json:"status"`
+    CreatedAt time.Time    `json:"created_at"`
+
+    // Заблокировано авторизациями по картам; списывать со счета
+    // можно только доступный остаток AvailableBalance = Balance - HoldAmount
+    HoldAmount       money.Amount `json:"hold_amount"`
+    AvailableBalance money.Amount `json:"available_balance"`
+}
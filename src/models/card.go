@@ -5,15 +5,43 @@ import (
 	"github.com/go-playground/validator/v10"
 )
 
+// Статусы карты
+const (
+	CardActive  = "active"
+	CardBlocked = "blocked" // временная блокировка клиентом
+	CardLost    = "lost"    // заблокирована навсегда (утеря, кража)
+	CardExpired = "expired"
+	CardClosed  = "closed"
+)
+
 type Card struct {
 	ID            uint      `json:"id"`
 	UserID        uint      `json:"user_id" validate:"required"`
 	AccountID     uint      `json:"account_id" validate:"required"`
-	EncryptedData string    `json:"encrypted_data"` // PGP encrypted (number + expiry)
-	Hmac          string    `json:"hmac"`           // HMAC-SHA256 of encrypted data
+	EncryptedData string    `json:"-"`              // PGP encrypted (number + expiry)
+	Hmac          string    `json:"-"`              // HMAC-SHA256 of card number (blind index)
+	KeyID         uint      `json:"-"`              // pgp_keys.id; 0 for cards encrypted before the key store
 	CvvHash       string    `json:"-"`              // bcrypt hash
+	Status        string    `json:"status"`
+	StatusReason  string    `json:"status_reason,omitempty"`
+	ExpiresAt     time.Time `json:"expires_at"`              // last day the card is valid
+	ReissuedFrom  uint      `json:"reissued_from,omitempty"` // card replaced by this one
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
+
+	// Неудачные проверки CVV/срока подряд; сбрасываются успешной проверкой
+	FailedVerifications int `json:"-"`
+}
+
+// Смена статуса карты
+type CardStatusEvent struct {
+	ID         uint      `json:"id"`
+	CardID     uint      `json:"card_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	Reason     string    `json:"reason"`
+	UserID     uint      `json:"user_id,omitempty"` // 0 - системное событие (истечение срока)
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 func (c *Card) ValidateLuhn(number string) bool {
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"bank-service/src/money"
+	"time"
+)
+
+const (
+	HoldAuthorized = "authorized"
+	HoldCaptured   = "captured"
+	HoldVoided     = "voided"
+	HoldExpired    = "expired"
+	HoldReversed   = "reversed" // списание отменено эквайрером, деньги возвращены
+)
+
+// Эквайрер, отправляющий авторизации по картам банка
+type Acquirer struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Авторизация по карте: Amount в валюте счета заблокирован до списания,
+// отмены или ExpiresAt. Списать можно не больше Amount, остаток
+// разблокируется.
+type CardHold struct {
+	ID             uint         `json:"id"`
+	AcquirerID     uint         `json:"acquirer_id"`
+	CardID         uint         `json:"card_id"`
+	AccountID      uint         `json:"-"`
+	Amount         money.Amount `json:"amount"`
+	Currency       string       `json:"currency"`
+	Merchant       string       `json:"merchant,omitempty"`
+	Reference      string       `json:"reference,omitempty"`
+	AuthCode       string       `json:"auth_code"`
+	Status         string       `json:"status"`
+	CapturedAmount money.Amount `json:"captured_amount,omitempty"`
+	TransactionID  uint         `json:"transaction_id,omitempty"`
+
+	ReversalTransactionID uint `json:"reversal_transaction_id,omitempty"`
+	ExpiresAt      time.Time    `json:"expires_at"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
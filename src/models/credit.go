@@ -1,14 +1,41 @@
 package models
 
-import "time"
+import (
+    "bank-service/src/money"
+    "time"
+)
+
+// Статусы кредита
+const (
+    CreditStatusActive    = "active"
+    CreditStatusOverdue   = "overdue"   // есть просрочка дольше порога
+    CreditStatusDefaulted = "defaulted" // просрочка дольше порога дефолта
+    CreditStatusClosed    = "closed"
+)
+
+// Виды графика платежей
+const (
+    ScheduleAnnuity        = "annuity"        // равные платежи
+    ScheduleDifferentiated = "differentiated" // равные доли основного долга, проценты на остаток
+)
 
 type Credit struct {
-    ID         uint      `json:"id"`
-    UserID     uint      `json:"user_id"`
-    AccountID  uint      `json:"account_id"`
-    Amount     float64   `json:"amount"`
-    Rate       float64   `json:"rate"`
-    Period     int       `json:"period"` // months
-    CreatedAt  time.Time `json:"created_at"`
-    Status     string    `json:"status"`
-}
\ No newline at end of file
+    ID           uint         `json:"id"`
+    UserID       uint         `json:"user_id"`
+    AccountID    uint         `json:"account_id"`
+    Amount       money.Amount `json:"amount"`
+    Rate         float64      `json:"rate"`
+    Period       int          `json:"period"` // months
+    ScheduleType string       `json:"schedule_type"`
+    CreatedAt    time.Time    `json:"created_at"`
+    Status       string       `json:"status"`
+
+    // Остаток основного долга
+    OutstandingPrincipal money.Amount `json:"outstanding_principal"`
+
+    IssueFee  money.Amount `json:"issue_fee"`
+    Insurance money.Amount `json:"insurance"`
+    PSK       float64      `json:"psk"` // полная стоимость кредита, % годовых
+
+    DisbursementTransactionID uint `json:"disbursement_transaction_id,omitempty"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+    "bank-service/src/money"
+    "time"
+)
+
+// Статусы заявки: submitted -> scoring -> approved/rejected -> signed -> active
+const (
+    ApplicationStatusSubmitted = "submitted"
+    ApplicationStatusScoring   = "scoring"
+    ApplicationStatusApproved  = "approved"
+    ApplicationStatusRejected  = "rejected"
+    ApplicationStatusSigned    = "signed"
+    ApplicationStatusActive    = "active"
+)
+
+type CreditApplication struct {
+    ID           uint         `json:"id"`
+    UserID       uint         `json:"user_id"`
+    AccountID    uint         `json:"account_id"`
+    Amount       money.Amount `json:"amount"`
+    Rate         float64      `json:"rate"`
+    Period       int          `json:"period"` // months
+    ScheduleType string       `json:"schedule_type"`
+    IssueFee     money.Amount `json:"issue_fee"`
+    Insurance    money.Amount `json:"insurance"`
+    PSK          float64      `json:"psk"` // ПСК на момент подачи, %
+    Status       string       `json:"status"`
+
+    // Результат скоринга; RejectReasons - коды причин отказа
+    Score         int      `json:"score"`
+    RejectReasons []string `json:"reject_reasons,omitempty"`
+
+    CreditID  uint      `json:"credit_id,omitempty"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
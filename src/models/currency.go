@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const CurrencyRUB = "RUB"
+
+// Валюты, в которых открываются счета. Для каждой должны быть заведены
+// системные счета cash_in и fx_position.
+var SupportedCurrencies = []string{"RUB", "USD", "EUR", "CNY", "GBP", "CHF", "KZT"}
+
+func IsSupportedCurrency(currency string) bool {
+    for _, c := range SupportedCurrencies {
+        if c == currency {
+            return true
+        }
+    }
+    return false
+}
+
+// Официальный курс ЦБ: Value рублей за Nominal единиц валюты
+type ExchangeRate struct {
+    Currency  string    `json:"currency"`
+    Date      time.Time `json:"date"`
+    Nominal   int       `json:"nominal"`
+    Value     float64   `json:"value"`
+    CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"bank-service/src/money"
+	"time"
+)
+
+// Тарифицируемые операции
+const (
+	FeeTransfer              = "transfer"
+	FeeCrossCurrencyTransfer = "cross_currency_transfer"
+	FeeCardIssuance          = "card_issuance"
+	FeeEarlyRepayment        = "early_repayment"
+)
+
+// Правило комиссии; суммы в рублях
+type FeeRule struct {
+	ID         uint         `json:"id"`
+	Operation  string       `json:"operation"`
+	MinVolume  money.Amount `json:"min_volume"` // нижняя граница месячного оборота
+	Flat       money.Amount `json:"flat"`
+	Percent    float64      `json:"percent"`
+	MinFee     money.Amount `json:"min_fee"`
+	MaxFee     money.Amount `json:"max_fee,omitempty"` // 0 - без ограничения
+	FreeCount  int          `json:"free_count"`
+	FreeAmount money.Amount `json:"free_amount"`
+}
+
+// Комиссия за операцию: Amount и Fee - в валюте счета, VolumeRUB - сумма
+// операции в рублях для месячного оборота
+type FeeCharge struct {
+	ID               uint         `json:"id"`
+	UserID           uint         `json:"user_id"`
+	Operation        string       `json:"operation"`
+	RuleID           uint         `json:"rule_id,omitempty"`
+	Amount           money.Amount `json:"amount"`
+	Currency         string       `json:"currency"`
+	VolumeRUB        money.Amount `json:"volume_rub"`
+	Fee              money.Amount `json:"fee"`
+	TransactionID    uint         `json:"transaction_id,omitempty"`
+	FeeTransactionID uint         `json:"fee_transaction_id,omitempty"`
+	CreatedAt        time.Time    `json:"created_at"`
+}
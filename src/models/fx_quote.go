@@ -0,0 +1,27 @@
+package models
+
+import (
+    "bank-service/src/money"
+    "time"
+)
+
+// Котировка обмена валюты между счетами клиента: Amount в FromCurrency
+// обменивается на ToAmount в ToCurrency по курсу Rate до ExpiresAt
+type FXQuote struct {
+    ID            string       `json:"id"`
+    UserID        uint         `json:"user_id"`
+    FromAccountID uint         `json:"from_account_id"`
+    ToAccountID   uint         `json:"to_account_id"`
+    FromCurrency  string       `json:"from_currency"`
+    ToCurrency    string       `json:"to_currency"`
+    Amount        money.Amount `json:"amount"`
+    ToAmount      money.Amount `json:"to_amount"`
+    CBRRate       float64      `json:"cbr_rate"` // официальный курс ЦБ
+    Rate          float64      `json:"rate"`     // курс для клиента с учетом спреда
+    Spread        float64      `json:"spread"`   // спред банка, %
+    ExpiresAt     time.Time    `json:"expires_at"`
+
+    TransactionID uint       `json:"transaction_id,omitempty"`
+    ExecutedAt    *time.Time `json:"executed_at,omitempty"`
+    CreatedAt     time.Time  `json:"created_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Сохраненный результат запроса с заголовком Idempotency-Key.
+// StatusCode == 0 означает, что исходный запрос еще выполняется.
+type IdempotencyKey struct {
+	UserID       uint      `json:"user_id"`
+	Key          string    `json:"key"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	RequestHash  string    `json:"request_hash"`
+	StatusCode   int       `json:"status_code"`
+	ResponseBody []byte    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"bank-service/src/money"
+	"time"
+)
+
+// Системные счета банка (таблица system_accounts)
+const (
+	SystemAccountCashIn             = "cash_in"
+	SystemAccountBankRevenue        = "bank_revenue"
+	SystemAccountPenalties          = "penalties"
+	SystemAccountCreditDisbursement = "credit_disbursement"
+	SystemAccountInsurance          = "insurance"
+	SystemAccountFXPosition         = "fx_position"     // валютная позиция, по счету на каждую валюту
+	SystemAccountCardSettlement     = "card_settlement" // расчеты с эквайерами по картам
+)
+
+const (
+	PostingDebit  = "debit"
+	PostingCredit = "credit"
+)
+
+// Проводка журнала: набор дебетовых и кредитовых записей с нулевой суммой
+type JournalEntry struct {
+	ID            uint      `json:"id"`
+	TransactionID uint      `json:"transaction_id,omitempty"`
+	Kind          string    `json:"kind"`
+	Description   string    `json:"description,omitempty"`
+	Postings      []Posting `json:"postings"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Запись по одному счету: клиентскому (AccountID) либо системному (SystemAccount).
+// Баланс счета равен сумме кредитовых записей за вычетом дебетовых.
+type Posting struct {
+	ID            uint         `json:"id"`
+	EntryID       uint         `json:"entry_id"`
+	AccountID     uint         `json:"account_id,omitempty"`
+	SystemAccount string       `json:"system_account,omitempty"`
+	Direction     string       `json:"direction"`
+	Amount        money.Amount `json:"amount"`
+	Currency      string       `json:"currency"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "bank-service/src/money"
+
+// Область действия лимита
+const (
+	LimitScopeUser = "user" // все операции клиента
+	LimitScopeCard = "card" // операции по одной карте
+)
+
+// Набор лимитов; суммы в рублях
+type Limits struct {
+	SingleMax  money.Amount `json:"single_max"`  // одна операция
+	DailyMax   money.Amount `json:"daily_max"`   // сумма за день
+	MonthlyMax money.Amount `json:"monthly_max"` // сумма за месяц
+	DailyCount int          `json:"daily_count"` // число операций за день
+}
+
+// Израсходовано с начала дня и месяца
+type LimitUsage struct {
+	DailyTotal   money.Amount `json:"daily_total"`
+	DailyCount   int          `json:"daily_count"`
+	MonthlyTotal money.Amount `json:"monthly_total"`
+}
@@ -1,12 +1,28 @@
 package models
 
-import "time"
+import (
+    "bank-service/src/money"
+    "time"
+)
 
+// Строка графика: Amount = Principal + Interest,
+// RemainingBalance - остаток основного долга после платежа.
+// Строка оплачена, когда погашены долг, проценты и неустойка.
 type PaymentSchedule struct {
-    ID        uint      `json:"id"`
-    CreditID  uint      `json:"credit_id"`
-    DueDate   time.Time `json:"due_date"`
-    Amount    float64   `json:"amount"`
-    Paid      bool      `json:"paid"`
+    ID               uint         `json:"id"`
+    CreditID         uint         `json:"credit_id"`
+    DueDate          time.Time    `json:"due_date"`
+    Amount           money.Amount `json:"amount"`
+    Principal        money.Amount `json:"principal"`
+    Interest         money.Amount `json:"interest"`
+    RemainingBalance money.Amount `json:"remaining_balance"`
+    Paid             bool         `json:"paid"`
+
+    // Погашено по строке; Penalty - вся начисленная неустойка
+    PrincipalPaid money.Amount `json:"principal_paid"`
+    InterestPaid  money.Amount `json:"interest_paid"`
+    Penalty       money.Amount `json:"penalty"`
+    PenaltyPaid   money.Amount `json:"penalty_paid"`
+
     CreatedAt time.Time `json:"created_at"`
 }
@@ -0,0 +1,17 @@
+package models
+
+import (
+    "bank-service/src/money"
+    "time"
+)
+
+// Неустойка за один день просрочки строки графика
+type PenaltyAccrual struct {
+    ID          uint         `json:"id"`
+    ScheduleID  uint         `json:"schedule_id"`
+    AccrualDate time.Time    `json:"accrual_date"`
+    BaseAmount  money.Amount `json:"base_amount"` // просроченные основной долг и проценты
+    Rate        float64      `json:"rate"`        // % в день
+    Amount      money.Amount `json:"amount"`
+    CreatedAt   time.Time    `json:"created_at"`
+}
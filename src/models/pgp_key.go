@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+const (
+	PGPKeyActive  = "active"  // ключ для шифрования новых данных, только один
+	PGPKeyRetired = "retired" // только для расшифровки
+)
+
+// Версия ключа PGP. SealedKey - закрытый ключ, зашифрованный мастер-ключом.
+type PGPKey struct {
+	ID        uint       `json:"id"`
+	KeyID     string     `json:"key_id"`
+	SealedKey []byte     `json:"-"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+    "bank-service/src/money"
+    "time"
+)
+
+// Периодичность регулярного перевода
+const (
+    FrequencyOnce    = "once"    // однократно в дату StartDate
+    FrequencyWeekly  = "weekly"  // каждые 7 дней начиная с StartDate
+    FrequencyMonthly = "monthly" // ежемесячно в день DayOfMonth
+)
+
+const (
+    StandingOrderActive    = "active"
+    StandingOrderCompleted = "completed"
+    StandingOrderCancelled = "cancelled"
+)
+
+const (
+    RunSucceeded = "succeeded"
+    RunFailed    = "failed"
+)
+
+// Регулярный (или отложенный) перевод между счетами. Завершается после
+// EndDate или MaxRuns исполнений, если они заданы.
+type StandingOrder struct {
+    ID            uint         `json:"id"`
+    UserID        uint         `json:"user_id"`
+    FromAccountID uint         `json:"from_account_id"`
+    ToAccountID   uint         `json:"to_account_id"`
+    Amount        money.Amount `json:"amount"`
+    Frequency     string       `json:"frequency"`
+    DayOfMonth    int          `json:"day_of_month,omitempty"`
+    StartDate     time.Time    `json:"start_date"`
+    EndDate       *time.Time   `json:"end_date,omitempty"`
+    MaxRuns       int          `json:"max_runs,omitempty"`
+    RunsCount     int          `json:"runs_count"`
+
+    // Ближайшее исполнение; повторные попытки при нехватке средств
+    NextRunDate   *time.Time `json:"next_run_date,omitempty"`
+    Attempt       int        `json:"attempt"`
+    NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+
+    Status    string    `json:"status"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Результат одной попытки исполнения
+type StandingOrderRun struct {
+    ID            uint      `json:"id"`
+    OrderID       uint      `json:"order_id"`
+    ScheduledFor  time.Time `json:"scheduled_for"`
+    Attempt       int       `json:"attempt"`
+    Status        string    `json:"status"`
+    Error         string    `json:"error,omitempty"`
+    TransactionID uint      `json:"transaction_id,omitempty"`
+    CreatedAt     time.Time `json:"created_at"`
+}
@@ -1,12 +1,42 @@
 package models
 
-import "time"
+import (
+    "bank-service/src/money"
+    "time"
+)
+
+// Типы операций
+const (
+    TransactionTransfer      = "transfer"
+    TransactionDeposit       = "deposit"
+    TransactionDisbursement  = "credit_disbursement"
+    TransactionCreditFee     = "credit_fee"
+    TransactionCreditPayment = "credit_payment"
+    TransactionPenalty       = "penalty"
+    TransactionExchange      = "currency_exchange"
+    TransactionReversal      = "reversal"
+    TransactionFee           = "fee"
+    TransactionCardPayment   = "card_payment"
+)
 
 type Transaction struct {
-    ID            uint      `json:"id"`
-    FromAccountID uint      `json:"from_account_id,omitempty"`
-    ToAccountID   uint      `json:"to_account_id"`
-    Amount        float64   `json:"amount"`
-    Currency      string    `json:"currency"`
-    CreatedAt     time.Time `json:"created_at"`
-}
\ No newline at end of file
+    ID            uint         `json:"id"`
+    FromAccountID uint         `json:"from_account_id,omitempty"`
+    ToAccountID   uint         `json:"to_account_id,omitempty"`
+    Amount        money.Amount `json:"amount"`
+    Currency      string       `json:"currency"`
+
+    // Зачисленная сумма в валюте получателя и примененный курс
+    // (единиц ToCurrency за единицу Currency) для переводов между валютами
+    ToAmount     money.Amount `json:"to_amount,omitempty"`
+    ToCurrency   string       `json:"to_currency,omitempty"`
+    ExchangeRate float64      `json:"exchange_rate,omitempty"`
+
+    Type          string       `json:"type"`
+    ReversalOf    uint         `json:"reversal_of,omitempty"` // исходный перевод для возврата
+    CardID        uint         `json:"card_id,omitempty"`     // карта, по которой совершена операция
+    CreatedAt     time.Time    `json:"created_at"`
+
+    // Транзакция БД, записавшая операцию; задает порядок курсора синхронизации
+    WriteXID uint64 `json:"-"`
+}
@@ -2,10 +2,18 @@ package models
 
 import "time"
 
+// Роли пользователей
+const (
+	RoleCustomer = "customer"
+	RoleStaff    = "staff" // сотрудник поддержки: возвраты переводов
+)
+
 type User struct {
 	ID           uint      `json:"id"`
 	Username     string    `json:"username"`
 	Email        string    `json:"email"`
+	Phone        string    `json:"phone,omitempty"`
+	Role         string    `json:"role"`
 	PasswordHash string    `json:"-"`
 	CreatedAt    time.Time `json:"created_at"`
 }
@@ -0,0 +1,239 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrTooManyDecimals  = errors.New("amount has more than 2 decimal places")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+)
+
+// Количество минимальных единиц (копеек, центов) в единице валюты
+const minorUnits = 100
+
+// Десятичная запись без экспоненты, дробей и hex-форм, которые
+// понимает big.Rat.SetString
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// Сумма в минимальных единицах валюты. Все денежные расчеты ведутся
+// в целых числах, дробные значения появляются только внутри Mul/FromRat
+// и округляются явно выбранным способом.
+type Amount int64
+
+// Способ округления до минимальной единицы
+type RoundingMode int
+
+const (
+	RoundHalfUp   RoundingMode = iota // математическое: 0.005 -> 0.01
+	RoundHalfEven                     // банковское: 0.005 -> 0.00, 0.015 -> 0.02
+	RoundDown                         // к нулю (в пользу клиента при начислениях)
+	RoundUp                           // от нуля
+)
+
+func FromMinor(minor int64) Amount {
+	return Amount(minor)
+}
+
+func (a Amount) Minor() int64 {
+	return int64(a)
+}
+
+// Разбор десятичной строки вида "1234.56" без потери точности
+func Parse(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	if !decimalPattern.MatchString(s) {
+		return 0, ErrInvalidAmount
+	}
+	if i := strings.IndexByte(s, '.'); i >= 0 && len(s)-i-1 > 2 {
+		return 0, ErrTooManyDecimals
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, ErrInvalidAmount
+	}
+	return FromRat(r, RoundHalfUp)
+}
+
+// Перевод значения в единицах валюты в минимальные единицы с округлением.
+// Значения вне диапазона int64 дают ErrInvalidAmount.
+func FromRat(r *big.Rat, mode RoundingMode) (Amount, error) {
+	scaled := new(big.Rat).Mul(r, big.NewRat(minorUnits, 1))
+	v := roundRat(scaled, mode)
+	if !v.IsInt64() {
+		return 0, ErrInvalidAmount
+	}
+	return Amount(v.Int64()), nil
+}
+
+// Значение в единицах валюты
+func (a Amount) Rat() *big.Rat {
+	return big.NewRat(int64(a), minorUnits)
+}
+
+// Умножение на коэффициент (ставку, долю) с явным округлением.
+// Коэффициенты приходят из расчетов, а не от клиента, поэтому выход
+// за диапазон int64 - ошибка программы, а не входных данных.
+func (a Amount) Mul(k *big.Rat, mode RoundingMode) Amount {
+	v, err := FromRat(new(big.Rat).Mul(a.Rat(), k), mode)
+	if err != nil {
+		panic(fmt.Sprintf("money: %s * %s overflows", a, k.RatString()))
+	}
+	return v
+}
+
+// Десятичное представление числа с плавающей точкой без двоичных хвостов:
+// 12.3 превращается в 123/10, а не в 12.300000000000000710...
+func Decimal(f float64) *big.Rat {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, 64))
+	return r
+}
+
+// Процент в виде доли: Percent(12.5) == 1/8
+func Percent(p float64) *big.Rat {
+	return new(big.Rat).Quo(Decimal(p), big.NewRat(100, 1))
+}
+
+func (a Amount) String() string {
+	sign := ""
+	v := int64(a)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/minorUnits, v%minorUnits)
+}
+
+func (a Amount) Float64() float64 {
+	f, _ := a.Rat().Float64()
+	return f
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// Принимает как число (100.5), так и строку ("100.50")
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// Чтение DECIMAL из database/sql. Агрегаты (AVG и т.п.) могут вернуть
+// больше двух знаков - они округляются математически.
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = 0
+		return nil
+	case int64:
+		return a.scanRat(new(big.Rat).SetInt64(v))
+	case float64:
+		return a.scanRat(Decimal(v))
+	case []byte:
+		return a.scanString(string(v))
+	case string:
+		return a.scanString(v)
+	}
+	return fmt.Errorf("cannot scan %T into money.Amount", src)
+}
+
+func (a *Amount) scanString(s string) error {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return ErrInvalidAmount
+	}
+	return a.scanRat(r)
+}
+
+func (a *Amount) scanRat(r *big.Rat) error {
+	v, err := FromRat(r, RoundHalfUp)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// Сумма вместе с валютой
+type Money struct {
+	Amount   Amount `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func New(amount Amount, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Amount: m.Amount - other.Amount, Currency: m.Currency}, nil
+}
+
+func (m Money) String() string {
+	return m.Amount.String() + " " + m.Currency
+}
+
+func roundRat(r *big.Rat, mode RoundingMode) *big.Int {
+	num := new(big.Int).Set(r.Num())
+	den := r.Denom()
+
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() == 0 {
+		return quo
+	}
+
+	// Направление, в котором увеличивается модуль
+	step := big.NewInt(int64(num.Sign()))
+	twiceRem := new(big.Int).Abs(rem)
+	twiceRem.Lsh(twiceRem, 1)
+	cmpHalf := twiceRem.Cmp(den)
+
+	switch mode {
+	case RoundDown:
+		return quo
+	case RoundUp:
+		return quo.Add(quo, step)
+	case RoundHalfEven:
+		if cmpHalf > 0 || (cmpHalf == 0 && quo.Bit(0) == 1) {
+			return quo.Add(quo, step)
+		}
+		return quo
+	default:
+		if cmpHalf >= 0 {
+			return quo.Add(quo, step)
+		}
+		return quo
+	}
+}
@@ -2,14 +2,20 @@ package repositories
 
 import (
 	"bank-service/src/models"
+	"bank-service/src/money"
 	"database/sql"
 	"errors"
-	
+	"time"
+
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 )
 
 var ErrAccountNotFound = errors.New("account not found")
 
+// Колонки счета в порядке сканирования scanAccount
+const accountColumns = `id, user_id, balance, hold_amount, currency, status, created_at`
+
 type AccountRepository struct {
 	db     *sql.DB
 	logger *logrus.Logger
@@ -22,19 +28,17 @@ func NewAccountRepository(db *sql.DB, logger *logrus.Logger) *AccountRepository
 func (r *AccountRepository) Create(account *models.Account) error {
 	return r.db.QueryRow(
 		`INSERT INTO accounts (user_id, currency) 
-		 VALUES ($1, $2) RETURNING id, created_at`,
+		 VALUES ($1, $2) RETURNING id, status, created_at`,
 		account.UserID, account.Currency,
-	).Scan(&account.ID, &account.CreatedAt)
+	).Scan(&account.ID, &account.Status, &account.CreatedAt)
 }
 
 func (r *AccountRepository) GetByIDAndUser(accountID, userID uint) (*models.Account, error) {
-	account := &models.Account{}
-	err := r.db.QueryRow(
-		`SELECT id, user_id, balance, currency, created_at 
+	account, err := scanAccount(r.db.QueryRow(
+		`SELECT `+accountColumns+`
 		 FROM accounts WHERE id = $1 AND user_id = $2`,
 		accountID, userID,
-	).Scan(&account.ID, &account.UserID, &account.Balance, &account.Currency, &account.CreatedAt)
-	
+	))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrAccountNotFound
 	}
@@ -42,67 +46,158 @@ func (r *AccountRepository) GetByIDAndUser(accountID, userID uint) (*models.Acco
 }
 
 func (r *AccountRepository) GetByID(accountID uint) (*models.Account, error) {
-	account := &models.Account{}
-	err := r.db.QueryRow(
-		`SELECT id, user_id, balance, currency, created_at 
+	account, err := scanAccount(r.db.QueryRow(
+		`SELECT `+accountColumns+`
 		 FROM accounts WHERE id = $1`,
 		accountID,
-	).Scan(&account.ID, &account.UserID, &account.Balance, &account.Currency, &account.CreatedAt)
-	
+	))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrAccountNotFound
 	}
 	return account, err
 }
 
-func (r *AccountRepository) UpdateBalance(accountID uint, amount float64) error {
-    _, err := r.db.Exec(
-        "UPDATE accounts SET balance = balance + $1 WHERE id = $2",
-        amount,
-        accountID,
-    )
-    return err
-}
+func (r *AccountRepository) GetByUserID(userID uint) ([]models.Account, error) {
+	rows, err := r.db.Query(
+		`SELECT `+accountColumns+`
+		 FROM accounts WHERE user_id = $1 ORDER BY id`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-func (r *AccountRepository) UpdateBalanceTx(tx *sql.Tx, accountID uint, amount float64) error {
-    res, err := tx.Exec(
-        "UPDATE accounts SET balance = balance + $1 WHERE id = $2",
-        amount,
-        accountID,
-    )
-    if err != nil {
-        return err
-    }
-    rowsAffected, err := res.RowsAffected()
-    if err != nil {
-        return err
-    }
-    if rowsAffected == 0 {
-        return ErrAccountNotFound
-    }
-    return nil
+	var accounts []models.Account
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, *account)
+	}
+	return accounts, rows.Err()
 }
 
-func (r *AccountRepository) BeginTx() (*sql.Tx, error) {
-    return r.db.Begin()
+// Счет с блокировкой строки до конца транзакции
+func (r *AccountRepository) GetByIDForUpdateTx(tx *sql.Tx, accountID uint) (*models.Account, error) {
+	account, err := scanAccount(tx.QueryRow(
+		`SELECT `+accountColumns+`
+		 FROM accounts WHERE id = $1 FOR UPDATE`,
+		accountID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAccountNotFound
+	}
+	return account, err
 }
 
-func (r *CreditRepository) GetByAccountID(accountID uint) ([]models.Credit, error) {
-	query := `SELECT id, user_id, account_id, amount, rate, period, created_at, status 
-	          FROM credits WHERE account_id = $1`
-	rows, err := r.db.Query(query, accountID)
+// Оба счета перевода с блокировкой строк. Строки блокируются в порядке
+// возрастания id независимо от направления перевода, поэтому встречные
+// переводы между одними и теми же счетами не блокируют друг друга взаимно.
+func (r *AccountRepository) GetPairForUpdateTx(tx *sql.Tx, firstID, secondID uint) (first, second *models.Account, err error) {
+	rows, err := tx.Query(
+		`SELECT `+accountColumns+`
+		 FROM accounts WHERE id IN ($1, $2) ORDER BY id FOR UPDATE`,
+		firstID, secondID,
+	)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
-	var credits []models.Credit
 	for rows.Next() {
-		var c models.Credit
-		if err := rows.Scan(&c.ID, &c.UserID, &c.AccountID, &c.Amount, &c.Rate, &c.Period, &c.CreatedAt, &c.Status); err != nil {
-			return nil, err
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		if account.ID == firstID {
+			first = account
+		} else {
+			second = account
 		}
-		credits = append(credits, c)
 	}
-	return credits, nil
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	if first == nil || second == nil {
+		return nil, nil, ErrAccountNotFound
+	}
+	return first, second, nil
+}
+
+// Изменение заблокированной суммы счета (delta < 0 - разблокировка).
+// Счет должен быть заблокирован вызывающим.
+func (r *AccountRepository) AddHoldTx(tx *sql.Tx, accountID uint, delta money.Amount) error {
+	res, err := tx.Exec(`UPDATE accounts SET hold_amount = hold_amount + $1 WHERE id = $2`, delta, accountID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
+
+func (r *AccountRepository) BeginTx() (*sql.Tx, error) {
+    return r.db.Begin()
+}
+
+// Число попыток транзакции при конфликте сериализации или взаимной блокировке
+const txMaxAttempts = 3
+
+// Выполнение fn в транзакции с повтором, если PostgreSQL прервал ее
+// из-за конфликта сериализации (40001) или взаимной блокировки (40P01).
+// fn должна быть готова к повторному вызову.
+func (r *AccountRepository) RunInTx(fn func(tx *sql.Tx) error) error {
+	var err error
+	for attempt := 1; attempt <= txMaxAttempts; attempt++ {
+		if err = r.runInTx(fn); !isRetryableTxError(err) {
+			return err
+		}
+		r.logger.WithError(err).Warnf("Transaction aborted, retrying (attempt %d)", attempt)
+		time.Sleep(time.Duration(attempt*attempt) * 10 * time.Millisecond)
+	}
+	return err
+}
+
+func (r *AccountRepository) runInTx(fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func isRetryableTxError(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && (pqErr.Code == "40001" || pqErr.Code == "40P01")
+}
+
+func scanAccount(row rowScanner) (*models.Account, error) {
+	account := &models.Account{}
+	err := row.Scan(
+		&account.ID,
+		&account.UserID,
+		&account.Balance,
+		&account.HoldAmount,
+		&account.Currency,
+		&account.Status,
+		&account.CreatedAt,
+	)
+	account.AvailableBalance = account.Balance - account.HoldAmount
+	return account, err
+}
+
+func (r *CreditRepository) GetByAccountID(accountID uint) ([]models.Credit, error) {
+	return r.queryCredits(`SELECT `+creditColumns+` FROM credits WHERE account_id = $1`, accountID)
 }
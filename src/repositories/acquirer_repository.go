@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"bank-service/src/models"
+	"database/sql"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+)
+
+var ErrAcquirerNotFound = errors.New("acquirer not found")
+
+type AcquirerRepository struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+func NewAcquirerRepository(db *sql.DB, logger *logrus.Logger) *AcquirerRepository {
+	return &AcquirerRepository{db: db, logger: logger}
+}
+
+func (r *AcquirerRepository) Create(acquirer *models.Acquirer, apiKeyHash string) error {
+	return r.db.QueryRow(
+		`INSERT INTO acquirers (name, api_key_hash) VALUES ($1, $2)
+		 RETURNING id, active, created_at`,
+		acquirer.Name, apiKeyHash,
+	).Scan(&acquirer.ID, &acquirer.Active, &acquirer.CreatedAt)
+}
+
+// Действующий эквайрер по SHA-256 ключа API
+func (r *AcquirerRepository) GetByAPIKeyHash(apiKeyHash string) (*models.Acquirer, error) {
+	acquirer := &models.Acquirer{}
+	err := r.db.QueryRow(
+		`SELECT id, name, active, created_at FROM acquirers WHERE api_key_hash = $1 AND active`,
+		apiKeyHash,
+	).Scan(&acquirer.ID, &acquirer.Name, &acquirer.Active, &acquirer.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAcquirerNotFound
+	}
+	return acquirer, err
+}
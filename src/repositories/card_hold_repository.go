@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"bank-service/src/models"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrHoldNotFound        = errors.New("authorization not found")
+	ErrHoldReferenceExists = errors.New("authorization with this reference already exists")
+)
+
+// Колонки авторизации в порядке сканирования scanCardHold
+const cardHoldColumns = `id, acquirer_id, card_id, account_id, amount, currency, COALESCE(merchant, ''),
+	COALESCE(reference, ''), auth_code, status, COALESCE(captured_amount, 0), COALESCE(transaction_id, 0),
+	COALESCE(reversal_transaction_id, 0), expires_at, created_at, updated_at`
+
+type CardHoldRepository struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+func NewCardHoldRepository(db *sql.DB, logger *logrus.Logger) *CardHoldRepository {
+	return &CardHoldRepository{db: db, logger: logger}
+}
+
+func (r *CardHoldRepository) CreateTx(tx *sql.Tx, hold *models.CardHold) error {
+	var merchant, reference interface{}
+	if hold.Merchant != "" {
+		merchant = hold.Merchant
+	}
+	if hold.Reference != "" {
+		reference = hold.Reference
+	}
+	err := tx.QueryRow(
+		`INSERT INTO card_holds (acquirer_id, card_id, account_id, amount, currency,
+		                         merchant, reference, auth_code, status, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		 RETURNING id, created_at, updated_at`,
+		hold.AcquirerID, hold.CardID, hold.AccountID, hold.Amount, hold.Currency,
+		merchant, reference, hold.AuthCode, hold.Status, hold.ExpiresAt,
+	).Scan(&hold.ID, &hold.CreatedAt, &hold.UpdatedAt)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrHoldReferenceExists
+	}
+	return err
+}
+
+func (r *CardHoldRepository) GetByIDAndAcquirer(id, acquirerID uint) (*models.CardHold, error) {
+	hold, err := scanCardHold(r.db.QueryRow(
+		`SELECT `+cardHoldColumns+` FROM card_holds WHERE id = $1 AND acquirer_id = $2`,
+		id, acquirerID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrHoldNotFound
+	}
+	return hold, err
+}
+
+// Авторизация с блокировкой строки; acquirerID = 0 - без проверки
+// эквайрера (для фонового истечения)
+func (r *CardHoldRepository) GetByIDForUpdateTx(tx *sql.Tx, id, acquirerID uint) (*models.CardHold, error) {
+	hold, err := scanCardHold(tx.QueryRow(
+		`SELECT `+cardHoldColumns+` FROM card_holds
+		 WHERE id = $1 AND ($2 = 0 OR acquirer_id = $2) FOR UPDATE`,
+		id, acquirerID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrHoldNotFound
+	}
+	return hold, err
+}
+
+// Авторизация по идентификатору операции эквайрера с блокировкой строки
+func (r *CardHoldRepository) GetByReferenceForUpdateTx(tx *sql.Tx, acquirerID uint, reference string) (*models.CardHold, error) {
+	hold, err := scanCardHold(tx.QueryRow(
+		`SELECT `+cardHoldColumns+` FROM card_holds
+		 WHERE acquirer_id = $1 AND reference = $2 FOR UPDATE`,
+		acquirerID, reference,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrHoldNotFound
+	}
+	return hold, err
+}
+
+// Id действующих авторизаций, срок которых истек к моменту now
+func (r *CardHoldRepository) GetExpiredIDs(now time.Time, limit int) ([]uint, error) {
+	rows, err := r.db.Query(
+		`SELECT id FROM card_holds WHERE status = $1 AND expires_at <= $2 ORDER BY expires_at LIMIT $3`,
+		models.HoldAuthorized, now, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uint
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Сохранение итога авторизации: статус, списанная сумма и операция списания
+func (r *CardHoldRepository) FinishTx(tx *sql.Tx, hold *models.CardHold) error {
+	return tx.QueryRow(
+		`UPDATE card_holds SET status = $1, captured_amount = $2, transaction_id = $3,
+		        reversal_transaction_id = $4, updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $5 RETURNING updated_at`,
+		hold.Status, hold.CapturedAmount, nullableID(hold.TransactionID),
+		nullableID(hold.ReversalTransactionID), hold.ID,
+	).Scan(&hold.UpdatedAt)
+}
+
+func scanCardHold(row rowScanner) (*models.CardHold, error) {
+	hold := &models.CardHold{}
+	err := row.Scan(
+		&hold.ID,
+		&hold.AcquirerID,
+		&hold.CardID,
+		&hold.AccountID,
+		&hold.Amount,
+		&hold.Currency,
+		&hold.Merchant,
+		&hold.Reference,
+		&hold.AuthCode,
+		&hold.Status,
+		&hold.CapturedAmount,
+		&hold.TransactionID,
+		&hold.ReversalTransactionID,
+		&hold.ExpiresAt,
+		&hold.CreatedAt,
+		&hold.UpdatedAt,
+	)
+	return hold, err
+}
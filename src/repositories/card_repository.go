@@ -4,7 +4,8 @@ import (
 	"bank-service/src/models"
 	"database/sql"
 	"errors"
-	
+	"time"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -12,6 +13,10 @@ var (
 	ErrCardNotFound = errors.New("card not found")
 )
 
+// Колонки карты в порядке сканирования scanCard
+const cardColumns = `id, user_id, account_id, encrypted_data, hmac, cvv_hash, COALESCE(key_id, 0), status,
+	COALESCE(status_reason, ''), expires_at, COALESCE(reissued_from, 0), failed_verifications, created_at, updated_at`
+
 type CardRepository struct {
 	db     *sql.DB
 	logger *logrus.Logger
@@ -22,34 +27,36 @@ func NewCardRepository(db *sql.DB, logger *logrus.Logger) *CardRepository {
 }
 
 func (r *CardRepository) Create(card *models.Card) error {
+	return r.createWith(r.db, card)
+}
+
+func (r *CardRepository) CreateTx(tx *sql.Tx, card *models.Card) error {
+	return r.createWith(tx, card)
+}
+
+func (r *CardRepository) createWith(q queryRower, card *models.Card) error {
 	query := `INSERT INTO cards 
-		(user_id, account_id, encrypted_data, hmac, cvv_hash) 
-		VALUES ($1, $2, $3, $4, $5) 
+		(user_id, account_id, encrypted_data, hmac, cvv_hash, key_id, status, expires_at, reissued_from) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) 
 		RETURNING id, created_at, updated_at`
 		
-	return r.db.QueryRow(query,
+	return q.QueryRow(query,
 		card.UserID,
 		card.AccountID,
 		card.EncryptedData,
 		card.Hmac,
 		card.CvvHash,
+		nullableID(card.KeyID),
+		card.Status,
+		card.ExpiresAt,
+		nullableID(card.ReissuedFrom),
 	).Scan(&card.ID, &card.CreatedAt, &card.UpdatedAt)
 }
 
 func (r *CardRepository) GetByIDAndUser(cardID, userID uint) (*models.Card, error) {
-	card := &models.Card{}
-	query := `SELECT id, user_id, account_id, encrypted_data, hmac, created_at, updated_at 
-		FROM cards WHERE id = $1 AND user_id = $2`
+	query := `SELECT ` + cardColumns + ` FROM cards WHERE id = $1 AND user_id = $2`
 	
-	err := r.db.QueryRow(query, cardID, userID).Scan(
-		&card.ID,
-		&card.UserID,
-		&card.AccountID,
-		&card.EncryptedData,
-		&card.Hmac,
-		&card.CreatedAt,
-		&card.UpdatedAt,
-	)
+	card, err := scanCard(r.db.QueryRow(query, cardID, userID))
 	
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrCardNotFound
@@ -57,11 +64,173 @@ func (r *CardRepository) GetByIDAndUser(cardID, userID uint) (*models.Card, erro
 	return card, err
 }
 
+// Карта клиента с блокировкой строки для смены статуса
+func (r *CardRepository) GetByIDAndUserForUpdateTx(tx *sql.Tx, cardID, userID uint) (*models.Card, error) {
+	query := `SELECT ` + cardColumns + ` FROM cards WHERE id = $1 AND user_id = $2 FOR UPDATE`
+
+	card, err := scanCard(tx.QueryRow(query, cardID, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCardNotFound
+	}
+	return card, err
+}
+
+// Статус карты для операции по ней. FOR SHARE не дает заблокировать
+// карту, пока операция не завершена.
+func (r *CardRepository) GetStatusForShareTx(tx *sql.Tx, cardID uint) (string, error) {
+	var status string
+	err := tx.QueryRow(`SELECT status FROM cards WHERE id = $1 FOR SHARE`, cardID).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrCardNotFound
+	}
+	return status, err
+}
+
+// Смена статуса карты с записью в историю. userID = 0 - системное событие.
+func (r *CardRepository) UpdateStatusTx(tx *sql.Tx, card *models.Card, status, reason string, userID uint) error {
+	if _, err := tx.Exec(
+		`UPDATE cards SET status = $1, status_reason = $2, updated_at = CURRENT_TIMESTAMP,
+		     failed_verifications = CASE WHEN $1 = $4 THEN 0 ELSE failed_verifications END
+		 WHERE id = $3`,
+		status, reason, card.ID, models.CardActive,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO card_status_events (card_id, from_status, to_status, reason, user_id)
+		 VALUES ($1, $2, $3, $4, $5)`,
+		card.ID, card.Status, status, reason, nullableID(userID),
+	); err != nil {
+		return err
+	}
+	card.Status = status
+	card.StatusReason = reason
+	return nil
+}
+
+// Учет неудачной проверки реквизитов. Действующая карта, у которой число
+// неудач подряд достигло limit, блокируется в той же транзакции.
+// Возвращает статус карты после учета.
+func (r *CardRepository) RecordFailedVerification(cardID uint, limit int, reason string) (string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	card := &models.Card{ID: cardID}
+	err = tx.QueryRow(
+		`UPDATE cards SET failed_verifications = failed_verifications + 1
+		 WHERE id = $1 RETURNING failed_verifications, status`,
+		cardID,
+	).Scan(&card.FailedVerifications, &card.Status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrCardNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if card.FailedVerifications >= limit && card.Status == models.CardActive {
+		if err := r.UpdateStatusTx(tx, card, models.CardBlocked, reason, 0); err != nil {
+			return "", err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return card.Status, nil
+}
+
+// Сброс счетчика неудачных проверок после успешной. Возвращает false,
+// если карта к этому моменту уже не действует.
+func (r *CardRepository) ResetFailedVerifications(cardID uint) (bool, error) {
+	result, err := r.db.Exec(
+		`UPDATE cards SET failed_verifications = 0 WHERE id = $1 AND status = $2`,
+		cardID, models.CardActive,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// Перевод в expired действующих и временно заблокированных карт, срок
+// которых закончился до today. Возвращает число карт.
+func (r *CardRepository) ExpireDue(today time.Time, reason string) (int64, error) {
+	result, err := r.db.Exec(
+		`WITH due AS (
+		     SELECT id, status FROM cards
+		     WHERE status IN ($1, $2) AND expires_at < $3
+		     FOR UPDATE
+		 ), expired AS (
+		     UPDATE cards c SET status = $4, status_reason = $5, updated_at = CURRENT_TIMESTAMP
+		     FROM due WHERE c.id = due.id
+		     RETURNING c.id, due.status
+		 )
+		 INSERT INTO card_status_events (card_id, from_status, to_status, reason)
+		 SELECT id, status, $4, $5 FROM expired`,
+		models.CardActive, models.CardBlocked, today, models.CardExpired, reason,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Поиск карты по HMAC номера; индекс idx_cards_hmac избавляет
+// от расшифровки всех карт
+func (r *CardRepository) GetByHmac(hmac string) (*models.Card, error) {
+	query := `SELECT ` + cardColumns + ` FROM cards WHERE hmac = $1`
+
+	card, err := scanCard(r.db.QueryRow(query, hmac))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCardNotFound
+	}
+	return card, err
+}
+
 func (r *CardRepository) GetByUser(userID uint) ([]models.Card, error) {
-	query := `SELECT id, account_id, encrypted_data, hmac, created_at, updated_at 
-		FROM cards WHERE user_id = $1`
-		
-	rows, err := r.db.Query(query, userID)
+	query := `SELECT ` + cardColumns + ` FROM cards WHERE user_id = $1 ORDER BY id`
+	return r.list(query, userID)
+}
+
+// Карты с id > afterID, зашифрованные другим ключом, чем keyID. Карты без ключа
+// (до появления хранилища ключей) расшифровать нельзя, они пропускаются.
+func (r *CardRepository) GetByOtherKey(keyID, afterID uint, limit int) ([]models.Card, error) {
+	query := `SELECT ` + cardColumns + ` FROM cards
+		WHERE key_id IS NOT NULL AND key_id <> $1 AND id > $2 ORDER BY id LIMIT $3`
+	return r.list(query, keyID, afterID, limit)
+}
+
+// Незакрытые карты без ключа шифрования (до появления хранилища ключей)
+func (r *CardRepository) CountWithoutKey() (int, error) {
+	var n int
+	err := r.db.QueryRow(
+		`SELECT COUNT(*) FROM cards WHERE key_id IS NULL AND status <> $1`,
+		models.CardClosed,
+	).Scan(&n)
+	return n, err
+}
+
+// Замена шифротекста и индекса карты после перешифрования. Возвращает
+// false, если карту уже перешифровал другой процесс.
+func (r *CardRepository) UpdateEncryption(card *models.Card, oldKeyID uint) (bool, error) {
+	result, err := r.db.Exec(
+		`UPDATE cards SET encrypted_data = $1, hmac = $2, key_id = $3, updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $4 AND key_id = $5`,
+		card.EncryptedData, card.Hmac, card.KeyID, card.ID, oldKeyID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+func (r *CardRepository) list(query string, args ...interface{}) ([]models.Card, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -69,18 +238,48 @@ func (r *CardRepository) GetByUser(userID uint) ([]models.Card, error) {
 
 	var cards []models.Card
 	for rows.Next() {
-		var card models.Card
-		if err := rows.Scan(
-			&card.ID,
-			&card.AccountID,
-			&card.EncryptedData,
-			&card.Hmac,
-			&card.CreatedAt,
-			&card.UpdatedAt,
-		); err != nil {
+		card, err := scanCard(rows)
+		if err != nil {
 			return nil, err
 		}
-		cards = append(cards, card)
+		cards = append(cards, *card)
+	}
+	return cards, rows.Err()
+}
+
+func scanCard(row rowScanner) (*models.Card, error) {
+	card := &models.Card{}
+	err := row.Scan(
+		&card.ID,
+		&card.UserID,
+		&card.AccountID,
+		&card.EncryptedData,
+		&card.Hmac,
+		&card.CvvHash,
+		&card.KeyID,
+		&card.Status,
+		&card.StatusReason,
+		&card.ExpiresAt,
+		&card.ReissuedFrom,
+		&card.FailedVerifications,
+		&card.CreatedAt,
+		&card.UpdatedAt,
+	)
+	return card, err
+}
+
+// Фиксирует показ номера карты по токену jti. Возвращает false,
+// если токен уже использовался.
+func (r *CardRepository) RecordReveal(jti string, cardID, userID uint, tokenExpiresAt time.Time) (bool, error) {
+	result, err := r.db.Exec(
+		`INSERT INTO card_reveals (jti, card_id, user_id, token_expires_at)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (jti) DO NOTHING`,
+		jti, cardID, userID, tokenExpiresAt,
+	)
+	if err != nil {
+		return false, err
 	}
-	return cards, nil
-}
\ No newline at end of file
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
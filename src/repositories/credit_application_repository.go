@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"bank-service/src/models"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+var ErrApplicationNotFound = errors.New("credit application not found")
+
+// Колонки заявки в порядке сканирования scanApplication
+const applicationColumns = `id, user_id, account_id, amount, rate, period, schedule_type,
+	issue_fee, insurance, COALESCE(psk, 0), status, COALESCE(score, 0), reject_reasons,
+	COALESCE(credit_id, 0), created_at, updated_at`
+
+type CreditApplicationRepository struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+func NewCreditApplicationRepository(db *sql.DB, logger *logrus.Logger) *CreditApplicationRepository {
+	return &CreditApplicationRepository{db: db, logger: logger}
+}
+
+func (r *CreditApplicationRepository) Create(app *models.CreditApplication) error {
+	query := `INSERT INTO credit_applications (user_id, account_id, amount, rate, period, schedule_type, issue_fee, insurance, psk, status)
+          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+          RETURNING id, created_at, updated_at`
+	return r.db.QueryRow(query,
+		app.UserID,
+		app.AccountID,
+		app.Amount,
+		app.Rate,
+		app.Period,
+		app.ScheduleType,
+		app.IssueFee,
+		app.Insurance,
+		app.PSK,
+		app.Status,
+	).Scan(&app.ID, &app.CreatedAt, &app.UpdatedAt)
+}
+
+func (r *CreditApplicationRepository) GetByIDAndUser(id, userID uint) (*models.CreditApplication, error) {
+	query := `SELECT ` + applicationColumns + ` FROM credit_applications WHERE id = $1 AND user_id = $2`
+	app, err := scanApplication(r.db.QueryRow(query, id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrApplicationNotFound
+	}
+	return app, err
+}
+
+// Заявка с блокировкой строки: подписание и выдача по одной заявке
+// выполняются строго последовательно
+func (r *CreditApplicationRepository) GetByIDForUpdateTx(tx *sql.Tx, id uint) (*models.CreditApplication, error) {
+	query := `SELECT ` + applicationColumns + ` FROM credit_applications WHERE id = $1 FOR UPDATE`
+	app, err := scanApplication(tx.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrApplicationNotFound
+	}
+	return app, err
+}
+
+func (r *CreditApplicationRepository) GetByUserID(userID uint) ([]models.CreditApplication, error) {
+	query := `SELECT ` + applicationColumns + ` FROM credit_applications WHERE user_id = $1 ORDER BY id`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var apps []models.CreditApplication
+	for rows.Next() {
+		app, err := scanApplication(rows)
+		if err != nil {
+			return nil, err
+		}
+		apps = append(apps, *app)
+	}
+	return apps, rows.Err()
+}
+
+func (r *CreditApplicationRepository) UpdateStatus(id uint, status string) error {
+	_, err := r.db.Exec(
+		"UPDATE credit_applications SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2",
+		status, id,
+	)
+	return err
+}
+
+func (r *CreditApplicationRepository) UpdateStatusTx(tx *sql.Tx, id uint, status string) error {
+	_, err := tx.Exec(
+		"UPDATE credit_applications SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2",
+		status, id,
+	)
+	return err
+}
+
+// Сохранение решения скоринга
+func (r *CreditApplicationRepository) SaveDecision(app *models.CreditApplication) error {
+	return r.db.QueryRow(
+		`UPDATE credit_applications
+		 SET status = $1, score = $2, reject_reasons = $3, updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $4 RETURNING updated_at`,
+		app.Status, app.Score, pq.Array(app.RejectReasons), app.ID,
+	).Scan(&app.UpdatedAt)
+}
+
+// Перевод подписанной заявки в active с привязкой выданного кредита
+func (r *CreditApplicationRepository) ActivateTx(tx *sql.Tx, app *models.CreditApplication, creditID uint) error {
+	err := tx.QueryRow(
+		`UPDATE credit_applications
+		 SET status = $1, credit_id = $2, updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $3 RETURNING updated_at`,
+		models.ApplicationStatusActive, creditID, app.ID,
+	).Scan(&app.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	app.Status = models.ApplicationStatusActive
+	app.CreditID = creditID
+	return nil
+}
+
+func scanApplication(row rowScanner) (*models.CreditApplication, error) {
+	a := &models.CreditApplication{}
+	err := row.Scan(
+		&a.ID,
+		&a.UserID,
+		&a.AccountID,
+		&a.Amount,
+		&a.Rate,
+		&a.Period,
+		&a.ScheduleType,
+		&a.IssueFee,
+		&a.Insurance,
+		&a.PSK,
+		&a.Status,
+		&a.Score,
+		pq.Array(&a.RejectReasons),
+		&a.CreditID,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+	)
+	return a, err
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"bank-service/src/models"
+	"bank-service/src/money"
 	"database/sql"
 	"errors"
 
@@ -12,6 +13,11 @@ var (
 	ErrCreditNotFound = errors.New("credit not found")
 )
 
+// Колонки кредита в порядке сканирования scanCredit
+const creditColumns = `id, user_id, account_id, amount, rate, period, created_at, status,
+	COALESCE(disbursement_transaction_id, 0), outstanding_principal, schedule_type,
+	issue_fee, insurance, COALESCE(psk, 0)`
+
 type CreditRepository struct {
 	db     *sql.DB
 	logger *logrus.Logger
@@ -22,35 +28,38 @@ func NewCreditRepository(db *sql.DB, logger *logrus.Logger) *CreditRepository {
 }
 
 func (r *CreditRepository) Create(credit *models.Credit) error {
-	query := `INSERT INTO credits (user_id, account_id, amount, rate, period, status) 
-          VALUES ($1, $2, $3, $4, $5, $6) 
+	return r.createWith(r.db, credit)
+}
+
+func (r *CreditRepository) CreateTx(tx *sql.Tx, credit *models.Credit) error {
+	return r.createWith(tx, credit)
+}
+
+func (r *CreditRepository) createWith(q queryRower, credit *models.Credit) error {
+	query := `INSERT INTO credits (user_id, account_id, amount, rate, period, status, disbursement_transaction_id, outstanding_principal, schedule_type, issue_fee, insurance, psk) 
+          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) 
           RETURNING id, created_at`
-	r.logger.Infof("Executing query: %s with values: userID=%d, accountID=%d, amount=%f, rate=%f, period=%d, status=%s",
+	r.logger.Infof("Executing query: %s with values: userID=%d, accountID=%d, amount=%s, rate=%f, period=%d, status=%s",
 		query, credit.UserID, credit.AccountID, credit.Amount, credit.Rate, credit.Period, credit.Status)
-	return r.db.QueryRow(query,
+	return q.QueryRow(query,
 		credit.UserID,
 		credit.AccountID,
 		credit.Amount,
 		credit.Rate,
 		credit.Period,
 		credit.Status,
+		nullableID(credit.DisbursementTransactionID),
+		credit.OutstandingPrincipal,
+		credit.ScheduleType,
+		credit.IssueFee,
+		credit.Insurance,
+		credit.PSK,
 	).Scan(&credit.ID, &credit.CreatedAt)
 }
 
 func (r *CreditRepository) GetByIDAndUser(creditID, userID uint) (*models.Credit, error) {
-	credit := &models.Credit{}
-	query := `SELECT id, user_id, account_id, amount, rate, period, created_at, status 
-          FROM credits WHERE id = $1 AND user_id = $2`
-	err := r.db.QueryRow(query, creditID, userID).Scan(
-		&credit.ID,
-		&credit.UserID,
-		&credit.AccountID,
-		&credit.Amount,
-		&credit.Rate,
-		&credit.Period,
-		&credit.CreatedAt,
-		&credit.Status,
-	)
+	query := `SELECT ` + creditColumns + ` FROM credits WHERE id = $1 AND user_id = $2`
+	credit, err := scanCredit(r.db.QueryRow(query, creditID, userID))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrCreditNotFound
 	}
@@ -62,10 +71,33 @@ func (r *CreditRepository) UpdateStatus(creditID uint, status string) error {
     return err
 }
 
+func (r *CreditRepository) UpdateStatusTx(tx *sql.Tx, creditID uint, status string) error {
+    _, err := tx.Exec("UPDATE credits SET status = $1 WHERE id = $2", status, creditID)
+    return err
+}
+
+func (r *CreditRepository) UpdateOutstandingPrincipalTx(tx *sql.Tx, creditID uint, amount money.Amount) error {
+    _, err := tx.Exec("UPDATE credits SET outstanding_principal = $1 WHERE id = $2", amount, creditID)
+    return err
+}
+
+// Кредит с блокировкой строки до конца транзакции - платежи по одному
+// кредиту выполняются строго последовательно
+func (r *CreditRepository) GetByIDForUpdateTx(tx *sql.Tx, creditID uint) (*models.Credit, error) {
+	query := `SELECT ` + creditColumns + ` FROM credits WHERE id = $1 FOR UPDATE`
+	credit, err := scanCredit(tx.QueryRow(query, creditID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCreditNotFound
+	}
+	return credit, err
+}
+
 func (r *CreditRepository) GetByUserID(userID uint) ([]models.Credit, error) {
-	query := `SELECT id, user_id, account_id, amount, rate, period, created_at, status 
-	          FROM credits WHERE user_id = \$1`
-	rows, err := r.db.Query(query, userID)
+	return r.queryCredits(`SELECT `+creditColumns+` FROM credits WHERE user_id = $1`, userID)
+}
+
+func (r *CreditRepository) queryCredits(query string, args ...interface{}) ([]models.Credit, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -73,11 +105,36 @@ func (r *CreditRepository) GetByUserID(userID uint) ([]models.Credit, error) {
 
 	var credits []models.Credit
 	for rows.Next() {
-		var c models.Credit
-		if err := rows.Scan(&c.ID, &c.UserID, &c.AccountID, &c.Amount, &c.Rate, &c.Period, &c.CreatedAt, &c.Status); err != nil {
+		c, err := scanCredit(rows)
+		if err != nil {
 			return nil, err
 		}
-		credits = append(credits, c)
+		credits = append(credits, *c)
 	}
-	return credits, nil
+	return credits, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCredit(row rowScanner) (*models.Credit, error) {
+	c := &models.Credit{}
+	err := row.Scan(
+		&c.ID,
+		&c.UserID,
+		&c.AccountID,
+		&c.Amount,
+		&c.Rate,
+		&c.Period,
+		&c.CreatedAt,
+		&c.Status,
+		&c.DisbursementTransactionID,
+		&c.OutstandingPrincipal,
+		&c.ScheduleType,
+		&c.IssueFee,
+		&c.Insurance,
+		&c.PSK,
+	)
+	return c, err
 }
@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"bank-service/src/models"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var ErrExchangeRateNotFound = errors.New("exchange rate not found")
+
+type ExchangeRateRepository struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+func NewExchangeRateRepository(db *sql.DB, logger *logrus.Logger) *ExchangeRateRepository {
+	return &ExchangeRateRepository{db: db, logger: logger}
+}
+
+// Сохранение курса; курс за ту же дату перезаписывается
+func (r *ExchangeRateRepository) Save(rate *models.ExchangeRate) error {
+	return r.db.QueryRow(
+		`INSERT INTO exchange_rates (currency, rate_date, nominal, value)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (currency, rate_date) DO UPDATE SET nominal = EXCLUDED.nominal, value = EXCLUDED.value
+		 RETURNING created_at`,
+		rate.Currency, rate.Date, rate.Nominal, rate.Value,
+	).Scan(&rate.CreatedAt)
+}
+
+// Курс, действующий на дату: последний установленный не позже date
+func (r *ExchangeRateRepository) GetOnDate(currency string, date time.Time) (*models.ExchangeRate, error) {
+	rate := &models.ExchangeRate{}
+	err := r.db.QueryRow(
+		`SELECT currency, rate_date, nominal, value, created_at
+		 FROM exchange_rates
+		 WHERE currency = $1 AND rate_date <= $2
+		 ORDER BY rate_date DESC LIMIT 1`,
+		currency, date,
+	).Scan(&rate.Currency, &rate.Date, &rate.Nominal, &rate.Value, &rate.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrExchangeRateNotFound
+	}
+	return rate, err
+}
@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"bank-service/src/models"
+	"bank-service/src/money"
+	"database/sql"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type FeeRepository struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+func NewFeeRepository(db *sql.DB, logger *logrus.Logger) *FeeRepository {
+	return &FeeRepository{db: db, logger: logger}
+}
+
+// Правила операции по возрастанию порога оборота
+func (r *FeeRepository) GetRules(operation string) ([]models.FeeRule, error) {
+	rows, err := r.db.Query(
+		`SELECT id, operation, min_volume, flat, percent, min_fee, COALESCE(max_fee, 0),
+		        free_count, free_amount
+		 FROM fee_rules WHERE operation = $1 ORDER BY min_volume`,
+		operation,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.FeeRule
+	for rows.Next() {
+		var rule models.FeeRule
+		if err := rows.Scan(
+			&rule.ID,
+			&rule.Operation,
+			&rule.MinVolume,
+			&rule.Flat,
+			&rule.Percent,
+			&rule.MinFee,
+			&rule.MaxFee,
+			&rule.FreeCount,
+			&rule.FreeAmount,
+		); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// Число операций и оборот в рублях с начала месяца since
+func (r *FeeRepository) MonthlyUsage(userID uint, operation string, since time.Time) (int, money.Amount, error) {
+	return r.monthlyUsage(r.db, userID, operation, since)
+}
+
+func (r *FeeRepository) MonthlyUsageTx(tx *sql.Tx, userID uint, operation string, since time.Time) (int, money.Amount, error) {
+	return r.monthlyUsage(tx, userID, operation, since)
+}
+
+func (r *FeeRepository) monthlyUsage(q queryRower, userID uint, operation string, since time.Time) (int, money.Amount, error) {
+	var count int
+	var volume money.Amount
+	err := q.QueryRow(
+		`SELECT COUNT(*), COALESCE(SUM(volume_rub), 0)
+		 FROM fee_charges WHERE user_id = $1 AND operation = $2 AND created_at >= $3`,
+		userID, operation, since,
+	).Scan(&count, &volume)
+	return count, volume, err
+}
+
+func (r *FeeRepository) CreateChargeTx(tx *sql.Tx, charge *models.FeeCharge) error {
+	return tx.QueryRow(
+		`INSERT INTO fee_charges (user_id, operation, rule_id, amount, currency, volume_rub, fee,
+		                          transaction_id, fee_transaction_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 RETURNING id, created_at`,
+		charge.UserID, charge.Operation, nullableID(charge.RuleID), charge.Amount, charge.Currency,
+		charge.VolumeRUB, charge.Fee, nullableID(charge.TransactionID), nullableID(charge.FeeTransactionID),
+	).Scan(&charge.ID, &charge.CreatedAt)
+}
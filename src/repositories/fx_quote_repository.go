@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"bank-service/src/models"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+var ErrQuoteNotFound = errors.New("fx quote not found")
+
+// Колонки котировки в порядке сканирования scanQuote
+const quoteColumns = `id, user_id, from_account_id, to_account_id, from_currency, to_currency,
+	amount, to_amount, cbr_rate, rate, spread, expires_at,
+	COALESCE(transaction_id, 0), executed_at, created_at`
+
+type FXQuoteRepository struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+func NewFXQuoteRepository(db *sql.DB, logger *logrus.Logger) *FXQuoteRepository {
+	return &FXQuoteRepository{db: db, logger: logger}
+}
+
+func (r *FXQuoteRepository) Create(quote *models.FXQuote) error {
+	return r.db.QueryRow(
+		`INSERT INTO fx_quotes (user_id, from_account_id, to_account_id, from_currency, to_currency,
+		                        amount, to_amount, cbr_rate, rate, spread, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		 RETURNING id, created_at`,
+		quote.UserID, quote.FromAccountID, quote.ToAccountID, quote.FromCurrency, quote.ToCurrency,
+		quote.Amount, quote.ToAmount, quote.CBRRate, quote.Rate, quote.Spread, quote.ExpiresAt,
+	).Scan(&quote.ID, &quote.CreatedAt)
+}
+
+// Котировка с блокировкой строки: по одной котировке возможен только один обмен
+func (r *FXQuoteRepository) GetByIDForUpdateTx(tx *sql.Tx, id string) (*models.FXQuote, error) {
+	quote, err := scanQuote(tx.QueryRow(`SELECT `+quoteColumns+` FROM fx_quotes WHERE id = $1 FOR UPDATE`, id))
+	var pqErr *pq.Error
+	if errors.Is(err, sql.ErrNoRows) || (errors.As(err, &pqErr) && pqErr.Code == "22P02") {
+		// 22P02 - строка не является UUID
+		return nil, ErrQuoteNotFound
+	}
+	return quote, err
+}
+
+func (r *FXQuoteRepository) MarkExecutedTx(tx *sql.Tx, quote *models.FXQuote, transactionID uint) error {
+	err := tx.QueryRow(
+		`UPDATE fx_quotes SET transaction_id = $1, executed_at = CURRENT_TIMESTAMP
+		 WHERE id = $2 RETURNING executed_at`,
+		transactionID, quote.ID,
+	).Scan(&quote.ExecutedAt)
+	if err != nil {
+		return err
+	}
+	quote.TransactionID = transactionID
+	return nil
+}
+
+func scanQuote(row rowScanner) (*models.FXQuote, error) {
+	q := &models.FXQuote{}
+	var executedAt sql.NullTime
+	err := row.Scan(
+		&q.ID,
+		&q.UserID,
+		&q.FromAccountID,
+		&q.ToAccountID,
+		&q.FromCurrency,
+		&q.ToCurrency,
+		&q.Amount,
+		&q.ToAmount,
+		&q.CBRRate,
+		&q.Rate,
+		&q.Spread,
+		&q.ExpiresAt,
+		&q.TransactionID,
+		&executedAt,
+		&q.CreatedAt,
+	)
+	if executedAt.Valid {
+		q.ExecutedAt = &executedAt.Time
+	}
+	return q, err
+}
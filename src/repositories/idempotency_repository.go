@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"bank-service/src/models"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+
+type IdempotencyRepository struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+func NewIdempotencyRepository(db *sql.DB, logger *logrus.Logger) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db, logger: logger}
+}
+
+// Занимает ключ за запросом. Возвращает false, если ключ уже использовался.
+func (r *IdempotencyRepository) Reserve(key *models.IdempotencyKey) (bool, error) {
+	err := r.db.QueryRow(
+		`INSERT INTO idempotency_keys (user_id, key, method, path, request_hash)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (user_id, key) DO NOTHING
+		 RETURNING created_at`,
+		key.UserID, key.Key, key.Method, key.Path, key.RequestHash,
+	).Scan(&key.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *IdempotencyRepository) Get(userID uint, key string) (*models.IdempotencyKey, error) {
+	k := &models.IdempotencyKey{}
+	var status sql.NullInt64
+	err := r.db.QueryRow(
+		`SELECT user_id, key, method, path, request_hash, status_code, response_body, created_at
+		 FROM idempotency_keys WHERE user_id = $1 AND key = $2`,
+		userID, key,
+	).Scan(&k.UserID, &k.Key, &k.Method, &k.Path, &k.RequestHash, &status, &k.ResponseBody, &k.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIdempotencyKeyNotFound
+	}
+	k.StatusCode = int(status.Int64)
+	return k, err
+}
+
+func (r *IdempotencyRepository) Complete(userID uint, key string, statusCode int, body []byte) error {
+	_, err := r.db.Exec(
+		`UPDATE idempotency_keys
+		 SET status_code = $1, response_body = $2, completed_at = CURRENT_TIMESTAMP
+		 WHERE user_id = $3 AND key = $4`,
+		statusCode, body, userID, key,
+	)
+	return err
+}
+
+func (r *IdempotencyRepository) Delete(userID uint, key string) error {
+	_, err := r.db.Exec("DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2", userID, key)
+	return err
+}
+
+func (r *IdempotencyRepository) DeleteOlderThan(before time.Time) (int64, error) {
+	res, err := r.db.Exec("DELETE FROM idempotency_keys WHERE created_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,185 @@
+package repositories
+
+import (
+	"bank-service/src/models"
+	"bank-service/src/money"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrUnbalancedEntry       = errors.New("journal entry is not balanced")
+	ErrSystemAccountNotFound = errors.New("system account not found")
+)
+
+type LedgerRepository struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+func NewLedgerRepository(db *sql.DB, logger *logrus.Logger) *LedgerRepository {
+	return &LedgerRepository{db: db, logger: logger}
+}
+
+// Сохраняет проводку и применяет ее записи к балансам счетов.
+// Балансы меняются только здесь, поэтому accounts.balance всегда
+// можно пересчитать по таблице postings.
+func (r *LedgerRepository) CreateEntryTx(tx *sql.Tx, entry *models.JournalEntry) error {
+	if err := validateEntry(entry); err != nil {
+		return err
+	}
+
+	err := tx.QueryRow(
+		`INSERT INTO journal_entries (transaction_id, kind, description)
+		 VALUES ($1, $2, $3) RETURNING id, created_at`,
+		nullableID(entry.TransactionID), entry.Kind, entry.Description,
+	).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	for i := range entry.Postings {
+		p := &entry.Postings[i]
+		p.EntryID = entry.ID
+
+		var systemAccount interface{}
+		if p.SystemAccount != "" {
+			systemAccount = p.SystemAccount
+		}
+		err := tx.QueryRow(
+			`INSERT INTO postings (entry_id, account_id, system_account, direction, amount, currency)
+			 VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+			p.EntryID, nullableID(p.AccountID), systemAccount, p.Direction, p.Amount, p.Currency,
+		).Scan(&p.ID, &p.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		if err := r.applyPostingTx(tx, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *LedgerRepository) applyPostingTx(tx *sql.Tx, p *models.Posting) error {
+	delta := p.Amount
+	if p.Direction == models.PostingDebit {
+		delta = -delta
+	}
+
+	var res sql.Result
+	var err error
+	if p.AccountID != 0 {
+		res, err = tx.Exec("UPDATE accounts SET balance = balance + $1 WHERE id = $2", delta, p.AccountID)
+	} else {
+		res, err = tx.Exec("UPDATE system_accounts SET balance = balance + $1 WHERE code = $2 AND currency = $3", delta, p.SystemAccount, p.Currency)
+	}
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		if p.AccountID != 0 {
+			return ErrAccountNotFound
+		}
+		return ErrSystemAccountNotFound
+	}
+	return nil
+}
+
+// Баланс клиентского счета, рассчитанный по записям журнала
+func (r *LedgerRepository) CalculateBalance(accountID uint) (money.Amount, error) {
+	var balance money.Amount
+	err := r.db.QueryRow(
+		`SELECT COALESCE(SUM(CASE WHEN direction = 'credit' THEN amount ELSE -amount END), 0)
+		 FROM postings WHERE account_id = $1`,
+		accountID,
+	).Scan(&balance)
+	return balance, err
+}
+
+// Остаток клиентского счета на момент before по записям журнала. Журнал -
+// источник истины: вводные остатки миграции 007 и пополнения до нее есть
+// только в нем, а не в transactions.
+func (r *LedgerRepository) BalanceBefore(accountID uint, before time.Time) (money.Amount, error) {
+	var balance money.Amount
+	err := r.db.QueryRow(
+		`SELECT COALESCE(SUM(CASE WHEN direction = 'credit' THEN amount ELSE -amount END), 0)
+		 FROM postings WHERE account_id = $1 AND created_at < $2`,
+		accountID, before,
+	).Scan(&balance)
+	return balance, err
+}
+
+func (r *LedgerRepository) GetByTransactionID(transactionID uint) ([]models.JournalEntry, error) {
+	rows, err := r.db.Query(
+		`SELECT e.id, e.kind, COALESCE(e.description, ''), e.created_at,
+		        p.id, COALESCE(p.account_id, 0), COALESCE(p.system_account, ''),
+		        p.direction, p.amount, p.currency, p.created_at
+		 FROM journal_entries e
+		 JOIN postings p ON p.entry_id = e.id
+		 WHERE e.transaction_id = $1
+		 ORDER BY e.id, p.id`,
+		transactionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.JournalEntry
+	for rows.Next() {
+		var e models.JournalEntry
+		var p models.Posting
+		if err := rows.Scan(
+			&e.ID, &e.Kind, &e.Description, &e.CreatedAt,
+			&p.ID, &p.AccountID, &p.SystemAccount,
+			&p.Direction, &p.Amount, &p.Currency, &p.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		p.EntryID = e.ID
+		if len(entries) == 0 || entries[len(entries)-1].ID != e.ID {
+			e.TransactionID = transactionID
+			entries = append(entries, e)
+		}
+		last := &entries[len(entries)-1]
+		last.Postings = append(last.Postings, p)
+	}
+	return entries, rows.Err()
+}
+
+func validateEntry(entry *models.JournalEntry) error {
+	if len(entry.Postings) < 2 {
+		return ErrUnbalancedEntry
+	}
+
+	sums := make(map[string]money.Amount)
+	for _, p := range entry.Postings {
+		if p.Amount <= 0 || (p.AccountID == 0) == (p.SystemAccount == "") {
+			return ErrUnbalancedEntry
+		}
+		switch p.Direction {
+		case models.PostingCredit:
+			sums[p.Currency] += p.Amount
+		case models.PostingDebit:
+			sums[p.Currency] -= p.Amount
+		default:
+			return ErrUnbalancedEntry
+		}
+	}
+	for _, sum := range sums {
+		if sum != 0 {
+			return ErrUnbalancedEntry
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"bank-service/src/models"
+	"bank-service/src/money"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type LimitRepository struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+func NewLimitRepository(db *sql.DB, logger *logrus.Logger) *LimitRepository {
+	return &LimitRepository{db: db, logger: logger}
+}
+
+// Максимальные лимиты продукта для области scope
+func (r *LimitRepository) GetDefaults(scope string) (*models.Limits, error) {
+	l := &models.Limits{}
+	err := r.db.QueryRow(
+		`SELECT single_max, daily_max, monthly_max, daily_count FROM limit_defaults WHERE scope = $1`,
+		scope,
+	).Scan(&l.SingleMax, &l.DailyMax, &l.MonthlyMax, &l.DailyCount)
+	return l, err
+}
+
+// Лимиты, установленные клиентом; cardID == 0 - общие лимиты клиента.
+// nil, если клиент лимиты не менял.
+func (r *LimitRepository) GetUserLimits(userID, cardID uint) (*models.Limits, error) {
+	query := `SELECT single_max, daily_max, monthly_max, daily_count FROM user_limits
+	          WHERE user_id = $1 AND card_id IS NULL`
+	args := []interface{}{userID}
+	if cardID != 0 {
+		query = `SELECT single_max, daily_max, monthly_max, daily_count FROM user_limits
+		         WHERE user_id = $1 AND card_id = $2`
+		args = append(args, cardID)
+	}
+
+	l := &models.Limits{}
+	err := r.db.QueryRow(query, args...).Scan(&l.SingleMax, &l.DailyMax, &l.MonthlyMax, &l.DailyCount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return l, err
+}
+
+func (r *LimitRepository) SaveUserLimits(userID, cardID uint, l *models.Limits) error {
+	if cardID == 0 {
+		_, err := r.db.Exec(
+			`INSERT INTO user_limits (user_id, single_max, daily_max, monthly_max, daily_count)
+			 VALUES ($1, $2, $3, $4, $5)
+			 ON CONFLICT (user_id) WHERE card_id IS NULL DO UPDATE
+			 SET single_max = EXCLUDED.single_max, daily_max = EXCLUDED.daily_max,
+			     monthly_max = EXCLUDED.monthly_max, daily_count = EXCLUDED.daily_count,
+			     updated_at = CURRENT_TIMESTAMP`,
+			userID, l.SingleMax, l.DailyMax, l.MonthlyMax, l.DailyCount,
+		)
+		return err
+	}
+	_, err := r.db.Exec(
+		`INSERT INTO user_limits (user_id, card_id, single_max, daily_max, monthly_max, daily_count)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (user_id, card_id) WHERE card_id IS NOT NULL DO UPDATE
+		 SET single_max = EXCLUDED.single_max, daily_max = EXCLUDED.daily_max,
+		     monthly_max = EXCLUDED.monthly_max, daily_count = EXCLUDED.daily_count,
+		     updated_at = CURRENT_TIMESTAMP`,
+		userID, cardID, l.SingleMax, l.DailyMax, l.MonthlyMax, l.DailyCount,
+	)
+	return err
+}
+
+// Расход лимитов с начала дня dayStart и месяца monthStart;
+// cardID == 0 - по всем операциям клиента
+func (r *LimitRepository) Usage(userID, cardID uint, dayStart, monthStart time.Time) (*models.LimitUsage, error) {
+	return r.usage(r.db, userID, cardID, dayStart, monthStart)
+}
+
+func (r *LimitRepository) UsageTx(tx *sql.Tx, userID, cardID uint, dayStart, monthStart time.Time) (*models.LimitUsage, error) {
+	return r.usage(tx, userID, cardID, dayStart, monthStart)
+}
+
+func (r *LimitRepository) usage(q queryRower, userID, cardID uint, dayStart, monthStart time.Time) (*models.LimitUsage, error) {
+	query := `SELECT COALESCE(SUM(amount_rub) FILTER (WHERE created_at >= $2), 0),
+	                 COUNT(*) FILTER (WHERE created_at >= $2),
+	                 COALESCE(SUM(amount_rub), 0)
+	          FROM limit_usage WHERE user_id = $1 AND created_at >= $3`
+	args := []interface{}{userID, dayStart, monthStart}
+	if cardID != 0 {
+		query += ` AND card_id = $4`
+		args = append(args, cardID)
+	}
+
+	u := &models.LimitUsage{}
+	err := q.QueryRow(query, args...).Scan(&u.DailyTotal, &u.DailyCount, &u.MonthlyTotal)
+	return u, err
+}
+
+func (r *LimitRepository) RecordUsageTx(tx *sql.Tx, userID, cardID uint, amountRUB money.Amount, transactionID uint) error {
+	_, err := tx.Exec(
+		`INSERT INTO limit_usage (user_id, card_id, amount_rub, transaction_id) VALUES ($1, $2, $3, $4)`,
+		userID, nullableID(cardID), amountRUB, nullableID(transactionID),
+	)
+	return err
+}
+
+// Расход лимитов по авторизации карты до списания
+func (r *LimitRepository) RecordHoldUsageTx(tx *sql.Tx, userID, cardID uint, amountRUB money.Amount, holdID uint) error {
+	_, err := tx.Exec(
+		`INSERT INTO limit_usage (user_id, card_id, amount_rub, hold_id) VALUES ($1, $2, $3, $4)`,
+		userID, nullableID(cardID), amountRUB, holdID,
+	)
+	return err
+}
+
+// Списание по авторизации: расход уменьшается пропорционально
+// списанной части и привязывается к операции списания
+func (r *LimitRepository) SettleHoldUsageTx(tx *sql.Tx, holdID uint, captured, held money.Amount, transactionID uint) error {
+	_, err := tx.Exec(
+		`UPDATE limit_usage SET amount_rub = ROUND(amount_rub * $1 / $2, 2), transaction_id = $3
+		 WHERE hold_id = $4`,
+		captured, held, transactionID, holdID,
+	)
+	return err
+}
+
+// Отмена или истечение авторизации возвращает лимит
+func (r *LimitRepository) ReleaseHoldUsageTx(tx *sql.Tx, holdID uint) error {
+	_, err := tx.Exec(`DELETE FROM limit_usage WHERE hold_id = $1`, holdID)
+	return err
+}
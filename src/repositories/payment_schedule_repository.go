@@ -2,12 +2,19 @@ package repositories
 
 import (
 	"bank-service/src/models"
+	"bank-service/src/money"
 	"database/sql"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+// Колонки строки графика в порядке сканирования scanSchedules
+const scheduleColumns = `id, credit_id, due_date, amount, principal, interest, remaining_balance, paid,
+	principal_paid, interest_paid,
+	COALESCE((SELECT SUM(pa.amount) FROM penalty_accruals pa WHERE pa.schedule_id = payment_schedules.id), 0),
+	penalty_paid, created_at`
+
 type PaymentScheduleRepository struct {
 	db     *sql.DB
 	logger *logrus.Logger
@@ -18,57 +25,123 @@ func NewPaymentScheduleRepository(db *sql.DB, logger *logrus.Logger) *PaymentSch
 }
 
 func (r *PaymentScheduleRepository) Create(schedule *models.PaymentSchedule) error {
-	query := `INSERT INTO payment_schedules (credit_id, due_date, amount, paid) 
-          VALUES ($1, $2, $3, $4) RETURNING id, created_at`
-	return r.db.QueryRow(query,
+	return r.createWith(r.db, schedule)
+}
+
+func (r *PaymentScheduleRepository) CreateTx(tx *sql.Tx, schedule *models.PaymentSchedule) error {
+	return r.createWith(tx, schedule)
+}
+
+func (r *PaymentScheduleRepository) createWith(q queryRower, schedule *models.PaymentSchedule) error {
+	query := `INSERT INTO payment_schedules (credit_id, due_date, amount, principal, interest, remaining_balance, paid) 
+          VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`
+	return q.QueryRow(query,
 		schedule.CreditID,
 		schedule.DueDate,
 		schedule.Amount,
+		schedule.Principal,
+		schedule.Interest,
+		schedule.RemainingBalance,
 		schedule.Paid,
 	).Scan(&schedule.ID, &schedule.CreatedAt)
 }
 
 func (r *PaymentScheduleRepository) GetByCreditID(creditID uint) ([]models.PaymentSchedule, error) {
-	query := `SELECT id, credit_id, due_date, amount, paid, created_at 
-          FROM payment_schedules WHERE credit_id = $1 ORDER BY due_date`
+	query := `SELECT ` + scheduleColumns + `
+          FROM payment_schedules WHERE credit_id = $1 ORDER BY due_date, id`
 	rows, err := r.db.Query(query, creditID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var schedules []models.PaymentSchedule
-	for rows.Next() {
-		var s models.PaymentSchedule
-		if err := rows.Scan(&s.ID, &s.CreditID, &s.DueDate, &s.Amount, &s.Paid, &s.CreatedAt); err != nil {
-			return nil, err
-		}
-		schedules = append(schedules, s)
-	}
-	return schedules, nil
+	return scanSchedules(rows)
 }
 
 func (r *PaymentScheduleRepository) MarkAsPaid(scheduleID uint) error {
-	_, err := r.db.Exec("UPDATE payment_schedules SET paid = TRUE WHERE id = $1", scheduleID)
+	_, err := r.db.Exec("UPDATE payment_schedules SET paid = TRUE, paid_at = CURRENT_TIMESTAMP WHERE id = $1", scheduleID)
 	return err
 }
 
 func (r *PaymentScheduleRepository) GetOverdueUnpaidSchedules(before time.Time) ([]models.PaymentSchedule, error) {
-	query := `SELECT id, credit_id, due_date, amount, paid, created_at 
-          FROM payment_schedules WHERE due_date < $1 AND paid = FALSE`
+	query := `SELECT ` + scheduleColumns + `
+          FROM payment_schedules WHERE due_date < $1 AND paid = FALSE ORDER BY due_date, id`
 	rows, err := r.db.Query(query, before)
 	if err != nil {
 		return nil, err
 	}
+	return scanSchedules(rows)
+}
+
+// Неоплаченные платежи по кредиту в порядке сроков
+func (r *PaymentScheduleRepository) GetUnpaidByCreditIDTx(tx *sql.Tx, creditID uint) ([]models.PaymentSchedule, error) {
+	query := `SELECT ` + scheduleColumns + `
+          FROM payment_schedules WHERE credit_id = $1 AND paid = FALSE ORDER BY due_date, id`
+	rows, err := tx.Query(query, creditID)
+	if err != nil {
+		return nil, err
+	}
+	return scanSchedules(rows)
+}
+
+// Учет погашения по строке графика; paid - строка погашена полностью
+func (r *PaymentScheduleRepository) ApplyPaymentTx(tx *sql.Tx, scheduleID uint, principal, interest, penalty money.Amount, paid bool) error {
+	_, err := tx.Exec(
+		`UPDATE payment_schedules
+		 SET principal_paid = principal_paid + $2,
+		     interest_paid = interest_paid + $3,
+		     penalty_paid = penalty_paid + $4,
+		     paid = $5,
+		     paid_at = CASE WHEN $5 THEN CURRENT_TIMESTAMP ELSE paid_at END
+		 WHERE id = $1`,
+		scheduleID, principal, interest, penalty, paid,
+	)
+	return err
+}
+
+// Разбивка строк, созданных до появления principal/interest
+func (r *PaymentScheduleRepository) UpdateBreakdownTx(tx *sql.Tx, scheduleID uint, principal, interest money.Amount) error {
+	_, err := tx.Exec(
+		"UPDATE payment_schedules SET principal = $2, interest = $3 WHERE id = $1",
+		scheduleID, principal, interest,
+	)
+	return err
+}
+
+// Удаление оставшихся платежей при пересчете графика или досрочном погашении
+func (r *PaymentScheduleRepository) DeleteUnpaidTx(tx *sql.Tx, creditID uint) error {
+	_, err := tx.Exec("DELETE FROM payment_schedules WHERE credit_id = $1 AND paid = FALSE", creditID)
+	return err
+}
+
+// Число платежей пользователя, оплаченных позже срока или просроченных на момент now
+func (r *PaymentScheduleRepository) CountOverdueByUser(userID uint, now time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRow(
+		`SELECT COUNT(*)
+		 FROM payment_schedules ps
+		 JOIN credits c ON ps.credit_id = c.id
+		 WHERE c.user_id = $1
+		   AND ((ps.paid AND ps.paid_at::date > ps.due_date)
+		     OR (NOT ps.paid AND ps.due_date < $2::date))`,
+		userID, now,
+	).Scan(&count)
+	return count, err
+}
+
+func scanSchedules(rows *sql.Rows) ([]models.PaymentSchedule, error) {
 	defer rows.Close()
 
 	var schedules []models.PaymentSchedule
 	for rows.Next() {
 		var s models.PaymentSchedule
-		if err := rows.Scan(&s.ID, &s.CreditID, &s.DueDate, &s.Amount, &s.Paid, &s.CreatedAt); err != nil {
+		if err := rows.Scan(
+			&s.ID, &s.CreditID, &s.DueDate, &s.Amount,
+			&s.Principal, &s.Interest, &s.RemainingBalance,
+			&s.Paid, &s.PrincipalPaid, &s.InterestPaid, &s.Penalty, &s.PenaltyPaid,
+			&s.CreatedAt,
+		); err != nil {
 			return nil, err
 		}
 		schedules = append(schedules, s)
 	}
-	return schedules, nil
+	return schedules, rows.Err()
 }
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"bank-service/src/models"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type PenaltyRepository struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+func NewPenaltyRepository(db *sql.DB, logger *logrus.Logger) *PenaltyRepository {
+	return &PenaltyRepository{db: db, logger: logger}
+}
+
+// Начисление за день. Повторное начисление за ту же дату игнорируется,
+// возвращается false.
+func (r *PenaltyRepository) AccrueTx(tx *sql.Tx, accrual *models.PenaltyAccrual) (bool, error) {
+	err := tx.QueryRow(
+		`INSERT INTO penalty_accruals (schedule_id, accrual_date, base_amount, rate, amount)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (schedule_id, accrual_date) DO NOTHING
+		 RETURNING id, created_at`,
+		accrual.ScheduleID, accrual.AccrualDate, accrual.BaseAmount, accrual.Rate, accrual.Amount,
+	).Scan(&accrual.ID, &accrual.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Дата последнего начисления по строке графика
+func (r *PenaltyRepository) LastAccrualDateTx(tx *sql.Tx, scheduleID uint) (time.Time, bool, error) {
+	var last sql.NullTime
+	err := tx.QueryRow(
+		"SELECT MAX(accrual_date) FROM penalty_accruals WHERE schedule_id = $1",
+		scheduleID,
+	).Scan(&last)
+	return last.Time, last.Valid, err
+}
+
+func (r *PenaltyRepository) GetByCreditID(creditID uint) ([]models.PenaltyAccrual, error) {
+	rows, err := r.db.Query(
+		`SELECT pa.id, pa.schedule_id, pa.accrual_date, pa.base_amount, pa.rate, pa.amount, pa.created_at
+		 FROM penalty_accruals pa
+		 JOIN payment_schedules ps ON pa.schedule_id = ps.id
+		 WHERE ps.credit_id = $1
+		 ORDER BY pa.accrual_date, pa.schedule_id`,
+		creditID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accruals []models.PenaltyAccrual
+	for rows.Next() {
+		var a models.PenaltyAccrual
+		if err := rows.Scan(&a.ID, &a.ScheduleID, &a.AccrualDate, &a.BaseAmount, &a.Rate, &a.Amount, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		accruals = append(accruals, a)
+	}
+	return accruals, rows.Err()
+}
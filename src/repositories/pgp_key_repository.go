@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"bank-service/src/models"
+	"database/sql"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+)
+
+var ErrPGPKeyNotFound = errors.New("pgp key not found")
+
+const pgpKeyColumns = `id, key_id, sealed_key, status, created_at, retired_at`
+
+type PGPKeyRepository struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+func NewPGPKeyRepository(db *sql.DB, logger *logrus.Logger) *PGPKeyRepository {
+	return &PGPKeyRepository{db: db, logger: logger}
+}
+
+func (r *PGPKeyRepository) GetAll() ([]models.PGPKey, error) {
+	rows, err := r.db.Query(`SELECT ` + pgpKeyColumns + ` FROM pgp_keys ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []models.PGPKey
+	for rows.Next() {
+		key, err := scanPGPKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *PGPKeyRepository) GetByID(id uint) (*models.PGPKey, error) {
+	key, err := scanPGPKey(r.db.QueryRow(`SELECT `+pgpKeyColumns+` FROM pgp_keys WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPGPKeyNotFound
+	}
+	return key, err
+}
+
+// Новый активный ключ; прежний активный переводится в retired.
+// Уникальный индекс idx_pgp_keys_active не даст двум ротациям
+// завершиться одновременно.
+func (r *PGPKeyRepository) Activate(key *models.PGPKey) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		`UPDATE pgp_keys SET status = $1, retired_at = CURRENT_TIMESTAMP WHERE status = $2`,
+		models.PGPKeyRetired, models.PGPKeyActive,
+	); err != nil {
+		return err
+	}
+
+	key.Status = models.PGPKeyActive
+	if err := tx.QueryRow(
+		`INSERT INTO pgp_keys (key_id, sealed_key, status) VALUES ($1, $2, $3)
+		 RETURNING id, created_at`,
+		key.KeyID, key.SealedKey, key.Status,
+	).Scan(&key.ID, &key.CreatedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Количество карт на каждом ключе
+func (r *PGPKeyRepository) CardCounts() (map[uint]int, error) {
+	rows, err := r.db.Query(`SELECT key_id, COUNT(*) FROM cards WHERE key_id IS NOT NULL GROUP BY key_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[uint]int)
+	for rows.Next() {
+		var id uint
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, err
+		}
+		counts[id] = n
+	}
+	return counts, rows.Err()
+}
+
+func scanPGPKey(row rowScanner) (*models.PGPKey, error) {
+	key := &models.PGPKey{}
+	err := row.Scan(
+		&key.ID,
+		&key.KeyID,
+		&key.SealedKey,
+		&key.Status,
+		&key.CreatedAt,
+		&key.RetiredAt,
+	)
+	return key, err
+}
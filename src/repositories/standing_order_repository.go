@@ -0,0 +1,242 @@
+package repositories
+
+import (
+	"bank-service/src/models"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrStandingOrderNotFound  = errors.New("standing order not found")
+	ErrStandingOrderClaimLost = errors.New("standing order claim expired")
+)
+
+// Колонки регулярного перевода в порядке сканирования scanStandingOrder
+const standingOrderColumns = `id, user_id, from_account_id, to_account_id, amount, frequency,
+	COALESCE(day_of_month, 0), start_date, end_date, COALESCE(max_runs, 0), runs_count,
+	next_run_date, attempt, next_attempt_at, status, created_at, updated_at`
+
+type StandingOrderRepository struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+func NewStandingOrderRepository(db *sql.DB, logger *logrus.Logger) *StandingOrderRepository {
+	return &StandingOrderRepository{db: db, logger: logger}
+}
+
+func (r *StandingOrderRepository) Create(order *models.StandingOrder) error {
+	var dayOfMonth, maxRuns interface{}
+	if order.DayOfMonth > 0 {
+		dayOfMonth = order.DayOfMonth
+	}
+	if order.MaxRuns > 0 {
+		maxRuns = order.MaxRuns
+	}
+	return r.db.QueryRow(
+		`INSERT INTO standing_orders (user_id, from_account_id, to_account_id, amount, frequency,
+		                              day_of_month, start_date, end_date, max_runs,
+		                              next_run_date, next_attempt_at, status)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		 RETURNING id, created_at, updated_at`,
+		order.UserID, order.FromAccountID, order.ToAccountID, order.Amount, order.Frequency,
+		dayOfMonth, order.StartDate, order.EndDate, maxRuns,
+		order.NextRunDate, order.NextAttemptAt, order.Status,
+	).Scan(&order.ID, &order.CreatedAt, &order.UpdatedAt)
+}
+
+func (r *StandingOrderRepository) GetByIDAndUser(id, userID uint) (*models.StandingOrder, error) {
+	query := `SELECT ` + standingOrderColumns + ` FROM standing_orders WHERE id = $1 AND user_id = $2`
+	order, err := scanStandingOrder(r.db.QueryRow(query, id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrStandingOrderNotFound
+	}
+	return order, err
+}
+
+func (r *StandingOrderRepository) GetByUserID(userID uint) ([]models.StandingOrder, error) {
+	query := `SELECT ` + standingOrderColumns + ` FROM standing_orders WHERE user_id = $1 ORDER BY id`
+	return r.list(query, userID)
+}
+
+// Активные поручения, очередная попытка которых наступила к моменту now
+func (r *StandingOrderRepository) GetDue(now time.Time, limit int) ([]models.StandingOrder, error) {
+	query := `SELECT ` + standingOrderColumns + ` FROM standing_orders
+	          WHERE status = $1 AND next_attempt_at <= $2
+	          ORDER BY next_attempt_at, id LIMIT $3`
+	return r.list(query, models.StandingOrderActive, now, limit)
+}
+
+// Захват поручения на исполнение: попытка переносится на until, чтобы
+// параллельный исполнитель не провел тот же перевод повторно. Возвращает
+// false, если поручение уже захвачено, отменено или еще не наступило.
+func (r *StandingOrderRepository) Claim(id uint, now, until time.Time) (bool, error) {
+	result, err := r.db.Exec(
+		`UPDATE standing_orders SET next_attempt_at = $1, updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $2 AND status = $3 AND next_attempt_at <= $4`,
+		until, id, models.StandingOrderActive, now,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// Отмена активного поручения владельцем
+func (r *StandingOrderRepository) Cancel(id, userID uint) error {
+	result, err := r.db.Exec(
+		`UPDATE standing_orders SET status = $1, next_attempt_at = NULL, updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $2 AND user_id = $3 AND status = $4`,
+		models.StandingOrderCancelled, id, userID, models.StandingOrderActive,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrStandingOrderNotFound
+	}
+	return nil
+}
+
+// Запись неуспешной попытки вместе с новым состоянием расписания поручения
+func (r *StandingOrderRepository) SaveRun(order *models.StandingOrder, run *models.StandingOrderRun) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.SaveRunTx(tx, order, run, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Запись результата попытки и нового состояния расписания в транзакции
+// вызывающего. Если задан claimedUntil, поручение должно быть все еще
+// захвачено этим исполнителем, иначе ErrStandingOrderClaimLost.
+// Отмененное за время попытки поручение остается отмененным.
+func (r *StandingOrderRepository) SaveRunTx(tx *sql.Tx, order *models.StandingOrder, run *models.StandingOrderRun, claimedUntil *time.Time) error {
+	var runError, transactionID interface{}
+	if run.Error != "" {
+		runError = run.Error
+	}
+	if run.TransactionID != 0 {
+		transactionID = run.TransactionID
+	}
+	err := tx.QueryRow(
+		`INSERT INTO standing_order_runs (order_id, scheduled_for, attempt, status, error, transaction_id)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id, created_at`,
+		run.OrderID, run.ScheduledFor, run.Attempt, run.Status, runError, transactionID,
+	).Scan(&run.ID, &run.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(
+		`UPDATE standing_orders
+		 SET runs_count = $1, next_run_date = $2, attempt = $3, next_attempt_at = $4,
+		     status = CASE WHEN status = $5 THEN status ELSE $6 END,
+		     updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $7 AND ($8::timestamp IS NULL OR next_attempt_at = $8)`,
+		order.RunsCount, order.NextRunDate, order.Attempt, order.NextAttemptAt,
+		models.StandingOrderCancelled, order.Status, order.ID, claimedUntil,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrStandingOrderClaimLost
+	}
+	return nil
+}
+
+func (r *StandingOrderRepository) GetRuns(orderID uint) ([]models.StandingOrderRun, error) {
+	rows, err := r.db.Query(
+		`SELECT id, order_id, scheduled_for, attempt, status, COALESCE(error, ''),
+		        COALESCE(transaction_id, 0), created_at
+		 FROM standing_order_runs WHERE order_id = $1 ORDER BY id`,
+		orderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []models.StandingOrderRun
+	for rows.Next() {
+		var run models.StandingOrderRun
+		err := rows.Scan(
+			&run.ID,
+			&run.OrderID,
+			&run.ScheduledFor,
+			&run.Attempt,
+			&run.Status,
+			&run.Error,
+			&run.TransactionID,
+			&run.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+func (r *StandingOrderRepository) list(query string, args ...interface{}) ([]models.StandingOrder, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []models.StandingOrder
+	for rows.Next() {
+		order, err := scanStandingOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
+	return orders, rows.Err()
+}
+
+func scanStandingOrder(row rowScanner) (*models.StandingOrder, error) {
+	o := &models.StandingOrder{}
+	var endDate, nextRunDate, nextAttemptAt sql.NullTime
+	err := row.Scan(
+		&o.ID,
+		&o.UserID,
+		&o.FromAccountID,
+		&o.ToAccountID,
+		&o.Amount,
+		&o.Frequency,
+		&o.DayOfMonth,
+		&o.StartDate,
+		&endDate,
+		&o.MaxRuns,
+		&o.RunsCount,
+		&nextRunDate,
+		&o.Attempt,
+		&nextAttemptAt,
+		&o.Status,
+		&o.CreatedAt,
+		&o.UpdatedAt,
+	)
+	if endDate.Valid {
+		o.EndDate = &endDate.Time
+	}
+	if nextRunDate.Valid {
+		o.NextRunDate = &nextRunDate.Time
+	}
+	if nextAttemptAt.Valid {
+		o.NextAttemptAt = &nextAttemptAt.Time
+	}
+	return o, err
+}
@@ -2,11 +2,18 @@ package repositories
 
 import (
     "bank-service/src/models"
+    "bank-service/src/money"
     "database/sql"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
     "time"
     "github.com/sirupsen/logrus"
 )
 
+var ErrTransactionNotFound = errors.New("transaction not found")
+
 type TransactionRepository struct {
     db     *sql.DB
     logger *logrus.Logger
@@ -17,37 +24,288 @@ func NewTransactionRepository(db *sql.DB, logger *logrus.Logger) *TransactionRep
 }
 
 func (r *TransactionRepository) Create(transaction *models.Transaction) error {
-    return r.db.QueryRow(
-        `INSERT INTO transactions (from_account_id, to_account_id, amount, currency)
-         VALUES ($1, $2, $3, $4)
+    return r.createWith(r.db, transaction)
+}
+
+func (r *TransactionRepository) CreateTx(tx *sql.Tx, transaction *models.Transaction) error {
+    return r.createWith(tx, transaction)
+}
+
+func (r *TransactionRepository) createWith(q queryRower, transaction *models.Transaction) error {
+    if transaction.Type == "" {
+        transaction.Type = models.TransactionTransfer
+    }
+    // Без конвертации зачисляется та же сумма в той же валюте
+    if transaction.ToAccountID != 0 && transaction.ToCurrency == "" {
+        transaction.ToAmount = transaction.Amount
+        transaction.ToCurrency = transaction.Currency
+    }
+
+    var toAmount, toCurrency, rate interface{}
+    if transaction.ToCurrency != "" {
+        toAmount, toCurrency = transaction.ToAmount, transaction.ToCurrency
+    }
+    if transaction.ExchangeRate != 0 {
+        rate = transaction.ExchangeRate
+    }
+    return q.QueryRow(
+        `INSERT INTO transactions (from_account_id, to_account_id, amount, currency, type, to_amount, to_currency, exchange_rate, reversal_of, card_id)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
          RETURNING id, created_at`,
-        transaction.FromAccountID,
-        transaction.ToAccountID,
+        nullableID(transaction.FromAccountID),
+        nullableID(transaction.ToAccountID),
         transaction.Amount,
         transaction.Currency,
+        transaction.Type,
+        toAmount,
+        toCurrency,
+        rate,
+        nullableID(transaction.ReversalOf),
+        nullableID(transaction.CardID),
     ).Scan(&transaction.ID, &transaction.CreatedAt)
 }
 
-func (r *TransactionRepository) SumIncome(userID uint, start, end time.Time) (float64, error) {
-    var income float64
+// Общий интерфейс *sql.DB и *sql.Tx для однострочных запросов
+type queryRower interface {
+    QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Нулевой идентификатор сохраняется как NULL (внешний или системный счет)
+func nullableID(id uint) interface{} {
+    if id == 0 {
+        return nil
+    }
+    return id
+}
+
+func (r *TransactionRepository) SumIncome(userID uint, start, end time.Time) (money.Amount, error) {
+    var income money.Amount
     err := r.db.QueryRow(
-        `SELECT COALESCE(SUM(amount), 0) 
+        `SELECT COALESCE(SUM(COALESCE(t.to_amount, t.amount)), 0) 
          FROM transactions t
          JOIN accounts a ON t.to_account_id = a.id
-         WHERE a.user_id = \$1 AND t.created_at BETWEEN \$2 AND \$3`,
+         WHERE a.user_id = $1 AND t.created_at BETWEEN $2 AND $3`,
         userID, start, end,
     ).Scan(&income)
     return income, err
 }
 
-func (r *TransactionRepository) SumExpenses(userID uint, start, end time.Time) (float64, error) {
-    var expenses float64
+func (r *TransactionRepository) SumExpenses(userID uint, start, end time.Time) (money.Amount, error) {
+    var expenses money.Amount
     err := r.db.QueryRow(
         `SELECT COALESCE(SUM(amount), 0)
          FROM transactions t
          JOIN accounts a ON t.from_account_id = a.id
-         WHERE a.user_id = \$1 AND t.created_at BETWEEN \$2 AND \$3`,
+         WHERE a.user_id = $1 AND t.created_at BETWEEN $2 AND $3`,
         userID, start, end,
     ).Scan(&expenses)
     return expenses, err
-}
\ No newline at end of file
+}
+
+// Поступления на счета клиента от третьих лиц по валютам зачисления.
+// Выдача кредитов, возвраты и переводы между своими счетами (в том числе
+// обмен валюты) доходом не считаются.
+func (r *TransactionRepository) SumExternalIncome(userID uint, start, end time.Time) (map[string]money.Amount, error) {
+    return r.sumByCurrency(
+        `SELECT COALESCE(NULLIF(t.to_currency, ''), t.currency), SUM(COALESCE(t.to_amount, t.amount))
+         FROM transactions t
+         JOIN accounts a ON t.to_account_id = a.id
+         WHERE a.user_id = $1 AND t.created_at BETWEEN $2 AND $3
+           AND t.type NOT IN ($4, $5)
+           AND NOT EXISTS (SELECT 1 FROM accounts o WHERE o.id = t.from_account_id AND o.user_id = $1)
+         GROUP BY 1`,
+        userID, start, end, models.TransactionDisbursement, models.TransactionReversal,
+    )
+}
+
+// Списания со счетов клиента в пользу третьих лиц по валютам списания,
+// с теми же исключениями, что и в SumExternalIncome
+func (r *TransactionRepository) SumExternalExpenses(userID uint, start, end time.Time) (map[string]money.Amount, error) {
+    return r.sumByCurrency(
+        `SELECT t.currency, SUM(t.amount)
+         FROM transactions t
+         JOIN accounts a ON t.from_account_id = a.id
+         WHERE a.user_id = $1 AND t.created_at BETWEEN $2 AND $3
+           AND t.type NOT IN ($4, $5)
+           AND NOT EXISTS (SELECT 1 FROM accounts o WHERE o.id = t.to_account_id AND o.user_id = $1)
+         GROUP BY 1`,
+        userID, start, end, models.TransactionDisbursement, models.TransactionReversal,
+    )
+}
+
+func (r *TransactionRepository) sumByCurrency(query string, args ...interface{}) (map[string]money.Amount, error) {
+    rows, err := r.db.Query(query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    sums := make(map[string]money.Amount)
+    for rows.Next() {
+        var currency string
+        var sum money.Amount
+        if err := rows.Scan(&currency, &sum); err != nil {
+            return nil, err
+        }
+        sums[currency] = sum
+    }
+    return sums, rows.Err()
+}
+// Колонки операции в порядке сканирования scanTransaction
+const transactionColumns = `id, COALESCE(from_account_id, 0), COALESCE(to_account_id, 0), amount, currency, type,
+    COALESCE(to_amount, 0), COALESCE(to_currency, ''), COALESCE(exchange_rate, 0),
+    COALESCE(reversal_of, 0), COALESCE(card_id, 0), created_at, write_xid::text::bigint`
+
+// Направления операции относительно счета
+const (
+    DirectionIn  = "in"
+    DirectionOut = "out"
+)
+
+// Отбор операций по счету. Нулевые значения полей не ограничивают выборку.
+// Суммы сравниваются в валюте счета: для входящих - зачисленная сумма.
+type TransactionFilter struct {
+    AccountID      uint
+    Direction      string
+    From, To       time.Time
+    MinAmount      money.Amount
+    MaxAmount      money.Amount
+    CounterpartyID uint
+
+    // Курсор: операции строго после AfterID в порядке выдачи. При Sync
+    // порядок - (AfterXID, AfterID), см. List
+    AfterID   uint
+    AfterXID  uint64
+    Ascending bool
+    Sync      bool // курсор синхронизации истории; только вместе с Ascending
+    Limit     int  // 0 - без ограничения
+}
+
+// Операции по счету в стабильном порядке: по убыванию id, по возрастанию
+// created_at, при синхронизации - по (write_xid, id)
+func (r *TransactionRepository) List(f TransactionFilter) ([]models.Transaction, error) {
+    args := []interface{}{f.AccountID}
+    arg := func(v interface{}) string {
+        args = append(args, v)
+        return fmt.Sprintf("$%d", len(args))
+    }
+
+    conds := []string{"(from_account_id = $1 OR to_account_id = $1)"}
+    switch f.Direction {
+    case DirectionIn:
+        conds = append(conds, "to_account_id = $1")
+    case DirectionOut:
+        conds = append(conds, "from_account_id = $1")
+    }
+    if !f.From.IsZero() {
+        conds = append(conds, "created_at >= "+arg(f.From))
+    }
+    if !f.To.IsZero() {
+        conds = append(conds, "created_at < "+arg(f.To))
+    }
+
+    accountAmount := "(CASE WHEN to_account_id = $1 THEN COALESCE(to_amount, amount) ELSE amount END)"
+    if f.MinAmount > 0 {
+        conds = append(conds, accountAmount+" >= "+arg(f.MinAmount))
+    }
+    if f.MaxAmount > 0 {
+        conds = append(conds, accountAmount+" <= "+arg(f.MaxAmount))
+    }
+    if f.CounterpartyID != 0 {
+        conds = append(conds, "(CASE WHEN from_account_id = $1 THEN to_account_id ELSE from_account_id END) = "+arg(f.CounterpartyID))
+    }
+
+    orderBy := "id DESC"
+    switch {
+    case f.Sync:
+        // При синхронизации выдаются только операции транзакций БД старше
+        // самой старой из выполняющихся: все, что станет видимым позже,
+        // получит write_xid не меньше и окажется после курсора
+        conds = append(conds, "write_xid < pg_snapshot_xmin(pg_current_snapshot())")
+        orderBy = "write_xid, id"
+    case f.Ascending:
+        orderBy = "created_at, id"
+    }
+    switch {
+    case f.AfterID == 0:
+    case f.Sync && f.AfterXID != 0:
+        conds = append(conds, "(write_xid, id) > ("+arg(strconv.FormatUint(f.AfterXID, 10))+"::xid8, "+arg(f.AfterID)+")")
+    case f.Ascending:
+        conds = append(conds, "id > "+arg(f.AfterID))
+    default:
+        conds = append(conds, "id < "+arg(f.AfterID))
+    }
+
+    query := `SELECT ` + transactionColumns + ` FROM transactions
+         WHERE ` + strings.Join(conds, " AND ") + `
+         ORDER BY ` + orderBy
+    if f.Limit > 0 {
+        query += ` LIMIT ` + arg(f.Limit)
+    }
+
+    rows, err := r.db.Query(query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    transactions := []models.Transaction{}
+    for rows.Next() {
+        t, err := scanTransaction(rows)
+        if err != nil {
+            return nil, err
+        }
+        transactions = append(transactions, *t)
+    }
+    return transactions, rows.Err()
+}
+
+func scanTransaction(row rowScanner) (*models.Transaction, error) {
+    t := &models.Transaction{}
+    err := row.Scan(
+        &t.ID,
+        &t.FromAccountID,
+        &t.ToAccountID,
+        &t.Amount,
+        &t.Currency,
+        &t.Type,
+        &t.ToAmount,
+        &t.ToCurrency,
+        &t.ExchangeRate,
+        &t.ReversalOf,
+        &t.CardID,
+        &t.CreatedAt,
+        &t.WriteXID,
+    )
+    return t, err
+}
+
+// Операция с блокировкой строки: возвраты по одному переводу
+// выполняются строго последовательно
+func (r *TransactionRepository) GetByIDForUpdateTx(tx *sql.Tx, id uint) (*models.Transaction, error) {
+    t, err := scanTransaction(tx.QueryRow(`SELECT `+transactionColumns+` FROM transactions WHERE id = $1 FOR UPDATE`, id))
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, ErrTransactionNotFound
+    }
+    return t, err
+}
+
+// Суммы уже выполненных возвратов по переводу: списано у получателя
+// (в его валюте) и возвращено отправителю (в валюте перевода)
+func (r *TransactionRepository) SumReversalsTx(tx *sql.Tx, id uint) (debited, refunded money.Amount, err error) {
+    err = tx.QueryRow(
+        `SELECT COALESCE(SUM(amount), 0), COALESCE(SUM(COALESCE(to_amount, amount)), 0)
+         FROM transactions WHERE reversal_of = $1`,
+        id,
+    ).Scan(&debited, &refunded)
+    return debited, refunded, err
+}
+
+func (r *TransactionRepository) RecordReversalTx(tx *sql.Tx, reversal *models.Transaction, staffID uint, reason string) error {
+    _, err := tx.Exec(
+        `INSERT INTO transaction_reversals (transaction_id, original_id, staff_id, reason)
+         VALUES ($1, $2, $3, $4)`,
+        reversal.ID, reversal.ReversalOf, staffID, reason,
+    )
+    return err
+}
@@ -9,7 +9,8 @@ import (
 )
 
 var (
-	ErrUserExists = errors.New("user already exists")
+	ErrUserExists   = errors.New("user already exists")
+	ErrUserNotFound = errors.New("user not found")
 )
 
 type UserRepository struct {
@@ -23,11 +24,15 @@ func NewUserRepository(db *sql.DB, logger *logrus.Logger) *UserRepository {
 
 func (r *UserRepository) Create(user *models.User) error {
 	query := `
-		INSERT INTO users (username, email, password_hash) 
-		VALUES ($1, $2, $3) 
+		INSERT INTO users (username, email, phone, password_hash) 
+		VALUES ($1, $2, $3, $4) 
 		RETURNING id, created_at`
 		
-	err := r.db.QueryRow(query, user.Username, user.Email, user.PasswordHash).Scan(
+	var phone interface{}
+	if user.Phone != "" {
+		phone = user.Phone
+	}
+	err := r.db.QueryRow(query, user.Username, user.Email, phone, user.PasswordHash).Scan(
 		&user.ID, &user.CreatedAt)
 		
 	if err != nil {
@@ -42,15 +47,37 @@ func (r *UserRepository) Create(user *models.User) error {
 func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	user := &models.User{}
 	query := `
-		SELECT id, username, email, password_hash, created_at 
+		SELECT id, username, email, COALESCE(phone, ''), role, password_hash, created_at 
 		FROM users WHERE email = $1`
 		
 	err := r.db.QueryRow(query, email).Scan(
-		&user.ID, &user.Username, &user.Email, 
+		&user.ID, &user.Username, &user.Email, &user.Phone, &user.Role,
 		&user.PasswordHash, &user.CreatedAt)
 		
 	if err != nil {
 		r.logger.WithError(err).Error("User not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *UserRepository) GetByPhone(phone string) (*models.User, error) {
+	user := &models.User{}
+	query := `
+		SELECT id, username, email, COALESCE(phone, ''), role, password_hash, created_at 
+		FROM users WHERE phone = $1`
+
+	err := r.db.QueryRow(query, phone).Scan(
+		&user.ID, &user.Username, &user.Email, &user.Phone, &user.Role,
+		&user.PasswordHash, &user.CreatedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
 		return nil, err
 	}
 	return user, nil
@@ -59,11 +86,11 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 func (r *UserRepository) GetByID(id uint) (*models.User, error) {
 	user := &models.User{}
 	query := `
-		SELECT id, username, email, password_hash, created_at 
+		SELECT id, username, email, COALESCE(phone, ''), role, password_hash, created_at 
 		FROM users WHERE id = $1`
 		
 	err := r.db.QueryRow(query, id).Scan(
-		&user.ID, &user.Username, &user.Email, 
+		&user.ID, &user.Username, &user.Email, &user.Phone, &user.Role,
 		&user.PasswordHash, &user.CreatedAt)
 		
 	if err != nil {
@@ -76,3 +103,14 @@ func (r *UserRepository) GetByID(id uint) (*models.User, error) {
 	}
 	return user, nil
 }
+
+// Блокировка строки пользователя: счетчики клиента (комиссии, лимиты)
+// читаются и пополняются строго последовательно
+func (r *UserRepository) LockTx(tx *sql.Tx, userID uint) error {
+	var id uint
+	err := tx.QueryRow(`SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	return err
+}
@@ -2,33 +2,74 @@ package services
 
 import (
     "bank-service/src/models"
+    "bank-service/src/money"
     "bank-service/src/repositories"
+    "bank-service/src/statement"
+    "database/sql"
+    "encoding/base64"
     "errors"
+    "strconv"
+    "strings"
+    "time"
     "github.com/sirupsen/logrus"
 )
 
+var (
+    ErrInsufficientFunds = errors.New("insufficient funds")
+    ErrAccountFrozen     = errors.New("account is frozen")
+    ErrSameAccount       = errors.New("cannot transfer to the same account")
+)
+
 type AccountService struct {
     accountRepo     *repositories.AccountRepository
     transactionRepo *repositories.TransactionRepository
+    ledgerRepo      *repositories.LedgerRepository
     logger          *logrus.Logger
+    rates           *ExchangeRateService
+    fees            *FeeService
+    limits          *LimitService
+    cardRepo        *repositories.CardRepository
+}
+
+// Часть списания в пользу системного счета банка
+type Charge struct {
+    SystemAccount string
+    Amount        money.Amount
 }
 
 func NewAccountService(
     accountRepo *repositories.AccountRepository,
     transactionRepo *repositories.TransactionRepository,
+    ledgerRepo *repositories.LedgerRepository,
     logger *logrus.Logger,
+    rates *ExchangeRateService,
+    fees *FeeService,
+    limits *LimitService,
+    cardRepo *repositories.CardRepository,
 ) *AccountService {
     return &AccountService{
         accountRepo:     accountRepo,
         transactionRepo: transactionRepo,
+        ledgerRepo:      ledgerRepo,
         logger:          logger,
+        rates:           rates,
+        fees:            fees,
+        limits:          limits,
+        cardRepo:        cardRepo,
     }
 }
 
-func (s *AccountService) CreateAccount(userID uint) (*models.Account, error) {
+func (s *AccountService) CreateAccount(userID uint, currency string) (*models.Account, error) {
+    if currency == "" {
+        currency = models.CurrencyRUB
+    }
+    if !models.IsSupportedCurrency(currency) {
+        return nil, ErrUnsupportedCurrency
+    }
+
     account := &models.Account{
         UserID:   userID,
-        Currency: "RUB",
+        Currency: currency,
     }
 
     if err := s.accountRepo.Create(account); err != nil {
@@ -42,40 +83,533 @@ func (s *AccountService) GetAccount(accountID uint) (*models.Account, error) {
     return s.accountRepo.GetByID(accountID)
 }
 
-func (s *AccountService) Transfer(fromAccountID, toAccountID uint, amount float64) error {
-    tx, err := s.accountRepo.BeginTx()
+// Перевод между счетами. Если валюты счетов различаются, сумма списывается
+// в валюте отправителя и зачисляется в валюте получателя по курсу ЦБ на
+// текущую дату; обе суммы и курс сохраняются в операции. Комиссия
+// списывается с отправителя в той же транзакции.
+func (s *AccountService) Transfer(fromAccountID, toAccountID uint, amount money.Amount) (*models.Transaction, error) {
+    return s.transfer(0, fromAccountID, toAccountID, amount, nil)
+}
+
+// Перевод, результат которого вызывающий записывает в той же транзакции:
+// если after вернет ошибку, перевод откатывается вместе с записью.
+// При повторе транзакции after вызывается заново.
+func (s *AccountService) TransferWith(fromAccountID, toAccountID uint, amount money.Amount, after func(tx *sql.Tx, transaction *models.Transaction) error) (*models.Transaction, error) {
+    return s.transfer(0, fromAccountID, toAccountID, amount, after)
+}
+
+// Перевод по карте: кроме лимитов клиента действуют лимиты карты.
+// Счет списания - счет карты, его определяет вызывающий.
+func (s *AccountService) TransferByCard(cardID, fromAccountID, toAccountID uint, amount money.Amount) (*models.Transaction, error) {
+    return s.transfer(cardID, fromAccountID, toAccountID, amount, nil)
+}
+
+func (s *AccountService) transfer(cardID, fromAccountID, toAccountID uint, amount money.Amount, after func(tx *sql.Tx, transaction *models.Transaction) error) (*models.Transaction, error) {
+    if fromAccountID == toAccountID {
+        return nil, ErrSameAccount
+    }
+    if amount <= 0 {
+        return nil, errors.New("amount must be positive")
+    }
+
+    from, err := s.accountRepo.GetByID(fromAccountID)
+    if err != nil {
+        return nil, err
+    }
+    to, err := s.accountRepo.GetByID(toAccountID)
+    if err != nil {
+        return nil, err
+    }
+
+    transaction := &models.Transaction{
+        FromAccountID: fromAccountID,
+        ToAccountID:   toAccountID,
+        Amount:        amount,
+        Currency:      from.Currency,
+        Type:          models.TransactionTransfer,
+        CardID:        cardID,
+    }
+    if from.Currency != to.Currency {
+        rate, err := s.rates.GetRate(from.Currency, to.Currency, time.Now())
+        if err != nil {
+            return nil, err
+        }
+        transaction.ToAmount = convert(amount, rate)
+        transaction.ToCurrency = to.Currency
+        transaction.ExchangeRate = rate
+        if transaction.ToAmount <= 0 {
+            return nil, ErrAmountTooSmall
+        }
+    }
+
+    operation := models.FeeTransfer
+    if transaction.ToCurrency != "" {
+        operation = models.FeeCrossCurrencyTransfer
+    }
+
+    // Лимиты проверяются до блокировки счетов: сначала строка клиента, затем счета
+    err = s.accountRepo.RunInTx(func(tx *sql.Tx) error {
+        if cardID != 0 {
+            status, err := s.cardRepo.GetStatusForShareTx(tx, cardID)
+            if err != nil {
+                return err
+            }
+            if status != models.CardActive {
+                return ErrCardNotActive
+            }
+        }
+        amountRUB, err := s.limits.CheckTx(tx, from.UserID, cardID, amount, from.Currency)
+        if err != nil {
+            return err
+        }
+        if err := s.TransferTx(tx, transaction); err != nil {
+            return err
+        }
+        if err := s.limits.RecordTx(tx, from.UserID, cardID, amountRUB, transaction.ID); err != nil {
+            return err
+        }
+        if _, err := s.ChargeFeeTx(tx, from.UserID, fromAccountID, operation, amount, from.Currency, transaction.ID); err != nil {
+            return err
+        }
+        if after != nil {
+            return after(tx, transaction)
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return transaction, nil
+}
+
+// Проведение заполненной операции перевода в транзакции вызывающего:
+// списание с проверкой остатка и зачисление (с конвертацией, если задана ToCurrency).
+// Оба счета блокируются в порядке id до любых изменений балансов.
+func (s *AccountService) TransferTx(tx *sql.Tx, transaction *models.Transaction) error {
+    if transaction.FromAccountID == transaction.ToAccountID {
+        return ErrSameAccount
+    }
+
+    from, to, err := s.accountRepo.GetPairForUpdateTx(tx, transaction.FromAccountID, transaction.ToAccountID)
     if err != nil {
         return err
     }
+    if from.Status == models.AccountFrozen || to.Status == models.AccountFrozen {
+        return ErrAccountFrozen
+    }
+    if from.AvailableBalance < transaction.Amount {
+        return ErrInsufficientFunds
+    }
+    return s.recordTx(tx, transaction, transferPostings(transaction)...)
+}
+
+// Записи перевода между клиентскими счетами. При конвертации деньги
+// проходят через валютную позицию банка, чтобы проводка сходилась
+// по каждой валюте отдельно.
+func transferPostings(t *models.Transaction) []models.Posting {
+    if t.ToCurrency == "" || t.ToCurrency == t.Currency {
+        return []models.Posting{
+            {AccountID: t.FromAccountID, Direction: models.PostingDebit, Amount: t.Amount},
+            {AccountID: t.ToAccountID, Direction: models.PostingCredit, Amount: t.Amount},
+        }
+    }
+    return []models.Posting{
+        {AccountID: t.FromAccountID, Direction: models.PostingDebit, Amount: t.Amount, Currency: t.Currency},
+        {SystemAccount: models.SystemAccountFXPosition, Direction: models.PostingCredit, Amount: t.Amount, Currency: t.Currency},
+        {SystemAccount: models.SystemAccountFXPosition, Direction: models.PostingDebit, Amount: t.ToAmount, Currency: t.ToCurrency},
+        {AccountID: t.ToAccountID, Direction: models.PostingCredit, Amount: t.ToAmount, Currency: t.ToCurrency},
+    }
+}
+
+// Пополнение счета: поступление денег извне через системный счет cash_in
+// в валюте счета
+func (s *AccountService) Deposit(accountID uint, amount money.Amount) (*models.Transaction, error) {
+    if amount <= 0 {
+        return nil, errors.New("amount must be positive")
+    }
+
+    account, err := s.accountRepo.GetByID(accountID)
+    if err != nil {
+        return nil, err
+    }
+
+    tx, err := s.accountRepo.BeginTx()
+    if err != nil {
+        return nil, err
+    }
     defer tx.Rollback()
 
-    if err := s.accountRepo.UpdateBalanceTx(tx, fromAccountID, -amount); err != nil {
-        return err
+    transaction := &models.Transaction{
+        ToAccountID: accountID,
+        Amount:      amount,
+        Currency:    account.Currency,
+        Type:        models.TransactionDeposit,
+    }
+    err = s.recordTx(tx, transaction,
+        models.Posting{SystemAccount: models.SystemAccountCashIn, Direction: models.PostingDebit, Amount: amount},
+        models.Posting{AccountID: accountID, Direction: models.PostingCredit, Amount: amount},
+    )
+    if err != nil {
+        return nil, err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    return transaction, nil
+}
+
+// Списание со счета клиента в пользу системных счетов банка
+// (платежи по кредитам, штрафы, комиссии)
+func (s *AccountService) ChargeAccount(accountID uint, txType string, charges ...Charge) (*models.Transaction, error) {
+    tx, err := s.accountRepo.BeginTx()
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+
+    transaction, err := s.ChargeAccountTx(tx, accountID, txType, charges...)
+    if err != nil {
+        return nil, err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    return transaction, nil
+}
+
+func (s *AccountService) ChargeAccountTx(tx *sql.Tx, accountID uint, txType string, charges ...Charge) (*models.Transaction, error) {
+    var total money.Amount
+    postings := make([]models.Posting, 0, len(charges)+1)
+    for _, c := range charges {
+        if c.Amount <= 0 {
+            continue
+        }
+        total += c.Amount
+        postings = append(postings, models.Posting{
+            SystemAccount: c.SystemAccount,
+            Direction:     models.PostingCredit,
+            Amount:        c.Amount,
+        })
+    }
+    if total <= 0 {
+        return nil, errors.New("amount must be positive")
+    }
+
+    account, err := s.accountRepo.GetByIDForUpdateTx(tx, accountID)
+    if err != nil {
+        return nil, err
+    }
+    if account.AvailableBalance < total {
+        return nil, ErrInsufficientFunds
+    }
+    postings = append(postings, models.Posting{AccountID: accountID, Direction: models.PostingDebit, Amount: total})
+
+    transaction := &models.Transaction{
+        FromAccountID: accountID,
+        Amount:        total,
+        Currency:      account.Currency,
+        Type:          txType,
+    }
+    if err := s.recordTx(tx, transaction, postings...); err != nil {
+        return nil, err
+    }
+    return transaction, nil
+}
+
+// Комиссия за операцию operation на сумму amount: расчет по тарифу,
+// списание в доход банка и учет в месячном обороте клиента. Списание
+// идет в транзакции самой операции, поэтому без комиссии операция не пройдет.
+func (s *AccountService) ChargeFeeTx(tx *sql.Tx, userID, accountID uint, operation string, amount money.Amount, currency string, transactionID uint) (*models.FeeCharge, error) {
+    charge, err := s.fees.CalculateTx(tx, userID, operation, amount, currency)
+    if err != nil {
+        return nil, err
     }
+    charge.TransactionID = transactionID
 
-    if err := s.accountRepo.UpdateBalanceTx(tx, toAccountID, amount); err != nil {
+    if charge.Fee > 0 {
+        feeTransaction, err := s.ChargeAccountTx(tx, accountID, models.TransactionFee,
+            Charge{SystemAccount: models.SystemAccountBankRevenue, Amount: charge.Fee},
+        )
+        if err != nil {
+            return nil, err
+        }
+        charge.FeeTransactionID = feeTransaction.ID
+    }
+
+    if err := s.fees.RecordTx(tx, charge); err != nil {
+        return nil, err
+    }
+    return charge, nil
+}
+
+// Блокировка суммы авторизации по карте: баланс не меняется,
+// уменьшается доступный остаток
+func (s *AccountService) PlaceHoldTx(tx *sql.Tx, accountID uint, amount money.Amount) error {
+    account, err := s.accountRepo.GetByIDForUpdateTx(tx, accountID)
+    if err != nil {
         return err
     }
+    if account.Status == models.AccountFrozen {
+        return ErrAccountFrozen
+    }
+    if account.AvailableBalance < amount {
+        return ErrInsufficientFunds
+    }
+    return s.accountRepo.AddHoldTx(tx, accountID, amount)
+}
 
-    if _, err := tx.Exec(
-        `INSERT INTO transactions (from_account_id, to_account_id, amount, currency)
-         VALUES ($1, $2, $3, 'RUB')`,
-        fromAccountID, toAccountID, amount,
-    ); err != nil {
+// Снятие блокировки авторизации при отмене или истечении
+func (s *AccountService) ReleaseHoldTx(tx *sql.Tx, accountID uint, amount money.Amount) error {
+    if _, err := s.accountRepo.GetByIDForUpdateTx(tx, accountID); err != nil {
         return err
     }
+    return s.accountRepo.AddHoldTx(tx, accountID, -amount)
+}
+
+// Списание по авторизации: блокировка снимается целиком, amount (не больше
+// заблокированного) списывается в расчеты с эквайерами. Заморозка счета
+// после авторизации списанию не мешает - оплата уже подтверждена.
+func (s *AccountService) CaptureHoldTx(tx *sql.Tx, hold *models.CardHold, amount money.Amount) (*models.Transaction, error) {
+    account, err := s.accountRepo.GetByIDForUpdateTx(tx, hold.AccountID)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.accountRepo.AddHoldTx(tx, hold.AccountID, -hold.Amount); err != nil {
+        return nil, err
+    }
+    if account.AvailableBalance+hold.Amount < amount {
+        return nil, ErrInsufficientFunds
+    }
 
-    return tx.Commit()
+    transaction := &models.Transaction{
+        FromAccountID: hold.AccountID,
+        Amount:        amount,
+        Currency:      hold.Currency,
+        Type:          models.TransactionCardPayment,
+        CardID:        hold.CardID,
+    }
+    err = s.recordTx(tx, transaction,
+        models.Posting{AccountID: hold.AccountID, Direction: models.PostingDebit, Amount: amount},
+        models.Posting{SystemAccount: models.SystemAccountCardSettlement, Direction: models.PostingCredit, Amount: amount},
+    )
+    if err != nil {
+        return nil, err
+    }
+    return transaction, nil
 }
 
+// Возврат списания по авторизации на счет карты
+func (s *AccountService) RefundCardPaymentTx(tx *sql.Tx, hold *models.CardHold) (*models.Transaction, error) {
+    if _, err := s.accountRepo.GetByIDForUpdateTx(tx, hold.AccountID); err != nil {
+        return nil, err
+    }
+
+    transaction := &models.Transaction{
+        ToAccountID: hold.AccountID,
+        Amount:      hold.CapturedAmount,
+        Currency:    hold.Currency,
+        Type:        models.TransactionReversal,
+        ReversalOf:  hold.TransactionID,
+        CardID:      hold.CardID,
+    }
+    err := s.recordTx(tx, transaction,
+        models.Posting{SystemAccount: models.SystemAccountCardSettlement, Direction: models.PostingDebit, Amount: hold.CapturedAmount},
+        models.Posting{AccountID: hold.AccountID, Direction: models.PostingCredit, Amount: hold.CapturedAmount},
+    )
+    if err != nil {
+        return nil, err
+    }
+    return transaction, nil
+}
 
-func (s *AccountService) Deposit(accountID uint, amount float64) error {
+// Зачисление на счет клиента с системного счета банка в рамках внешней транзакции БД
+// (например, выдача кредита вместе с созданием самого кредита)
+func (s *AccountService) FundAccountTx(tx *sql.Tx, systemAccount string, accountID uint, amount money.Amount, txType string) (*models.Transaction, error) {
     if amount <= 0 {
-        return errors.New("amount must be positive")
+        return nil, errors.New("amount must be positive")
+    }
+
+    // Системные счета выдачи кредитов ведутся в рублях
+    transaction := &models.Transaction{
+        ToAccountID: accountID,
+        Amount:      amount,
+        Currency:    models.CurrencyRUB,
+        Type:        txType,
+    }
+    err := s.recordTx(tx, transaction,
+        models.Posting{SystemAccount: systemAccount, Direction: models.PostingDebit, Amount: amount},
+        models.Posting{AccountID: accountID, Direction: models.PostingCredit, Amount: amount},
+    )
+    if err != nil {
+        return nil, err
+    }
+    return transaction, nil
+}
+
+// Счет с блокировкой строки до конца транзакции вызывающего
+func (s *AccountService) GetForUpdateTx(tx *sql.Tx, accountID uint) (*models.Account, error) {
+    return s.accountRepo.GetByIDForUpdateTx(tx, accountID)
+}
+
+func (s *AccountService) BeginTx() (*sql.Tx, error) {
+    return s.accountRepo.BeginTx()
+}
+
+// Транзакция с повтором при конфликте сериализации или взаимной блокировке
+func (s *AccountService) RunInTx(fn func(tx *sql.Tx) error) error {
+    return s.accountRepo.RunInTx(fn)
+}
+
+// Сохраняет операцию и объясняющую ее проводку в рамках транзакции БД
+func (s *AccountService) recordTx(tx *sql.Tx, transaction *models.Transaction, postings ...models.Posting) error {
+    if err := s.transactionRepo.CreateTx(tx, transaction); err != nil {
+        return err
     }
-    return s.accountRepo.UpdateBalance(accountID, amount)
+
+    for i := range postings {
+        if postings[i].Currency == "" {
+            postings[i].Currency = transaction.Currency
+        }
+    }
+    return s.ledgerRepo.CreateEntryTx(tx, &models.JournalEntry{
+        TransactionID: transaction.ID,
+        Kind:          transaction.Type,
+        Postings:      postings,
+    })
+}
+
+// Сверка сохраненного баланса с балансом, рассчитанным по журналу
+func (s *AccountService) ReconcileBalance(accountID uint) (stored, ledger money.Amount, err error) {
+    account, err := s.accountRepo.GetByID(accountID)
+    if err != nil {
+        return 0, 0, err
+    }
+
+    ledger, err = s.ledgerRepo.CalculateBalance(accountID)
+    if err != nil {
+        return 0, 0, err
+    }
+    return account.Balance, ledger, nil
 }
 
 func (s *AccountService) GetByIDAndUser(accountID, userID uint) (*models.Account, error) {
     return s.accountRepo.GetByIDAndUser(accountID, userID)
-}
\ No newline at end of file
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Размер страницы истории операций
+const (
+    defaultHistoryLimit = 50
+    maxHistoryLimit     = 200
+)
+
+// Страница истории операций. NextCursor пуст, если операций больше нет;
+// при синхронизации в порядке возрастания по нему забираются новые операции.
+// Синхронизация отстает от еще не зафиксированных операций, поэтому только
+// что проведенная операция может появиться в следующем запросе.
+type TransactionPage struct {
+    Transactions []models.Transaction `json:"transactions"`
+    NextCursor   string               `json:"next_cursor,omitempty"`
+}
+
+// История операций по счету с курсорной пагинацией
+func (s *AccountService) GetTransactions(filter repositories.TransactionFilter, cursor string) (*TransactionPage, error) {
+    if cursor != "" {
+        xid, id, err := decodeCursor(cursor)
+        if err != nil {
+            return nil, err
+        }
+        filter.AfterXID, filter.AfterID = xid, id
+    }
+    // История по возрастанию - это синхронизация по курсору
+    filter.Sync = filter.Ascending
+    if filter.Limit <= 0 {
+        filter.Limit = defaultHistoryLimit
+    }
+    if filter.Limit > maxHistoryLimit {
+        filter.Limit = maxHistoryLimit
+    }
+
+    // Лишняя запись показывает, есть ли следующая страница
+    limit := filter.Limit
+    filter.Limit++
+    transactions, err := s.transactionRepo.List(filter)
+    if err != nil {
+        return nil, err
+    }
+
+    page := &TransactionPage{Transactions: transactions}
+    if len(transactions) > limit {
+        page.Transactions = transactions[:limit]
+        page.NextCursor = encodeCursor(filter.Ascending, page.Transactions[limit-1])
+    } else if filter.Ascending && len(transactions) > 0 {
+        // Курсор синхронизации: следующий запрос вернет только новые операции
+        page.NextCursor = encodeCursor(true, transactions[len(transactions)-1])
+    } else if filter.Ascending {
+        page.NextCursor = cursor
+    }
+    return page, nil
+}
+
+// Курсор по убыванию - id, по возрастанию - "write_xid.id"
+func encodeCursor(ascending bool, t models.Transaction) string {
+    raw := strconv.FormatUint(uint64(t.ID), 10)
+    if ascending {
+        raw = strconv.FormatUint(t.WriteXID, 10) + "." + raw
+    }
+    return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Курсор без write_xid (выданный до его появления) продолжает выдачу по id
+func decodeCursor(cursor string) (uint64, uint, error) {
+    raw, err := base64.RawURLEncoding.DecodeString(cursor)
+    if err != nil {
+        return 0, 0, ErrInvalidCursor
+    }
+    var xid uint64
+    idPart := string(raw)
+    if i := strings.IndexByte(idPart, '.'); i >= 0 {
+        xid, err = strconv.ParseUint(idPart[:i], 10, 64)
+        if err != nil || xid == 0 {
+            return 0, 0, ErrInvalidCursor
+        }
+        idPart = idPart[i+1:]
+    }
+    id, err := strconv.ParseUint(idPart, 10, 64)
+    if err != nil || id == 0 {
+        return 0, 0, ErrInvalidCursor
+    }
+    return xid, uint(id), nil
+}
+
+// Выписка по счету за период [from, to): входящий остаток и операции
+// рассчитываются по таблице transactions
+func (s *AccountService) GetStatement(accountID uint, from, to time.Time) (*statement.Statement, error) {
+    if !from.Before(to) {
+        return nil, errors.New("invalid statement period")
+    }
+
+    account, err := s.accountRepo.GetByID(accountID)
+    if err != nil {
+        return nil, err
+    }
+
+    opening, err := s.ledgerRepo.BalanceBefore(accountID, from)
+    if err != nil {
+        return nil, err
+    }
+
+    transactions, err := s.transactionRepo.List(repositories.TransactionFilter{
+        AccountID: accountID,
+        From:      from,
+        To:        to,
+        Ascending: true,
+    })
+    if err != nil {
+        return nil, err
+    }
+    return statement.Build(*account, from, to, opening, transactions), nil
+}
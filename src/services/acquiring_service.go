@@ -0,0 +1,352 @@
+package services
+
+import (
+	"bank-service/src/crypto"
+	"bank-service/src/models"
+	"bank-service/src/money"
+	"bank-service/src/repositories"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrHoldCurrencyMismatch = errors.New("currency must match the card account currency")
+	ErrHoldNotAuthorized    = errors.New("authorization is already captured, voided or expired")
+	ErrCaptureExceedsHold   = errors.New("capture amount exceeds the authorized amount")
+)
+
+const (
+	// Срок блокировки суммы без списания
+	holdTTL = 7 * 24 * time.Hour
+
+	holdExpiryBatchSize = 100
+	acquirerKeyPrefix   = "acq_"
+)
+
+// Запрос авторизации от эквайрера. Currency необязательна и должна
+// совпадать с валютой счета карты; Reference - идентификатор операции
+// у эквайрера, уникальный в пределах эквайрера.
+type AuthorizationRequest struct {
+	CardNumber string       `json:"card_number"`
+	Expiry     string       `json:"expiry"`
+	CVV        string       `json:"cvv"`
+	Amount     money.Amount `json:"amount"`
+	Currency   string       `json:"currency"`
+	Merchant   string       `json:"merchant"`
+	Reference  string       `json:"reference"`
+}
+
+// Эквайринг: авторизация платежей по картам банка с блокировкой суммы
+// на счете, последующее списание (capture) или отмена (void)
+type AcquiringService struct {
+	holdRepo       *repositories.CardHoldRepository
+	acquirerRepo   *repositories.AcquirerRepository
+	cardRepo       *repositories.CardRepository
+	cardService    *CardService
+	accountService *AccountService
+	limits         *LimitService
+	logger         *logrus.Logger
+}
+
+func NewAcquiringService(
+	holdRepo *repositories.CardHoldRepository,
+	acquirerRepo *repositories.AcquirerRepository,
+	cardRepo *repositories.CardRepository,
+	cardService *CardService,
+	accountService *AccountService,
+	limits *LimitService,
+	logger *logrus.Logger,
+) *AcquiringService {
+	return &AcquiringService{
+		holdRepo:       holdRepo,
+		acquirerRepo:   acquirerRepo,
+		cardRepo:       cardRepo,
+		cardService:    cardService,
+		accountService: accountService,
+		limits:         limits,
+		logger:         logger,
+	}
+}
+
+// Регистрация эквайрера; ключ API возвращается только здесь
+func (s *AcquiringService) CreateAcquirer(name string) (*models.Acquirer, string, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, "", errors.New("name is required")
+	}
+	apiKey, err := crypto.GenerateAPIKey(acquirerKeyPrefix)
+	if err != nil {
+		return nil, "", err
+	}
+	acquirer := &models.Acquirer{Name: strings.TrimSpace(name)}
+	if err := s.acquirerRepo.Create(acquirer, crypto.HashAPIKey(apiKey)); err != nil {
+		return nil, "", err
+	}
+	s.logger.Infof("Acquirer %d (%s) registered", acquirer.ID, acquirer.Name)
+	return acquirer, apiKey, nil
+}
+
+// Авторизация: проверка реквизитов, статуса карты и лимитов, блокировка
+// суммы на счете карты
+func (s *AcquiringService) Authorize(acquirerID uint, req AuthorizationRequest) (*models.CardHold, error) {
+	if req.Amount <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+
+	card, err := s.cardService.VerifyCard(strings.Join(strings.Fields(req.CardNumber), ""), req.Expiry, req.CVV)
+	if err != nil {
+		return nil, err
+	}
+	account, err := s.accountService.GetByIDAndUser(card.AccountID, card.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Currency != "" && req.Currency != account.Currency {
+		return nil, ErrHoldCurrencyMismatch
+	}
+
+	authCode, err := generateAuthCode()
+	if err != nil {
+		return nil, err
+	}
+
+	var hold *models.CardHold
+	err = s.accountService.RunInTx(func(tx *sql.Tx) error {
+		// Карту могли заблокировать после проверки реквизитов
+		status, err := s.cardRepo.GetStatusForShareTx(tx, card.ID)
+		if err != nil {
+			return err
+		}
+		if status != models.CardActive {
+			return ErrCardNotActive
+		}
+
+		amountRUB, err := s.limits.CheckTx(tx, card.UserID, card.ID, req.Amount, account.Currency)
+		if err != nil {
+			return err
+		}
+		if err := s.accountService.PlaceHoldTx(tx, card.AccountID, req.Amount); err != nil {
+			return err
+		}
+
+		hold = &models.CardHold{
+			AcquirerID: acquirerID,
+			CardID:     card.ID,
+			AccountID:  card.AccountID,
+			Amount:     req.Amount,
+			Currency:   account.Currency,
+			Merchant:   req.Merchant,
+			Reference:  req.Reference,
+			AuthCode:   authCode,
+			Status:     models.HoldAuthorized,
+			ExpiresAt:  time.Now().Add(holdTTL),
+		}
+		if err := s.holdRepo.CreateTx(tx, hold); err != nil {
+			return err
+		}
+		return s.limits.RecordHoldTx(tx, card.UserID, card.ID, amountRUB, hold.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Card %d authorized %s %s for acquirer %d, hold %d", card.ID, hold.Amount, hold.Currency, acquirerID, hold.ID)
+	return hold, nil
+}
+
+func (s *AcquiringService) GetHold(acquirerID, holdID uint) (*models.CardHold, error) {
+	return s.holdRepo.GetByIDAndAcquirer(holdID, acquirerID)
+}
+
+// Списание по авторизации. amount = 0 - вся заблокированная сумма;
+// при частичном списании остаток разблокируется.
+func (s *AcquiringService) Capture(acquirerID, holdID uint, amount money.Amount) (*models.CardHold, error) {
+	if amount < 0 {
+		return nil, errors.New("amount must not be negative")
+	}
+
+	var hold *models.CardHold
+	err := s.accountService.RunInTx(func(tx *sql.Tx) error {
+		var err error
+		hold, err = s.authorizedHoldTx(tx, holdID, acquirerID)
+		if err != nil {
+			return err
+		}
+
+		captured := amount
+		if captured == 0 {
+			captured = hold.Amount
+		}
+		if captured > hold.Amount {
+			return ErrCaptureExceedsHold
+		}
+
+		transaction, err := s.accountService.CaptureHoldTx(tx, hold, captured)
+		if err != nil {
+			return err
+		}
+		if err := s.limits.SettleHoldTx(tx, hold.ID, captured, hold.Amount, transaction.ID); err != nil {
+			return err
+		}
+
+		hold.Status = models.HoldCaptured
+		hold.CapturedAmount = captured
+		hold.TransactionID = transaction.ID
+		return s.holdRepo.FinishTx(tx, hold)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Hold %d captured %s of %s %s", hold.ID, hold.CapturedAmount, hold.Amount, hold.Currency)
+	return hold, nil
+}
+
+// Отмена авторизации эквайрером: сумма разблокируется
+func (s *AcquiringService) Void(acquirerID, holdID uint) (*models.CardHold, error) {
+	var hold *models.CardHold
+	err := s.accountService.RunInTx(func(tx *sql.Tx) error {
+		var err error
+		hold, err = s.authorizedHoldTx(tx, holdID, acquirerID)
+		if err != nil {
+			return err
+		}
+		return s.releaseTx(tx, hold, models.HoldVoided)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Hold %d voided", hold.ID)
+	return hold, nil
+}
+
+// Авторизация со списанием в одном запросе (ISO 8583 0200). Если списать
+// не удалось, авторизация отменяется.
+func (s *AcquiringService) Purchase(acquirerID uint, req AuthorizationRequest) (*models.CardHold, error) {
+	hold, err := s.Authorize(acquirerID, req)
+	if err != nil {
+		return nil, err
+	}
+	captured, err := s.Capture(acquirerID, hold.ID, 0)
+	if err != nil {
+		if _, voidErr := s.Void(acquirerID, hold.ID); voidErr != nil {
+			s.logger.WithError(voidErr).Errorf("Failed to void hold %d after capture failure", hold.ID)
+		}
+		return nil, err
+	}
+	return captured, nil
+}
+
+// Реверсал операции эквайрера по reference: действующая авторизация
+// отменяется, списанная - возвращается на счет. Повторный реверсал
+// ничего не меняет.
+func (s *AcquiringService) Reverse(acquirerID uint, reference string) (*models.CardHold, error) {
+	var hold *models.CardHold
+	err := s.accountService.RunInTx(func(tx *sql.Tx) error {
+		var err error
+		hold, err = s.holdRepo.GetByReferenceForUpdateTx(tx, acquirerID, reference)
+		if err != nil {
+			return err
+		}
+
+		switch hold.Status {
+		case models.HoldAuthorized:
+			return s.releaseTx(tx, hold, models.HoldVoided)
+		case models.HoldCaptured:
+			transaction, err := s.accountService.RefundCardPaymentTx(tx, hold)
+			if err != nil {
+				return err
+			}
+			if err := s.limits.ReleaseHoldTx(tx, hold.ID); err != nil {
+				return err
+			}
+			hold.Status = models.HoldReversed
+			hold.ReversalTransactionID = transaction.ID
+			return s.holdRepo.FinishTx(tx, hold)
+		default:
+			return nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Hold %d reversed by acquirer %d, status %s", hold.ID, acquirerID, hold.Status)
+	return hold, nil
+}
+
+// Разблокировка авторизаций, не списанных за holdTTL
+func (s *AcquiringService) ExpireHolds() error {
+	for {
+		ids, err := s.holdRepo.GetExpiredIDs(time.Now(), holdExpiryBatchSize)
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, id := range ids {
+			err := s.accountService.RunInTx(func(tx *sql.Tx) error {
+				hold, err := s.holdRepo.GetByIDForUpdateTx(tx, id, 0)
+				if err != nil {
+					return err
+				}
+				// Авторизацию могли списать или отменить после выборки
+				if hold.Status != models.HoldAuthorized {
+					return nil
+				}
+				return s.releaseTx(tx, hold, models.HoldExpired)
+			})
+			if err != nil {
+				s.logger.WithError(err).Errorf("Failed to expire hold %d", id)
+				failed++
+			}
+		}
+
+		// Неудачные авторизации попали бы в следующую выборку снова
+		if failed > 0 {
+			return fmt.Errorf("%d holds failed to expire", failed)
+		}
+		if len(ids) < holdExpiryBatchSize {
+			return nil
+		}
+	}
+}
+
+// Действующая авторизация эквайрера с блокировкой строки
+func (s *AcquiringService) authorizedHoldTx(tx *sql.Tx, holdID, acquirerID uint) (*models.CardHold, error) {
+	hold, err := s.holdRepo.GetByIDForUpdateTx(tx, holdID, acquirerID)
+	if err != nil {
+		return nil, err
+	}
+	if hold.Status != models.HoldAuthorized || !time.Now().Before(hold.ExpiresAt) {
+		return nil, ErrHoldNotAuthorized
+	}
+	return hold, nil
+}
+
+func (s *AcquiringService) releaseTx(tx *sql.Tx, hold *models.CardHold, status string) error {
+	if err := s.accountService.ReleaseHoldTx(tx, hold.AccountID, hold.Amount); err != nil {
+		return err
+	}
+	if err := s.limits.ReleaseHoldTx(tx, hold.ID); err != nil {
+		return err
+	}
+	hold.Status = status
+	return s.holdRepo.FinishTx(tx, hold)
+}
+
+// Шестизначный код авторизации
+func generateAuthCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"bank-service/src/models"
+	"bank-service/src/money"
 	"bank-service/src/repositories"
 	"time"
 )
@@ -26,7 +28,7 @@ func NewAnalyticsService(
 	}
 }
 
-func (s *AnalyticsService) GetMonthlyIncomeExpenses(userID uint, year int, month time.Month) (income, expenses float64, err error) {
+func (s *AnalyticsService) GetMonthlyIncomeExpenses(userID uint, year int, month time.Month) (income, expenses money.Amount, err error) {
     start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
     end := start.AddDate(0, 1, 0)
 
@@ -44,17 +46,17 @@ func (s *AnalyticsService) GetMonthlyIncomeExpenses(userID uint, year int, month
 }
 
 
-// Аналитика кредитной нагрузки
-func (s *AnalyticsService) GetCreditLoad(userID uint) (float64, error) {
+// Аналитика кредитной нагрузки: остаток основного долга по непогашенным кредитам
+func (s *AnalyticsService) GetCreditLoad(userID uint) (money.Amount, error) {
 	credits, err := s.creditRepo.GetByUserID(userID)
 	if err != nil {
 		return 0, err
 	}
 
-	var total float64
+	var total money.Amount
 	for _, c := range credits {
-		if c.Status == "active" {
-			total += c.Amount
+		if c.Status != models.CreditStatusClosed {
+			total += c.OutstandingPrincipal
 		}
 	}
 	return total, nil
@@ -62,7 +64,7 @@ func (s *AnalyticsService) GetCreditLoad(userID uint) (float64, error) {
 
 
 // Прогноз баланса на N дней (учет запланированных платежей)
-func (s *AnalyticsService) PredictBalance(accountID uint, days int) (float64, error) {
+func (s *AnalyticsService) PredictBalance(accountID uint, days int) (money.Amount, error) {
     // Получаем текущий баланс аккаунта
     account, err := s.accountRepo.GetByID(accountID)
     if err != nil {
@@ -79,7 +81,7 @@ func (s *AnalyticsService) PredictBalance(accountID uint, days int) (float64, er
     }
 
     // Суммируем все запланированные платежи по кредитам за период
-    var totalPayments float64 = 0
+    var totalPayments money.Amount
     for _, credit := range credits {
         // Получаем график платежей по кредиту
         schedules, err := s.paymentScheduleRepo.GetByCreditID(credit.ID)
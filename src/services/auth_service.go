@@ -3,6 +3,8 @@ package services
 import (
 	"bank-service/src/models"
 	"bank-service/src/repositories"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"time"
 	
@@ -11,6 +13,18 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+var (
+	ErrInvalidStepUpToken = errors.New("invalid or expired step-up token")
+	ErrStepUpTokenUsed    = errors.New("step-up token has already been used")
+)
+
+// Токен повторного ввода пароля: короткоживущий, с отдельной областью
+// действия, для одной чувствительной операции (показ номера карты)
+const (
+	StepUpScope = "card_reveal"
+	stepUpTTL   = 5 * time.Minute
+)
+
 type AuthService struct {
 	userRepo  *repositories.UserRepository
 	jwtSecret string
@@ -30,6 +44,14 @@ func NewAuthService(
 }
 
 func (s *AuthService) Register(user *models.User) error {
+	if user.Phone != "" {
+		phone, err := NormalizePhone(user.Phone)
+		if err != nil {
+			return err
+		}
+		user.Phone = phone
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword(
 		[]byte(user.PasswordHash), bcrypt.DefaultCost)
 	if err != nil {
@@ -66,4 +88,61 @@ func (s *AuthService) Login(email, password string) (string, error) {
 	}
 
 	return tokenString, nil
-}
\ No newline at end of file
+}
+
+// Выдача step-up токена после повторной проверки пароля
+func (s *AuthService) StepUp(userID uint, password string) (string, time.Time, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil || user == nil {
+		return "", time.Time{}, errors.New("invalid credentials")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		s.logger.Warnf("Step-up failed for user %d - invalid password", userID)
+		return "", time.Time{}, errors.New("invalid credentials")
+	}
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().Add(stepUpTTL)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   user.ID,
+		"scope": StepUpScope,
+		"jti":   hex.EncodeToString(jti),
+		"exp":   expiresAt.Unix(),
+	})
+	tokenString, err := token.SignedString([]byte(s.jwtSecret))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return tokenString, expiresAt, nil
+}
+
+// Проверка step-up токена пользователя userID. Одноразовость
+// обеспечивает вызывающий по возвращаемому jti.
+func (s *AuthService) VerifyStepUp(userID uint, tokenString string) (jti string, expiresAt time.Time, err error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return []byte(s.jwtSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid {
+		return "", time.Time{}, ErrInvalidStepUpToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["scope"] != StepUpScope {
+		return "", time.Time{}, ErrInvalidStepUpToken
+	}
+	sub, _ := claims["sub"].(float64)
+	jti, _ = claims["jti"].(string)
+	if uint(sub) != userID || jti == "" {
+		return "", time.Time{}, ErrInvalidStepUpToken
+	}
+
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return "", time.Time{}, ErrInvalidStepUpToken
+	}
+	return jti, exp.Time, nil
+}
@@ -6,49 +6,308 @@ import (
 	"bank-service/src/crypto"
 	hmacpkg "crypto/hmac"
     "crypto/sha256"
+    "database/sql"
     "encoding/hex"
+    "errors"
     "fmt"
     "math/rand"
+    "slices"
+    "strings"
     "time"
 	
     "golang.org/x/crypto/bcrypt"
-    "github.com/ProtonMail/go-crypto/openpgp"
     "github.com/sirupsen/logrus"
 )
 
+var (
+	ErrCardNotActive        = errors.New("card is not active")
+	ErrCardStatusTransition = errors.New("operation is not allowed in the current card status")
+	ErrCardReasonRequired   = errors.New("reason is required")
+	ErrCardDataMismatch     = errors.New("card data does not match")
+	ErrCardExpired          = errors.New("card has expired")
+	ErrCardKeyMissing       = errors.New("card data was encrypted with a key that was never stored, the card needs reissue")
+)
+
+// Срок действия новой карты
+const cardValidityYears = 5
+
+// Неудачных проверок CVV/срока подряд, после которых карта блокируется
+const maxFailedVerifications = 3
+
+// Карта в списке клиента: номер маскирован
+type CardSummary struct {
+	ID           uint      `json:"id"`
+	AccountID    uint      `json:"account_id"`
+	MaskedNumber string    `json:"masked_number,omitempty"`
+	Expiry       string    `json:"expiry,omitempty"`
+	Status       string    `json:"status"`
+	StatusReason string    `json:"status_reason,omitempty"`
+	ReissuedFrom uint      `json:"reissued_from,omitempty"`
+	NeedsReissue bool      `json:"needs_reissue,omitempty"` // данные карты не расшифровать, см. ErrCardKeyMissing
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Полные реквизиты карты; отдаются только через RevealCard
+type CardDetails struct {
+	ID        uint   `json:"id"`
+	AccountID uint   `json:"account_id"`
+	Number    string `json:"number"`
+	Expiry    string `json:"expiry"`
+}
+
+// Карт за один запрос при перешифровании
+const reencryptBatchSize = 100
+
 type CardService struct {
-	cardRepo    *repositories.CardRepository
-	accountRepo *repositories.AccountRepository
-	pgpEntity   *openpgp.Entity
-	logger      *logrus.Logger
+	cardRepo       *repositories.CardRepository
+	accountRepo    *repositories.AccountRepository
+	keys           *KeyService
+	indexKey       []byte
+	logger         *logrus.Logger
+	accountService *AccountService
 }
 
 func NewCardService(
 	cardRepo *repositories.CardRepository,
 	accountRepo *repositories.AccountRepository,
-	pgpEntity *openpgp.Entity,
+	keys *KeyService,
+	indexKey []byte,
 	logger *logrus.Logger,
+	accountService *AccountService,
 ) *CardService {
 	return &CardService{
-		cardRepo:    cardRepo,
-		accountRepo: accountRepo,
-		pgpEntity:   pgpEntity,
-		logger:      logger,
+		cardRepo:       cardRepo,
+		accountRepo:    accountRepo,
+		keys:           keys,
+		indexKey:       indexKey,
+		logger:         logger,
+		accountService: accountService,
 	}
 }
 
 func (s *CardService) GenerateCard(userID, accountID uint, cvv string) (*models.Card, error) {
 	// Проверка прав доступа
-	if _, err := s.accountRepo.GetByIDAndUser(accountID, userID); err != nil {
+	account, err := s.accountRepo.GetByIDAndUser(accountID, userID)
+	if err != nil {
 		return nil, fmt.Errorf("account access denied")
 	}
 
-	// Генерация данных карты
+	card, err := s.newCard(userID, accountID, cvv)
+	if err != nil {
+		return nil, err
+	}
+
+	// Карта выпускается только вместе со списанием комиссии за выпуск
+	err = s.accountService.RunInTx(func(tx *sql.Tx) error {
+		if err := s.cardRepo.CreateTx(tx, card); err != nil {
+			return err
+		}
+		_, err := s.accountService.ChargeFeeTx(tx, userID, accountID, models.FeeCardIssuance, 0, account.Currency, 0)
+		return err
+	})
+	if errors.Is(err, ErrInsufficientFunds) {
+		return nil, err
+	}
+	if err != nil {
+		s.logger.Errorf("failed to save card: %v", err)
+		return nil, fmt.Errorf("failed to save card: %w", err)
+	}
+	
+	return card, nil
+}
+
+func (s *CardService) GetUserCards(userID uint) ([]CardSummary, error) {
+	cards, err := s.cardRepo.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]CardSummary, 0, len(cards))
+	for _, card := range cards {
+		summaries = append(summaries, s.Summarize(&card))
+	}
+	return summaries, nil
+}
+
+// Незакрытые карты, выпущенные до хранилища ключей: их данные не
+// расшифровать и не найти по номеру, карты нужно перевыпустить
+func (s *CardService) CountCardsWithoutKey() (int, error) {
+	return s.cardRepo.CountWithoutKey()
+}
+
+// Маскированное представление карты. Если данные карты не расшифровываются,
+// номер и срок не показываются.
+func (s *CardService) Summarize(card *models.Card) CardSummary {
+	summary := CardSummary{
+		ID:        card.ID,
+		AccountID:    card.AccountID,
+		Status:       card.Status,
+		StatusReason: card.StatusReason,
+		ReissuedFrom: card.ReissuedFrom,
+		CreatedAt:    card.CreatedAt,
+	}
+	number, expiry, err := s.decryptCardData(card)
+	if errors.Is(err, ErrCardKeyMissing) {
+		// Такие карты известны и учитываются в CountCardsWithoutKey
+		summary.NeedsReissue = true
+		return summary
+	}
+	if err != nil {
+		s.logger.WithError(err).Warnf("Failed to decrypt card %d", card.ID)
+		return summary
+	}
+	summary.MaskedNumber = maskPAN(number)
+	summary.Expiry = expiry
+	return summary
+}
+
+// Полный номер карты по одноразовому step-up токену jti
+func (s *CardService) RevealCard(userID, cardID uint, jti string, tokenExpiresAt time.Time) (*CardDetails, error) {
+	card, err := s.cardRepo.GetByIDAndUser(cardID, userID)
+	if err != nil {
+		return nil, err
+	}
+	number, expiry, err := s.decryptCardData(card)
+	if err != nil {
+		return nil, err
+	}
+
+	fresh, err := s.cardRepo.RecordReveal(jti, card.ID, userID, tokenExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, ErrStepUpTokenUsed
+	}
+
+	s.logger.Infof("Card %d number revealed to user %d", card.ID, userID)
+	return &CardDetails{
+		ID:        card.ID,
+		AccountID: card.AccountID,
+		Number:    number,
+		Expiry:    expiry,
+	}, nil
+}
+
+func (s *CardService) GetCard(userID, cardID uint) (*models.Card, error) {
+	return s.cardRepo.GetByIDAndUser(cardID, userID)
+}
+
+// Проверка реквизитов карты при авторизации: номер, срок действия и CVV.
+// Какой именно реквизит не совпал, не сообщается.
+func (s *CardService) VerifyCard(number, expiry, cvv string) (*models.Card, error) {
+	card, err := s.FindByNumber(number)
+	if errors.Is(err, repositories.ErrCardNotFound) {
+		return nil, ErrCardDataMismatch
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Статус проверяется до реквизитов: по недействующей карте ответ не
+	// зависит от CVV, поэтому подбирать его после блокировки бесполезно
+	switch {
+	case card.Status == models.CardExpired || card.ExpiresAt.Before(day(time.Now())):
+		return nil, ErrCardExpired
+	case card.Status != models.CardActive:
+		return nil, ErrCardNotActive
+	}
+
+	_, cardExpiry, err := s.decryptCardData(card)
+	if err != nil {
+		return nil, err
+	}
+	if expiry != cardExpiry ||
+		bcrypt.CompareHashAndPassword([]byte(card.CvvHash), []byte(cvv)) != nil {
+		status, err := s.cardRepo.RecordFailedVerification(card.ID, maxFailedVerifications, "too many failed verification attempts")
+		if err != nil {
+			return nil, err
+		}
+		if status != models.CardActive {
+			s.logger.Warnf("Card %d blocked after %d failed verifications", card.ID, maxFailedVerifications)
+			return nil, ErrCardNotActive
+		}
+		s.logger.Warnf("Card %d verification failed", card.ID)
+		return nil, ErrCardDataMismatch
+	}
+
+	// Карту могли заблокировать параллельные неудачные попытки
+	if card.FailedVerifications > 0 {
+		active, err := s.cardRepo.ResetFailedVerifications(card.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			return nil, ErrCardNotActive
+		}
+	}
+	return card, nil
+}
+
+// Поиск карты по полному номеру через HMAC номера
+func (s *CardService) FindByNumber(number string) (*models.Card, error) {
+	return s.cardRepo.GetByHmac(s.cardIndex(number))
+}
+
+// Перешифрование карт активным ключом. Заодно пересчитывается слепой
+// индекс: карты, выпущенные до появления отдельного ключа индекса,
+// снова находятся по номеру. Возвращает число перешифрованных карт.
+func (s *CardService) ReencryptCards() (int, error) {
+	keyID, _ := s.keys.Current()
+
+	migrated := 0
+	var afterID uint
+	for {
+		cards, err := s.cardRepo.GetByOtherKey(keyID, afterID, reencryptBatchSize)
+		if err != nil {
+			return migrated, err
+		}
+
+		for i := range cards {
+			card := &cards[i]
+			afterID = card.ID
+			if err := s.reencrypt(card); err != nil {
+				// Карта останется на старом ключе до следующего запуска
+				s.logger.WithError(err).Errorf("Failed to re-encrypt card %d", card.ID)
+				continue
+			}
+			migrated++
+		}
+
+		if len(cards) < reencryptBatchSize {
+			return migrated, nil
+		}
+	}
+}
+
+func (s *CardService) reencrypt(card *models.Card) error {
+	oldKeyID := card.KeyID
+	number, expiry, err := s.decryptCardData(card)
+	if err != nil {
+		return err
+	}
+	keyID, encrypted, err := s.encryptCardData(fmt.Sprintf("%s|%s", number, expiry))
+	if err != nil {
+		return err
+	}
+
+	card.EncryptedData = encrypted
+	card.KeyID = keyID
+	card.Hmac = s.cardIndex(number)
+	_, err = s.cardRepo.UpdateEncryption(card, oldKeyID)
+	return err
+}
+// Новая карта со сгенерированным номером: данные зашифрованы, CVV захеширован
+func (s *CardService) newCard(userID, accountID uint, cvv string) (*models.Card, error) {
+	// Генерация данных карты; карта действует до конца месяца, указанного на ней
 	cardNumber := generateLuhnValidNumber()
-	expiry := time.Now().AddDate(5, 0, 0).Format("01/06")
+	validThrough := time.Now().AddDate(cardValidityYears, 0, 0)
+	expiry := validThrough.Format("01/06")
+	y, m, _ := validThrough.Date()
+	expiresAt := time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC)
 	
 	// Шифрование данных
-	encryptedData, hmac, err := s.encryptCardData(fmt.Sprintf("%s|%s", cardNumber, expiry))
+	keyID, encryptedData, err := s.encryptCardData(fmt.Sprintf("%s|%s", cardNumber, expiry))
 	if err != nil {
 		return nil, err
 	}
@@ -59,24 +318,117 @@ func (s *CardService) GenerateCard(userID, accountID uint, cvv string) (*models.
 		return nil, fmt.Errorf("failed to hash CVV")
 	}
 
-	card := &models.Card{
+	return &models.Card{
 		UserID:        userID,
 		AccountID:     accountID,
 		EncryptedData: encryptedData,
-		Hmac:          hmac,
+		Hmac:          s.cardIndex(cardNumber),
+		KeyID:         keyID,
 		CvvHash:       string(cvvHash),
+		Status:        models.CardActive,
+		ExpiresAt:     expiresAt,
+	}, nil
+}
+
+// Временная блокировка карты клиентом; permanent - окончательная
+// блокировка при утере или краже, снять ее нельзя
+func (s *CardService) BlockCard(userID, cardID uint, reason string, permanent bool) (*models.Card, error) {
+	if permanent {
+		return s.changeStatus(userID, cardID, models.CardLost, reason, models.CardActive, models.CardBlocked)
 	}
+	return s.changeStatus(userID, cardID, models.CardBlocked, reason, models.CardActive)
+}
 
-	if err := s.cardRepo.Create(card); err != nil {
-		s.logger.Errorf("failed to save card: %v", err)
-		return nil, fmt.Errorf("failed to save card: %w", err)
+func (s *CardService) UnblockCard(userID, cardID uint, reason string) (*models.Card, error) {
+	if reason == "" {
+		reason = "unblocked by cardholder"
 	}
-	
+	return s.changeStatus(userID, cardID, models.CardActive, reason, models.CardBlocked)
+}
+
+func (s *CardService) CloseCard(userID, cardID uint, reason string) (*models.Card, error) {
+	return s.changeStatus(userID, cardID, models.CardClosed, reason,
+		models.CardActive, models.CardBlocked, models.CardLost, models.CardExpired)
+}
+
+// Перевыпуск: новая карта с новым номером к тому же счету, старая закрывается.
+// Комиссия за выпуск не списывается.
+func (s *CardService) ReissueCard(userID, cardID uint, cvv, reason string) (*models.Card, error) {
+	if reason == "" {
+		return nil, ErrCardReasonRequired
+	}
+	old, err := s.cardRepo.GetByIDAndUser(cardID, userID)
+	if err != nil {
+		return nil, err
+	}
+	card, err := s.newCard(userID, old.AccountID, cvv)
+	if err != nil {
+		return nil, err
+	}
+	card.ReissuedFrom = old.ID
+
+	err = s.accountService.RunInTx(func(tx *sql.Tx) error {
+		locked, err := s.cardRepo.GetByIDAndUserForUpdateTx(tx, cardID, userID)
+		if err != nil {
+			return err
+		}
+		if locked.Status == models.CardClosed {
+			return ErrCardStatusTransition
+		}
+		if err := s.cardRepo.UpdateStatusTx(tx, locked, models.CardClosed, "reissued: "+reason, userID); err != nil {
+			return err
+		}
+		return s.cardRepo.CreateTx(tx, card)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Card %d reissued as card %d for user %d", cardID, card.ID, userID)
+	return card, nil
+}
+
+// Смена статуса карты, допустимая только из статусов from
+func (s *CardService) changeStatus(userID, cardID uint, to, reason string, from ...string) (*models.Card, error) {
+	if reason == "" {
+		return nil, ErrCardReasonRequired
+	}
+
+	var card *models.Card
+	err := s.accountService.RunInTx(func(tx *sql.Tx) error {
+		var err error
+		card, err = s.cardRepo.GetByIDAndUserForUpdateTx(tx, cardID, userID)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(from, card.Status) {
+			return ErrCardStatusTransition
+		}
+		// Разблокировать карту с истекшим сроком нельзя, даже если
+		// задача истечения до нее еще не дошла
+		if to == models.CardActive && card.ExpiresAt.Before(day(time.Now())) {
+			return ErrCardStatusTransition
+		}
+		return s.cardRepo.UpdateStatusTx(tx, card, to, reason, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Card %d status changed to %s for user %d: %s", card.ID, to, userID, reason)
 	return card, nil
 }
 
-func (s *CardService) GetUserCards(userID uint) ([]models.Card, error) {
-	return s.cardRepo.GetByUser(userID)
+// Перевод карт с истекшим сроком в статус expired
+func (s *CardService) ExpireCards() (int64, error) {
+	n, err := s.cardRepo.ExpireDue(day(time.Now()), "expiry date passed")
+	if err != nil {
+		return 0, err
+	}
+	if n > 0 {
+		s.logger.Infof("%d cards expired", n)
+	}
+	return n, nil
 }
 
 func generateLuhnValidNumber() string {
@@ -104,17 +456,56 @@ func generateLuhnValidNumber() string {
 	return number + fmt.Sprintf("%d", checkDigit)
 }
 
-func (s *CardService) encryptCardData(data string) (encrypted string, hmac string, err error) {
-	// Шифрование PGP
-	encrypted, err = crypto.EncryptPGP(data, s.pgpEntity)
+// Шифрование активным ключом; возвращает id ключа для cards.key_id
+func (s *CardService) encryptCardData(data string) (uint, string, error) {
+	keyID, entity := s.keys.Current()
+	encrypted, err := crypto.EncryptPGP(data, entity)
+	if err != nil {
+		return 0, "", fmt.Errorf("PGP encryption failed: %v", err)
+	}
+	return keyID, encrypted, nil
+}
+
+// Номер и срок действия из зашифрованных данных карты ("number|expiry")
+func (s *CardService) decryptCardData(card *models.Card) (number, expiry string, err error) {
+	if card.KeyID == 0 {
+		return "", "", ErrCardKeyMissing
+	}
+	entity, err := s.keys.Get(card.KeyID)
+	if err != nil {
+		return "", "", err
+	}
+	data, err := crypto.DecryptPGP(card.EncryptedData, entity)
 	if err != nil {
-		return "", "", fmt.Errorf("PGP encryption failed: %v", err)
+		return "", "", fmt.Errorf("PGP decryption failed: %v", err)
 	}
+	number, expiry, ok := strings.Cut(data, "|")
+	if !ok {
+		return "", "", errors.New("malformed card data")
+	}
+	return number, expiry, nil
+}
 
-	// Генерация HMAC
-	mac := hmacpkg.New(sha256.New, []byte(s.pgpEntity.PrivateKey.KeyIdString()))
-	mac.Write([]byte(encrypted))
-	hmac = hex.EncodeToString(mac.Sum(nil))
+// Маска номера: видны первая и последние четыре цифры, "4*** **** **** 1234"
+func maskPAN(number string) string {
+	var b strings.Builder
+	for i, r := range number {
+		if i > 0 && i%4 == 0 {
+			b.WriteByte(' ')
+		}
+		if i == 0 || i >= len(number)-4 {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('*')
+		}
+	}
+	return b.String()
+}
 
-	return encrypted, hmac, nil
+// HMAC номера карты (слепой индекс): детерминирован, в отличие от
+// шифротекста PGP, поэтому по нему можно искать карту
+func (s *CardService) cardIndex(number string) string {
+	mac := hmacpkg.New(sha256.New, s.indexKey)
+	mac.Write([]byte(number))
+	return hex.EncodeToString(mac.Sum(nil))
 }
\ No newline at end of file
@@ -1,9 +1,13 @@
 package services
 
 import (
+    "bank-service/src/models"
     "bytes"
     "fmt"
     "net/http"
+    "strconv"
+    "strings"
+    "time"
     "github.com/beevik/etree"
 )
 
@@ -44,4 +48,65 @@ func (s *CBRService) GetKeyRate() (float64, error) {
     var result float64
     _, err = fmt.Sscanf(rate.Text(), "%f", &result)
     return result, err
-}
\ No newline at end of file
+}
+
+// Официальные курсы валют на дату (метод GetCursOnDate сервиса DailyInfo)
+func (s *CBRService) GetCursOnDate(date time.Time) ([]models.ExchangeRate, error) {
+    soapRequest := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+    <soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+      <soap:Body>
+        <GetCursOnDate xmlns="http://web.cbr.ru/">
+          <On_date>%s</On_date>
+        </GetCursOnDate>
+      </soap:Body>
+    </soap:Envelope>`, date.Format("2006-01-02T15:04:05"))
+
+    req, err := http.NewRequest(http.MethodPost, s.soapEndpoint, bytes.NewBufferString(soapRequest))
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+    req.Header.Set("SOAPAction", "http://web.cbr.ru/GetCursOnDate")
+
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+
+    doc := etree.NewDocument()
+    if _, err := doc.ReadFrom(resp.Body); err != nil {
+        return nil, err
+    }
+
+    var rates []models.ExchangeRate
+    for _, el := range doc.FindElements("//ValuteCursOnDate") {
+        code := el.FindElement("VchCode")
+        nom := el.FindElement("Vnom")
+        curs := el.FindElement("Vcurs")
+        if code == nil || nom == nil || curs == nil {
+            continue
+        }
+
+        // Номинал приходит как число с дробной частью: 1, 10, 100
+        nominal, err := strconv.ParseFloat(strings.TrimSpace(nom.Text()), 64)
+        if err != nil || nominal < 1 {
+            return nil, fmt.Errorf("invalid nominal for %s: %q", code.Text(), nom.Text())
+        }
+        value, err := strconv.ParseFloat(strings.TrimSpace(curs.Text()), 64)
+        if err != nil {
+            return nil, fmt.Errorf("invalid rate for %s: %w", code.Text(), err)
+        }
+
+        rates = append(rates, models.ExchangeRate{
+            Currency: strings.TrimSpace(code.Text()),
+            Date:     date,
+            Nominal:  int(nominal),
+            Value:    value,
+        })
+    }
+    if len(rates) == 0 {
+        return nil, fmt.Errorf("exchange rates not found in response")
+    }
+    return rates, nil
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"bank-service/src/models"
+	"bank-service/src/money"
+	"bank-service/src/repositories"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var ErrApplicationNotApproved = errors.New("credit application is not approved")
+
+// Период, за который усредняются доходы и расходы клиента
+const scoringMonths = 3
+
+type CreditApplicationService struct {
+	applicationRepo     *repositories.CreditApplicationRepository
+	transactionRepo     *repositories.TransactionRepository
+	paymentScheduleRepo *repositories.PaymentScheduleRepository
+	creditService       *CreditService
+	analyticsService    *AnalyticsService
+	scoring             ScoringEngine
+	logger              *logrus.Logger
+}
+
+func NewCreditApplicationService(
+	applicationRepo *repositories.CreditApplicationRepository,
+	transactionRepo *repositories.TransactionRepository,
+	paymentScheduleRepo *repositories.PaymentScheduleRepository,
+	creditService *CreditService,
+	analyticsService *AnalyticsService,
+	scoring ScoringEngine,
+	logger *logrus.Logger,
+) *CreditApplicationService {
+	return &CreditApplicationService{
+		applicationRepo:     applicationRepo,
+		transactionRepo:     transactionRepo,
+		paymentScheduleRepo: paymentScheduleRepo,
+		creditService:       creditService,
+		analyticsService:    analyticsService,
+		scoring:             scoring,
+		logger:              logger,
+	}
+}
+
+// Подача заявки и ее скоринг. Ставка фиксируется на момент подачи.
+func (s *CreditApplicationService) Submit(userID, accountID uint, terms CreditTerms) (*models.CreditApplication, error) {
+	terms, err := s.creditService.resolveTerms(terms)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := s.creditService.AccountService().GetByIDAndUser(accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if account.Currency != models.CurrencyRUB {
+		return nil, ErrCreditCurrency
+	}
+
+	// Условия с ПСК выше предельной не одобряются независимо от скоринга
+	quote, err := s.quoteWithinCap(terms)
+	if err != nil {
+		return nil, err
+	}
+
+	app := &models.CreditApplication{
+		UserID:       userID,
+		AccountID:    accountID,
+		Amount:       terms.Amount,
+		Rate:         terms.Rate,
+		Period:       terms.Period,
+		ScheduleType: terms.ScheduleType,
+		IssueFee:     terms.IssueFee,
+		Insurance:    terms.Insurance,
+		PSK:          quote.PSK,
+		Status:       models.ApplicationStatusSubmitted,
+	}
+	if err := s.applicationRepo.Create(app); err != nil {
+		return nil, err
+	}
+
+	if err := s.applicationRepo.UpdateStatus(app.ID, models.ApplicationStatusScoring); err != nil {
+		return nil, err
+	}
+	app.Status = models.ApplicationStatusScoring
+
+	input, err := s.scoringInput(userID, account, quote)
+	if err != nil {
+		// Заявка остается поданной, скоринг можно повторить
+		if err := s.applicationRepo.UpdateStatus(app.ID, models.ApplicationStatusSubmitted); err != nil {
+			s.logger.WithError(err).Errorf("Failed to reset application %d", app.ID)
+		}
+		return nil, err
+	}
+
+	result := s.scoring.Score(input)
+	app.Score = result.Score
+	app.RejectReasons = result.Reasons
+	app.Status = models.ApplicationStatusRejected
+	if result.Approved {
+		app.Status = models.ApplicationStatusApproved
+	}
+	if err := s.applicationRepo.SaveDecision(app); err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Credit application %d %s, score %d, reasons %v", app.ID, app.Status, app.Score, app.RejectReasons)
+	return app, nil
+}
+
+func (s *CreditApplicationService) scoringInput(userID uint, account *models.Account, quote *CreditQuote) (ScoringInput, error) {
+	now := time.Now()
+	start := now.AddDate(0, -scoringMonths, 0)
+
+	// Учитываются только деньги третьих лиц: иначе выданный кредит или
+	// перевод между своими счетами улучшал бы скоринг следующей заявки
+	incomes, err := s.transactionRepo.SumExternalIncome(userID, start, now)
+	if err != nil {
+		return ScoringInput{}, err
+	}
+	income, err := s.sumRUB(incomes, now)
+	if err != nil {
+		return ScoringInput{}, err
+	}
+	outgoings, err := s.transactionRepo.SumExternalExpenses(userID, start, now)
+	if err != nil {
+		return ScoringInput{}, err
+	}
+	expenses, err := s.sumRUB(outgoings, now)
+	if err != nil {
+		return ScoringInput{}, err
+	}
+
+	load, err := s.analyticsService.GetCreditLoad(userID)
+	if err != nil {
+		return ScoringInput{}, err
+	}
+
+	overdue, err := s.paymentScheduleRepo.CountOverdueByUser(userID, now)
+	if err != nil {
+		return ScoringInput{}, err
+	}
+
+	return ScoringInput{
+		Amount:          quote.Amount,
+		MonthlyPayment:  quote.MonthlyPayment,
+		MonthlyIncome:   income / scoringMonths,
+		MonthlyExpenses: expenses / scoringMonths,
+		CreditLoad:      load,
+		OverdueCount:    overdue,
+		AccountAge:      now.Sub(account.CreatedAt),
+	}, nil
+}
+
+// Сумма по валютам в рублях по курсу ЦБ на дату at
+func (s *CreditApplicationService) sumRUB(sums map[string]money.Amount, at time.Time) (money.Amount, error) {
+	var total money.Amount
+	for currency, amount := range sums {
+		rate, err := s.creditService.AccountService().rates.GetRate(currency, models.CurrencyRUB, at)
+		if err != nil {
+			return 0, err
+		}
+		total += convert(amount, rate)
+	}
+	return total, nil
+}
+
+func (s *CreditApplicationService) GetApplication(userID, id uint) (*models.CreditApplication, error) {
+	return s.applicationRepo.GetByIDAndUser(id, userID)
+}
+
+func (s *CreditApplicationService) GetApplications(userID uint) ([]models.CreditApplication, error) {
+	return s.applicationRepo.GetByUserID(userID)
+}
+
+// Подписание одобренной заявки и выдача кредита. Заявка сначала фиксируется
+// как signed; если выдача не удалась, повторное подписание повторяет выдачу.
+func (s *CreditApplicationService) Sign(userID, id uint) (*models.Credit, error) {
+	app, err := s.applicationRepo.GetByIDAndUser(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch app.Status {
+	case models.ApplicationStatusApproved:
+		// ПСК пересчитывается от даты выдачи; предел проверяется до подписания,
+		// чтобы подписанная заявка не застряла на отказе в выдаче
+		if _, err := s.quoteWithinCap(applicationTerms(app)); err != nil {
+			return nil, err
+		}
+		if err := s.applicationRepo.UpdateStatus(app.ID, models.ApplicationStatusSigned); err != nil {
+			return nil, err
+		}
+	case models.ApplicationStatusSigned:
+	default:
+		return nil, ErrApplicationNotApproved
+	}
+
+	return s.issue(app.ID)
+}
+
+func (s *CreditApplicationService) issue(id uint) (*models.Credit, error) {
+	tx, err := s.creditService.AccountService().BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Повторная блокировка исключает двойную выдачу по одной заявке
+	app, err := s.applicationRepo.GetByIDForUpdateTx(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if app.Status != models.ApplicationStatusSigned {
+		return nil, ErrApplicationNotApproved
+	}
+
+	credit, err := s.creditService.CreateCreditTx(tx, app.UserID, app.AccountID, applicationTerms(app))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applicationRepo.ActivateTx(tx, app, credit.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return credit, nil
+}
+
+// Расчет условий с проверкой предельного значения ПСК
+func (s *CreditApplicationService) quoteWithinCap(terms CreditTerms) (*CreditQuote, error) {
+	quote, err := s.creditService.Quote(terms)
+	if err != nil {
+		return nil, err
+	}
+	if s.creditService.maxPSK > 0 && quote.PSK > s.creditService.maxPSK {
+		return nil, ErrPSKExceedsCap
+	}
+	return quote, nil
+}
+
+func applicationTerms(app *models.CreditApplication) CreditTerms {
+	return CreditTerms{
+		Amount:       app.Amount,
+		Rate:         app.Rate,
+		Period:       app.Period,
+		ScheduleType: app.ScheduleType,
+		IssueFee:     app.IssueFee,
+		Insurance:    app.Insurance,
+	}
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"bank-service/src/models"
+	"bank-service/src/money"
+	"bank-service/src/repositories"
+	"database/sql"
+	"errors"
+	"math/big"
+	"time"
+)
+
+// Виды платежей по кредиту
+const (
+	CreditPaymentInstallment = "installment" // очередной платеж по графику
+	CreditPaymentPartial     = "partial"     // частичное досрочное погашение
+	CreditPaymentFull        = "full"        // полное досрочное погашение
+)
+
+// Пересчет графика после частичного досрочного погашения
+const (
+	RescheduleReduceTerm    = "reduce_term"
+	RescheduleReducePayment = "reduce_payment"
+)
+
+var (
+	ErrCreditNotActive      = errors.New("credit is not active")
+	ErrNothingToPay         = errors.New("credit has no unpaid installments")
+	ErrOverdueInstallments  = errors.New("overdue installments must be paid first")
+	ErrInvalidPaymentType   = errors.New("invalid payment type")
+	ErrInvalidPaymentAmount = errors.New("invalid payment amount")
+	ErrInvalidStrategy      = errors.New("strategy must be reduce_term or reduce_payment")
+)
+
+type CreditPayment struct {
+	Type     string       `json:"type"`
+	Amount   money.Amount `json:"amount"`
+	Strategy string       `json:"strategy"`
+}
+
+type CreditPaymentResult struct {
+	Credit      *models.Credit           `json:"credit"`
+	Transaction *models.Transaction      `json:"transaction"`
+	Schedule    []models.PaymentSchedule `json:"schedule"`
+}
+
+// Платеж клиента по кредиту: очередной, частичный досрочный или полное погашение
+func (s *CreditService) MakePayment(userID, creditID uint, payment CreditPayment) (*CreditPaymentResult, error) {
+	tx, err := s.accountService.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	credit, err := s.creditRepo.GetByIDForUpdateTx(tx, creditID)
+	if err != nil {
+		return nil, err
+	}
+	if credit.UserID != userID {
+		return nil, repositories.ErrCreditNotFound
+	}
+	if credit.Status == models.CreditStatusClosed {
+		return nil, ErrCreditNotActive
+	}
+
+	unpaid, err := s.unpaidRowsTx(tx, credit)
+	if err != nil {
+		return nil, err
+	}
+	if len(unpaid) == 0 {
+		return nil, ErrNothingToPay
+	}
+
+	// Неустойка по сегодняшний день входит в очередной платеж
+	now := time.Now()
+	today := day(now)
+	if err := s.accruePenaltiesTx(tx, unpaid, today); err != nil {
+		return nil, err
+	}
+
+	principalBefore := credit.OutstandingPrincipal
+	var transaction *models.Transaction
+	switch payment.Type {
+	case CreditPaymentInstallment:
+		transaction, err = s.repayRowsTx(tx, credit, unpaid[:1], debtOf(&unpaid[0]).total())
+		if err == nil {
+			err = s.refreshStatusTx(tx, credit, unpaid, today)
+		}
+	case CreditPaymentPartial:
+		transaction, err = s.repayPartiallyTx(tx, credit, unpaid, payment.Amount, payment.Strategy, now)
+	case CreditPaymentFull:
+		transaction, err = s.payOffTx(tx, credit, unpaid, now)
+	default:
+		err = ErrInvalidPaymentType
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Комиссия за досрочное погашение - с досрочно погашенного основного долга
+	if payment.Type != CreditPaymentInstallment {
+		repaid := principalBefore - credit.OutstandingPrincipal
+		_, err := s.accountService.ChargeFeeTx(tx, userID, credit.AccountID, models.FeeEarlyRepayment, repaid, models.CurrencyRUB, transaction.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	schedule, err := s.paymentScheduleRepo.GetByCreditID(credit.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &CreditPaymentResult{Credit: credit, Transaction: transaction, Schedule: schedule}, nil
+}
+
+// Частичное досрочное погашение: вся сумма идет в основной долг,
+// оставшиеся платежи пересчитываются выбранным способом
+func (s *CreditService) repayPartiallyTx(tx *sql.Tx, credit *models.Credit, unpaid []models.PaymentSchedule, amount money.Amount, strategy string, now time.Time) (*models.Transaction, error) {
+	if day(unpaid[0].DueDate).Before(day(now)) {
+		return nil, ErrOverdueInstallments
+	}
+	if amount <= 0 || amount >= credit.OutstandingPrincipal {
+		return nil, ErrInvalidPaymentAmount
+	}
+	if strategy != RescheduleReduceTerm && strategy != RescheduleReducePayment {
+		return nil, ErrInvalidStrategy
+	}
+
+	transaction, err := s.accountService.ChargeAccountTx(tx, credit.AccountID, models.TransactionCreditPayment,
+		Charge{SystemAccount: models.SystemAccountCreditDisbursement, Amount: amount},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	credit.OutstandingPrincipal -= amount
+	if err := s.creditRepo.UpdateOutstandingPrincipalTx(tx, credit.ID, credit.OutstandingPrincipal); err != nil {
+		return nil, err
+	}
+
+	r := monthlyRate(credit.Rate)
+	firstDue := unpaid[0].DueDate
+
+	var rows []models.PaymentSchedule
+	switch {
+	case strategy == RescheduleReducePayment:
+		// Срок прежний, платеж уменьшается
+		rows = buildSchedule(credit.ScheduleType, credit.OutstandingPrincipal, r, len(unpaid), firstDue)
+	case credit.ScheduleType == models.ScheduleDifferentiated:
+		// Доля основного долга прежняя, срок сокращается
+		rows = differentiatedRows(credit.OutstandingPrincipal, r, unpaid[0].Principal, 0, firstDue)
+	default:
+		// Платеж прежний, срок сокращается
+		rows = annuityRows(credit.OutstandingPrincipal, r, unpaid[0].Amount, 0, firstDue)
+	}
+
+	if err := s.paymentScheduleRepo.DeleteUnpaidTx(tx, credit.ID); err != nil {
+		return nil, err
+	}
+	return transaction, s.saveSchedule(tx, credit.ID, rows)
+}
+
+// Полное досрочное погашение: остаток долга и проценты за фактические
+// дни пользования с начала текущего периода
+func (s *CreditService) payOffTx(tx *sql.Tx, credit *models.Credit, unpaid []models.PaymentSchedule, now time.Time) (*models.Transaction, error) {
+	if day(unpaid[0].DueDate).Before(day(now)) {
+		return nil, ErrOverdueInstallments
+	}
+
+	periodStart := unpaid[0].DueDate.AddDate(0, -1, 0)
+	if credit.CreatedAt.After(periodStart) {
+		periodStart = credit.CreatedAt
+	}
+	days := int64(now.Sub(periodStart).Hours() / 24)
+	if days < 0 {
+		days = 0
+	}
+	dailyShare := new(big.Rat).Mul(money.Percent(credit.Rate), big.NewRat(days, 365))
+	interest := credit.OutstandingPrincipal.Mul(dailyShare, interestRounding)
+
+	transaction, err := s.accountService.ChargeAccountTx(tx, credit.AccountID, models.TransactionCreditPayment,
+		Charge{SystemAccount: models.SystemAccountCreditDisbursement, Amount: credit.OutstandingPrincipal},
+		Charge{SystemAccount: models.SystemAccountBankRevenue, Amount: interest},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	credit.OutstandingPrincipal = 0
+	return transaction, s.closeCreditTx(tx, credit)
+}
+
+func (s *CreditService) closeCreditTx(tx *sql.Tx, credit *models.Credit) error {
+	if err := s.paymentScheduleRepo.DeleteUnpaidTx(tx, credit.ID); err != nil {
+		return err
+	}
+	if err := s.creditRepo.UpdateOutstandingPrincipalTx(tx, credit.ID, 0); err != nil {
+		return err
+	}
+	credit.Status = models.CreditStatusClosed
+	return s.creditRepo.UpdateStatusTx(tx, credit.ID, models.CreditStatusClosed)
+}
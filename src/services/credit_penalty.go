@@ -0,0 +1,295 @@
+package services
+
+import (
+	"bank-service/src/models"
+	"bank-service/src/money"
+	"database/sql"
+	"time"
+)
+
+// Неустойка за просрочку и пороги смены статуса кредита
+type PenaltyPolicy struct {
+	DailyRate        float64 // % в день от просроченных основного долга и процентов
+	OverdueAfterDays int     // дней просрочки до статуса overdue
+	DefaultAfterDays int     // дней просрочки до статуса defaulted; 0 - не переводить
+}
+
+// Задолженность по строке графика в порядке погашения
+type rowDebt struct {
+	Penalty   money.Amount
+	Interest  money.Amount
+	Principal money.Amount
+}
+
+func (d rowDebt) total() money.Amount {
+	return d.Penalty + d.Interest + d.Principal
+}
+
+func debtOf(row *models.PaymentSchedule) rowDebt {
+	return rowDebt{
+		Penalty:   row.Penalty - row.PenaltyPaid,
+		Interest:  row.Interest - row.InterestPaid,
+		Principal: row.Principal - row.PrincipalPaid,
+	}
+}
+
+// Распределение суммы: сначала неустойка, затем проценты, затем основной долг
+func allocate(amount money.Amount, debt rowDebt) rowDebt {
+	take := func(d money.Amount) money.Amount {
+		if d > amount {
+			d = amount
+		}
+		amount -= d
+		return d
+	}
+	return rowDebt{
+		Penalty:   take(debt.Penalty),
+		Interest:  take(debt.Interest),
+		Principal: take(debt.Principal),
+	}
+}
+
+// Начало суток; даты графика хранятся без времени
+func day(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Неоплаченные строки графика. Строкам, созданным до появления разбивки
+// платежа, проставляются проценты от текущего остатка долга.
+func (s *CreditService) unpaidRowsTx(tx *sql.Tx, credit *models.Credit) ([]models.PaymentSchedule, error) {
+	rows, err := s.paymentScheduleRepo.GetUnpaidByCreditIDTx(tx, credit.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		if row.Principal+row.Interest == row.Amount || row.PrincipalPaid+row.InterestPaid > 0 {
+			continue
+		}
+		interest := credit.OutstandingPrincipal.Mul(monthlyRate(credit.Rate), interestRounding)
+		if interest > row.Amount {
+			interest = row.Amount
+		}
+		principal := row.Amount - interest
+		if principal > credit.OutstandingPrincipal {
+			principal = credit.OutstandingPrincipal
+		}
+		row.Principal, row.Interest = principal, interest
+		if err := s.paymentScheduleRepo.UpdateBreakdownTx(tx, row.ID, principal, interest); err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+// Начисление неустойки по просроченным строкам за каждый день просрочки
+// по today включительно. Повторный запуск в тот же день ничего не начисляет.
+func (s *CreditService) accruePenaltiesTx(tx *sql.Tx, rows []models.PaymentSchedule, today time.Time) error {
+	if s.penalties.DailyRate <= 0 {
+		return nil
+	}
+	rate := money.Percent(s.penalties.DailyRate)
+
+	for i := range rows {
+		row := &rows[i]
+		if !day(row.DueDate).Before(today) {
+			break
+		}
+		debt := debtOf(row)
+		base := debt.Interest + debt.Principal
+		if base <= 0 {
+			continue
+		}
+
+		from := day(row.DueDate).AddDate(0, 0, 1)
+		last, ok, err := s.penaltyRepo.LastAccrualDateTx(tx, row.ID)
+		if err != nil {
+			return err
+		}
+		if ok && !day(last).Before(from) {
+			from = day(last).AddDate(0, 0, 1)
+		}
+
+		for d := from; !d.After(today); d = d.AddDate(0, 0, 1) {
+			accrual := &models.PenaltyAccrual{
+				ScheduleID:  row.ID,
+				AccrualDate: d,
+				BaseAmount:  base,
+				Rate:        s.penalties.DailyRate,
+				Amount:      base.Mul(rate, penaltyRounding),
+			}
+			created, err := s.penaltyRepo.AccrueTx(tx, accrual)
+			if err != nil {
+				return err
+			}
+			if created {
+				row.Penalty += accrual.Amount
+			}
+		}
+	}
+	return nil
+}
+
+// Погашение строк графика по порядку одним списанием со счета.
+// Внутри строки сумма распределяется функцией allocate.
+func (s *CreditService) repayRowsTx(tx *sql.Tx, credit *models.Credit, rows []models.PaymentSchedule, amount money.Amount) (*models.Transaction, error) {
+	var total rowDebt
+	allocations := make([]rowDebt, len(rows))
+	for i := range rows {
+		if amount <= 0 {
+			break
+		}
+		a := allocate(amount, debtOf(&rows[i]))
+		allocations[i] = a
+		amount -= a.total()
+
+		total.Penalty += a.Penalty
+		total.Interest += a.Interest
+		total.Principal += a.Principal
+	}
+
+	transaction, err := s.accountService.ChargeAccountTx(tx, credit.AccountID, models.TransactionCreditPayment,
+		Charge{SystemAccount: models.SystemAccountCreditDisbursement, Amount: total.Principal},
+		Charge{SystemAccount: models.SystemAccountBankRevenue, Amount: total.Interest},
+		Charge{SystemAccount: models.SystemAccountPenalties, Amount: total.Penalty},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, a := range allocations {
+		if a.total() == 0 {
+			continue
+		}
+		row := &rows[i]
+		row.PenaltyPaid += a.Penalty
+		row.InterestPaid += a.Interest
+		row.PrincipalPaid += a.Principal
+		row.Paid = debtOf(row).total() == 0
+		if err := s.paymentScheduleRepo.ApplyPaymentTx(tx, row.ID, a.Principal, a.Interest, a.Penalty, row.Paid); err != nil {
+			return nil, err
+		}
+	}
+
+	credit.OutstandingPrincipal -= total.Principal
+	if credit.OutstandingPrincipal == 0 {
+		return transaction, s.closeCreditTx(tx, credit)
+	}
+	return transaction, s.creditRepo.UpdateOutstandingPrincipalTx(tx, credit.ID, credit.OutstandingPrincipal)
+}
+
+// Статус по самой ранней непогашенной строке: overdue и defaulted
+// после порогов PenaltyPolicy, active после погашения просрочки
+func (s *CreditService) refreshStatusTx(tx *sql.Tx, credit *models.Credit, rows []models.PaymentSchedule, today time.Time) error {
+	if credit.Status == models.CreditStatusClosed {
+		return nil
+	}
+
+	status := models.CreditStatusActive
+	for i := range rows {
+		if rows[i].Paid {
+			continue
+		}
+		days := int(today.Sub(day(rows[i].DueDate)).Hours() / 24)
+		switch {
+		case days <= 0:
+		case s.penalties.DefaultAfterDays > 0 && days >= s.penalties.DefaultAfterDays:
+			status = models.CreditStatusDefaulted
+		case days >= s.penalties.OverdueAfterDays:
+			status = models.CreditStatusOverdue
+		}
+		break
+	}
+
+	if status == credit.Status {
+		return nil
+	}
+	s.logger.Infof("Credit %d status changed from %s to %s", credit.ID, credit.Status, status)
+	credit.Status = status
+	return s.creditRepo.UpdateStatusTx(tx, credit.ID, status)
+}
+
+// Обработка просрочек: начисление неустойки, списание доступных средств
+// в счет просроченной задолженности и смена статуса кредита
+func (s *CreditService) ProcessOverduePayments() error {
+	today := day(time.Now())
+	overdueSchedules, err := s.paymentScheduleRepo.GetOverdueUnpaidSchedules(today)
+	if err != nil {
+		return err
+	}
+
+	processed := make(map[uint]bool)
+	for _, schedule := range overdueSchedules {
+		if processed[schedule.CreditID] {
+			continue
+		}
+		processed[schedule.CreditID] = true
+
+		if err := s.collectOverdue(schedule.CreditID, today); err != nil {
+			s.logger.WithError(err).Warnf("Failed to collect overdue payments for credit %d", schedule.CreditID)
+		}
+	}
+
+	return nil
+}
+
+func (s *CreditService) collectOverdue(creditID uint, today time.Time) error {
+	tx, err := s.accountService.BeginTx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	credit, err := s.creditRepo.GetByIDForUpdateTx(tx, creditID)
+	if err != nil {
+		return err
+	}
+	if credit.Status == models.CreditStatusClosed {
+		return nil
+	}
+
+	rows, err := s.unpaidRowsTx(tx, credit)
+	if err != nil {
+		return err
+	}
+	if err := s.accruePenaltiesTx(tx, rows, today); err != nil {
+		return err
+	}
+
+	overdue := 0
+	var debt money.Amount
+	for overdue < len(rows) && day(rows[overdue].DueDate).Before(today) {
+		debt += debtOf(&rows[overdue]).total()
+		overdue++
+	}
+
+	account, err := s.accountService.GetForUpdateTx(tx, credit.AccountID)
+	if err != nil {
+		return err
+	}
+	if pay := min(account.AvailableBalance, debt); pay > 0 {
+		if _, err := s.repayRowsTx(tx, credit, rows[:overdue], pay); err != nil {
+			return err
+		}
+		debt -= pay
+	}
+
+	if err := s.refreshStatusTx(tx, credit, rows, today); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if debt > 0 {
+		s.logger.Infof("Insufficient funds for credit %d, overdue debt %s", credit.ID, debt)
+		return s.emailService.SendPaymentNotification(
+			s.userRepo,
+			credit.UserID,
+			money.New(debt, "RUB").String(),
+		)
+	}
+	return nil
+}
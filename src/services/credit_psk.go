@@ -0,0 +1,109 @@
+package services
+
+import (
+	"bank-service/src/models"
+	"bank-service/src/money"
+	"math"
+	"time"
+)
+
+// Расчет полной стоимости кредита (ПСК) по формуле ч. 2 ст. 6 Федерального
+// закона N 353-ФЗ «О потребительском кредите (займе)»:
+//
+//	ПСК = i * ЧБП * 100,
+//
+// где i - процентная ставка базового периода, при которой
+//
+//	SUM( ДПk / ((1 + ek*i) * (1 + i)^qk) ) = 0,
+//
+// ДПk - k-й денежный поток, qk - число полных базовых периодов с момента
+// выдачи до k-го потока, ek - доля неполного базового периода. Платежи
+// ежемесячные, поэтому базовый период - календарный месяц, ЧБП = 12,
+// а неполный период считается в днях из расчета 30 дней в месяце.
+
+const (
+	pskBasePeriodDays = 30
+	pskPeriodsPerYear = 12
+
+	// Наибольшая ПСК, которую можно раскрыть и сохранить (credits.psk DECIMAL(10,3))
+	pskMaxValue = 9999999.999
+)
+
+// Денежный поток заемщика: отрицательный - получение средств, положительный - платеж
+type cashFlow struct {
+	date   time.Time
+	amount money.Amount
+}
+
+// Потоки по кредиту: выдача за вычетом комиссии и страховки, затем платежи по графику
+func creditCashFlows(terms CreditTerms, issuedAt time.Time, rows []models.PaymentSchedule) []cashFlow {
+	flows := make([]cashFlow, 0, len(rows)+1)
+	flows = append(flows, cashFlow{
+		date:   issuedAt,
+		amount: -(terms.Amount - terms.IssueFee - terms.Insurance),
+	})
+	for _, row := range rows {
+		flows = append(flows, cashFlow{date: row.DueDate, amount: row.Amount})
+	}
+	return flows
+}
+
+// ПСК в процентах годовых с точностью до третьего знака. Если она больше
+// pskMaxValue, возвращается ErrPSKExceedsCap.
+func fullCreditCost(issuedAt time.Time, flows []cashFlow) (float64, error) {
+	type term struct {
+		amount float64
+		q      float64
+		e      float64
+	}
+	terms := make([]term, len(flows))
+	for k, f := range flows {
+		q, e := basePeriods(issuedAt, f.date)
+		terms[k] = term{amount: f.amount.Float64(), q: float64(q), e: e}
+	}
+
+	npv := func(i float64) float64 {
+		var sum float64
+		for _, t := range terms {
+			sum += t.amount / ((1 + t.e*i) * math.Pow(1+i, t.q))
+		}
+		return sum
+	}
+
+	// Сумма дисконтированных потоков убывает по i: ищем корень делением пополам
+	maxRate := pskMaxValue / pskPeriodsPerYear / 100
+	lo, hi := 0.0, 1.0
+	if npv(lo) <= 0 {
+		return 0, nil
+	}
+	for npv(hi) > 0 {
+		if hi >= maxRate {
+			return 0, ErrPSKExceedsCap
+		}
+		hi = math.Min(hi*2, maxRate)
+	}
+	for n := 0; n < 200; n++ {
+		mid := (lo + hi) / 2
+		if npv(mid) > 0 {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	return math.Round(lo*pskPeriodsPerYear*100*1000) / 1000, nil
+}
+
+// Число полных месяцев от выдачи до даты потока и доля неполного месяца
+func basePeriods(issuedAt, date time.Time) (int, float64) {
+	if !date.After(issuedAt) {
+		return 0, 0
+	}
+
+	months := 0
+	for !issuedAt.AddDate(0, months+1, 0).After(date) {
+		months++
+	}
+	days := math.Floor(date.Sub(issuedAt.AddDate(0, months, 0)).Hours() / 24)
+	return months, days / pskBasePeriodDays
+}
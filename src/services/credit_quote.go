@@ -0,0 +1,70 @@
+package services
+
+import (
+	"bank-service/src/models"
+	"bank-service/src/money"
+	"math"
+	"math/big"
+	"time"
+)
+
+// Предварительный расчет кредита без его оформления
+type CreditQuote struct {
+	Amount         money.Amount             `json:"amount"`
+	Rate           float64                  `json:"rate"`
+	Period         int                      `json:"period"`
+	ScheduleType   string                   `json:"schedule_type"`
+	MonthlyPayment money.Amount             `json:"monthly_payment"` // для дифференцированного графика - первый платеж
+	TotalPayment   money.Amount             `json:"total_payment"`
+	Overpayment    money.Amount             `json:"overpayment"`
+	EffectiveRate  float64                  `json:"effective_rate"` // эффективная годовая ставка, %
+	PSK            float64                  `json:"psk"`            // полная стоимость кредита, %
+	Schedule       []models.PaymentSchedule `json:"schedule"`
+}
+
+// Расчет графика теми же функциями, что и при оформлении кредита,
+// но без записи в CreditRepository и PaymentScheduleRepository
+func (s *CreditService) Quote(terms CreditTerms) (*CreditQuote, error) {
+	terms, err := s.resolveTerms(terms)
+	if err != nil {
+		return nil, err
+	}
+
+	issuedAt := time.Now()
+	rows := s.generatePaymentSchedule(terms, issuedAt)
+	psk, err := fullCreditCost(issuedAt, creditCashFlows(terms, issuedAt, rows))
+	if err != nil {
+		return nil, err
+	}
+
+	// Переплата включает комиссию и страховку, удержанные при выдаче
+	total := terms.IssueFee + terms.Insurance
+	for _, row := range rows {
+		total += row.Amount
+	}
+
+	return &CreditQuote{
+		Amount:         terms.Amount,
+		Rate:           terms.Rate,
+		Period:         terms.Period,
+		ScheduleType:   terms.ScheduleType,
+		MonthlyPayment: rows[0].Amount,
+		TotalPayment:   total,
+		Overpayment:    total - terms.Amount,
+		EffectiveRate:  effectiveAnnualRate(monthlyRate(terms.Rate)),
+		PSK:            psk,
+		Schedule:       rows,
+	}, nil
+}
+
+// Эффективная ставка с учетом ежемесячной капитализации: ((1 + r)^12 - 1) * 100
+func effectiveAnnualRate(r *big.Rat) float64 {
+	one := big.NewRat(1, 1)
+	base := new(big.Rat).Add(one, r)
+	pow := new(big.Rat).Set(one)
+	for i := 0; i < 12; i++ {
+		pow.Mul(pow, base)
+	}
+	f, _ := pow.Sub(pow, one).Float64()
+	return math.Round(f*100*100) / 100
+}
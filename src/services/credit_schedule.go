@@ -0,0 +1,96 @@
+package services
+
+import (
+	"bank-service/src/models"
+	"bank-service/src/money"
+	"math/big"
+	"time"
+)
+
+// Округление: проценты и платежи - математическое, штрафы - в пользу клиента
+const (
+	interestRounding = money.RoundHalfUp
+	penaltyRounding  = money.RoundDown
+)
+
+// Ограничение срока пересчитанного графика (50 лет)
+const maxScheduleRows = 600
+
+// График на n месяцев для долга balance
+func buildSchedule(scheduleType string, balance money.Amount, r *big.Rat, n int, firstDue time.Time) []models.PaymentSchedule {
+	if scheduleType == models.ScheduleDifferentiated {
+		return differentiatedRows(balance, r, balance.Mul(big.NewRat(1, int64(n)), interestRounding), n, firstDue)
+	}
+	return annuityRows(balance, r, annuityPayment(balance, r, n), n, firstDue)
+}
+
+// Аннуитетные строки с фиксированным платежом payment.
+// Последний платеж гасит остаток долга целиком, чтобы округление
+// ежемесячных платежей не оставляло копеечных хвостов. При maxRows == 0
+// срок определяется тем, за сколько платежей погашается долг.
+func annuityRows(balance money.Amount, r *big.Rat, payment money.Amount, maxRows int, firstDue time.Time) []models.PaymentSchedule {
+	var rows []models.PaymentSchedule
+	for i := 1; balance > 0; i++ {
+		interest := balance.Mul(r, interestRounding)
+		principal := payment - interest
+		if i == maxRows || i == maxScheduleRows || principal >= balance {
+			principal = balance
+		}
+		balance -= principal
+
+		rows = append(rows, scheduleRow(firstDue.AddDate(0, i-1, 0), principal, interest, balance))
+	}
+	return rows
+}
+
+// Дифференцированные строки: каждый месяц гасится одна и та же доля
+// основного долга, проценты начисляются на его остаток
+func differentiatedRows(balance money.Amount, r *big.Rat, principalPart money.Amount, maxRows int, firstDue time.Time) []models.PaymentSchedule {
+	var rows []models.PaymentSchedule
+	for i := 1; balance > 0; i++ {
+		interest := balance.Mul(r, interestRounding)
+		principal := principalPart
+		if i == maxRows || i == maxScheduleRows || principal >= balance {
+			principal = balance
+		}
+		balance -= principal
+
+		rows = append(rows, scheduleRow(firstDue.AddDate(0, i-1, 0), principal, interest, balance))
+	}
+	return rows
+}
+
+func scheduleRow(dueDate time.Time, principal, interest, remaining money.Amount) models.PaymentSchedule {
+	return models.PaymentSchedule{
+		DueDate:          dueDate,
+		Amount:           principal + interest,
+		Principal:        principal,
+		Interest:         interest,
+		RemainingBalance: remaining,
+		Paid:             false,
+	}
+}
+
+// Ежемесячная ставка в долях (rate - годовая в процентах)
+func monthlyRate(annualRate float64) *big.Rat {
+	return new(big.Rat).Quo(money.Percent(annualRate), big.NewRat(12, 1))
+}
+
+// Аннуитетный платеж A = P * (r * (1+r)^n) / ((1+r)^n - 1),
+// вычисляется в рациональных числах и округляется один раз
+func annuityPayment(principal money.Amount, r *big.Rat, n int) money.Amount {
+	one := big.NewRat(1, 1)
+	if r.Sign() == 0 {
+		return principal.Mul(big.NewRat(1, int64(n)), interestRounding)
+	}
+
+	pow := new(big.Rat).Set(one)
+	base := new(big.Rat).Add(one, r)
+	for i := 0; i < n; i++ {
+		pow.Mul(pow, base)
+	}
+
+	k := new(big.Rat).Mul(r, pow)
+	k.Quo(k, new(big.Rat).Sub(pow, one))
+	return principal.Mul(k, interestRounding)
+}
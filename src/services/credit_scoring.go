@@ -0,0 +1,100 @@
+package services
+
+import (
+	"bank-service/src/money"
+	"math/big"
+	"time"
+)
+
+// Коды причин отказа
+const (
+	ReasonNoIncome         = "no_income"
+	ReasonPaymentToIncome  = "payment_to_income_too_high"
+	ReasonDebtBurden       = "debt_burden_too_high"
+	ReasonNegativeCashFlow = "negative_cash_flow"
+	ReasonOverdueHistory   = "overdue_history"
+	ReasonAccountTooYoung  = "account_too_young"
+)
+
+// Данные для скоринга, собранные по уже имеющейся истории клиента
+type ScoringInput struct {
+	Amount         money.Amount // сумма кредита
+	MonthlyPayment money.Amount // ежемесячный платеж по новому кредиту
+
+	// Средние доходы и расходы за месяц по операциям счетов
+	MonthlyIncome   money.Amount
+	MonthlyExpenses money.Amount
+
+	CreditLoad   money.Amount // остаток долга по действующим кредитам
+	OverdueCount int          // платежи, оплаченные позже срока или просроченные
+	AccountAge   time.Duration
+}
+
+type ScoringResult struct {
+	Approved bool
+	Score    int // 0..100
+	Reasons  []string
+}
+
+// Движок скоринга; реализация подключается в NewCreditApplicationService
+type ScoringEngine interface {
+	Score(input ScoringInput) ScoringResult
+}
+
+// Скоринг по набору правил: каждое нарушенное правило снижает балл
+// и добавляет код причины; одобрение - только без нарушений
+type RuleBasedScoring struct {
+	MaxPaymentToIncome *big.Rat      // доля платежа в доходе
+	MaxDebtToIncome    *big.Rat      // весь долг к доходу за месяц
+	MaxOverdue         int           // допустимое число просрочек
+	MinAccountAge      time.Duration // минимальный срок жизни счета
+}
+
+func NewRuleBasedScoring() *RuleBasedScoring {
+	return &RuleBasedScoring{
+		MaxPaymentToIncome: big.NewRat(50, 100),
+		MaxDebtToIncome:    big.NewRat(12, 1),
+		MaxOverdue:         0,
+		MinAccountAge:      30 * 24 * time.Hour,
+	}
+}
+
+func (e *RuleBasedScoring) Score(in ScoringInput) ScoringResult {
+	score := 100
+	var reasons []string
+	fail := func(reason string, weight int) {
+		reasons = append(reasons, reason)
+		score -= weight
+	}
+
+	if in.MonthlyIncome <= 0 {
+		fail(ReasonNoIncome, 40)
+	} else {
+		if exceeds(in.MonthlyPayment, in.MonthlyIncome, e.MaxPaymentToIncome) {
+			fail(ReasonPaymentToIncome, 30)
+		}
+		if exceeds(in.CreditLoad+in.Amount, in.MonthlyIncome, e.MaxDebtToIncome) {
+			fail(ReasonDebtBurden, 20)
+		}
+		if in.MonthlyExpenses+in.MonthlyPayment > in.MonthlyIncome {
+			fail(ReasonNegativeCashFlow, 10)
+		}
+	}
+	if in.OverdueCount > e.MaxOverdue {
+		fail(ReasonOverdueHistory, 20)
+	}
+	if in.AccountAge < e.MinAccountAge {
+		fail(ReasonAccountTooYoung, 10)
+	}
+
+	if score < 0 {
+		score = 0
+	}
+	return ScoringResult{Approved: len(reasons) == 0, Score: score, Reasons: reasons}
+}
+
+// a / b > limit
+func exceeds(a, b money.Amount, limit *big.Rat) bool {
+	ratio := new(big.Rat).SetFrac64(int64(a), int64(b))
+	return ratio.Cmp(limit) > 0
+}
@@ -2,15 +2,32 @@ package services
 
 import (
 	"bank-service/src/models"
+	"bank-service/src/money"
 	"bank-service/src/repositories"
+	"database/sql"
 	"errors"
-	"math"
-	"time"
 	"fmt"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+var (
+	ErrInvalidScheduleType = errors.New("schedule type must be annuity or differentiated")
+	ErrPSKExceedsCap       = errors.New("full cost of credit exceeds the allowed maximum")
+	ErrCreditCurrency      = errors.New("credits are issued to RUB accounts only")
+	ErrInvalidCreditTerms  = errors.New("invalid credit parameters")
+)
+
+// Границы условий продукта: от них зависит размер графика и стоимость
+// расчета ПСК, поэтому они проверяются до любых вычислений
+const (
+	maxCreditPeriod = 360 // месяцев
+	maxCreditRate   = 100 // % годовых
+)
+
+var maxCreditAmount = money.FromMinor(100_000_000 * 100)
+
 type CreditService struct {
 	creditRepo          *repositories.CreditRepository
 	paymentScheduleRepo *repositories.PaymentScheduleRepository
@@ -19,6 +36,9 @@ type CreditService struct {
     cbrService          *CBRService
 	userRepo            *repositories.UserRepository
 	emailService        *EmailService       
+	maxPSK              float64 // предельное значение ПСК, %; 0 - без ограничения
+	penaltyRepo         *repositories.PenaltyRepository
+	penalties           PenaltyPolicy
 }
 
 func NewCreditService(
@@ -29,6 +49,9 @@ func NewCreditService(
 	logger *logrus.Logger,
 	userRepo            *repositories.UserRepository,
 	emailService        *EmailService,
+	maxPSK float64,
+	penaltyRepo *repositories.PenaltyRepository,
+	penalties PenaltyPolicy,
 ) *CreditService {
 	return &CreditService{
 		creditRepo:          creditRepo,
@@ -38,140 +61,166 @@ func NewCreditService(
 		logger:              logger,
 		userRepo: userRepo,
 		emailService: emailService,
+		maxPSK: maxPSK,
+		penaltyRepo: penaltyRepo,
+		penalties: penalties,
 	}
 }
 
-// Оформление кредита с расчетом аннуитетных платежей
-func (s *CreditService) CreateCredit(userID, accountID uint, amount float64, rate float64, period int) (*models.Credit, error) {
-    if rate <= 0 {
-        keyRate, err := s.cbrService.GetKeyRate()
-        if err != nil {
-            s.logger.Warnf("Using default rate 10%%, failed to get CBR rate: %v", err)
-            rate = 10.0 // дефолтная ставка при ошибке
-        } else {
-            rate = keyRate
-        }
-    }
+// Условия кредита
+type CreditTerms struct {
+	Amount       money.Amount `json:"amount"`
+	Rate         float64      `json:"rate"`
+	Period       int          `json:"period"` // в месяцах
+	ScheduleType string       `json:"schedule_type"`
+	IssueFee     money.Amount `json:"issue_fee"` // комиссия за выдачу
+	Insurance    money.Amount `json:"insurance"` // страховая премия, удерживается при выдаче
+}
 
-	if amount <= 0 || rate <= 0 || period <= 0 {
-		return nil, errors.New("invalid credit parameters")
+// Оформление кредита с расчетом графика платежей. Выдача средств, сам кредит
+// и график создаются в транзакции вызывающего; условия уже проверены
+// resolveTerms, предел ПСК и принадлежность счета - вызывающим.
+func (s *CreditService) CreateCreditTx(tx *sql.Tx, userID, accountID uint, terms CreditTerms) (*models.Credit, error) {
+	issuedAt := time.Now()
+	rows := s.generatePaymentSchedule(terms, issuedAt)
+
+	psk, err := fullCreditCost(issuedAt, creditCashFlows(terms, issuedAt, rows))
+	if err != nil {
+		return nil, err
 	}
 
 	credit := &models.Credit{
 		UserID:    userID,
 		AccountID: accountID,
-		Amount:    amount,
-		Rate:      rate,
-		Period:    period,
-		Status:    "active",
+		Amount:    terms.Amount,
+		Rate:      terms.Rate,
+		Period:    terms.Period,
+		Status:    models.CreditStatusActive,
+
+		ScheduleType:         terms.ScheduleType,
+		OutstandingPrincipal: terms.Amount,
+		IssueFee:             terms.IssueFee,
+		Insurance:            terms.Insurance,
+		PSK:                  psk,
 	}
 
-	err := s.creditRepo.Create(credit)
+	disbursement, err := s.accountService.FundAccountTx(tx,
+		models.SystemAccountCreditDisbursement, accountID, terms.Amount, models.TransactionDisbursement)
 	if err != nil {
 		return nil, err
 	}
+	credit.DisbursementTransactionID = disbursement.ID
 
-	// Генерация графика платежей
-	err = s.generatePaymentSchedule(credit)
-	if err != nil {
+	if terms.IssueFee > 0 || terms.Insurance > 0 {
+		_, err := s.accountService.ChargeAccountTx(tx, accountID, models.TransactionCreditFee,
+			Charge{SystemAccount: models.SystemAccountBankRevenue, Amount: terms.IssueFee},
+			Charge{SystemAccount: models.SystemAccountInsurance, Amount: terms.Insurance},
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.creditRepo.CreateTx(tx, credit); err != nil {
 		return nil, err
 	}
 
+	if err := s.saveSchedule(tx, credit.ID, rows); err != nil {
+		return nil, err
+	}
 	return credit, nil
 }
 
-// Расчет аннуитетного платежа и создание графика платежей
-func (s *CreditService) generatePaymentSchedule(credit *models.Credit) error {
-	// Ежемесячная ставка в долях (rate - годовая в процентах)
-	monthlyRate := credit.Rate / 100 / 12
-	P := credit.Amount
-	n := float64(credit.Period)
-
-	// Аннуитетный платеж A = P * (r * (1+r)^n) / ((1+r)^n - 1)
-	A := P * (monthlyRate * math.Pow(1+monthlyRate, n)) / (math.Pow(1+monthlyRate, n) - 1)
-	A = math.Round(A*100) / 100 // округление до копеек
-
-	for i := 1; i <= credit.Period; i++ {
-		dueDate := time.Now().AddDate(0, i, 0)
-		schedule := &models.PaymentSchedule{
-			CreditID: credit.ID,
-			DueDate:  dueDate,
-			Amount:   A,
-			Paid:     false,
-		}
-		err := s.paymentScheduleRepo.Create(schedule)
-		if err != nil {
+// Проверка условий кредита. Без ставки берется ключевая ставка ЦБ,
+// без вида графика - аннуитет.
+func (s *CreditService) resolveTerms(terms CreditTerms) (CreditTerms, error) {
+    if terms.Rate <= 0 {
+        keyRate, err := s.cbrService.GetKeyRate()
+        if err != nil {
+            s.logger.Warnf("Using default rate 10%%, failed to get CBR rate: %v", err)
+            terms.Rate = 10.0 // дефолтная ставка при ошибке
+        } else {
+            terms.Rate = keyRate
+        }
+    }
+
+	if terms.Amount <= 0 || terms.Rate <= 0 || terms.Period <= 0 {
+		return terms, ErrInvalidCreditTerms
+	}
+	if terms.Amount > maxCreditAmount {
+		return terms, fmt.Errorf("%w: amount must not exceed %s", ErrInvalidCreditTerms, maxCreditAmount)
+	}
+	if terms.Rate > maxCreditRate {
+		return terms, fmt.Errorf("%w: rate must not exceed %d%%", ErrInvalidCreditTerms, maxCreditRate)
+	}
+	if terms.Period > maxCreditPeriod {
+		return terms, fmt.Errorf("%w: period must not exceed %d months", ErrInvalidCreditTerms, maxCreditPeriod)
+	}
+	if terms.IssueFee < 0 || terms.Insurance < 0 || terms.IssueFee+terms.Insurance >= terms.Amount {
+		return terms, fmt.Errorf("%w: invalid credit fees", ErrInvalidCreditTerms)
+	}
+
+	if terms.ScheduleType == "" {
+		terms.ScheduleType = models.ScheduleAnnuity
+	}
+	if terms.ScheduleType != models.ScheduleAnnuity && terms.ScheduleType != models.ScheduleDifferentiated {
+		return terms, ErrInvalidScheduleType
+	}
+	return terms, nil
+}
+
+// Расчет графика платежей выбранного вида; первый платеж - через месяц после выдачи
+func (s *CreditService) generatePaymentSchedule(terms CreditTerms, issuedAt time.Time) []models.PaymentSchedule {
+	return buildSchedule(terms.ScheduleType, terms.Amount, monthlyRate(terms.Rate), terms.Period, issuedAt.AddDate(0, 1, 0))
+}
+
+func (s *CreditService) saveSchedule(tx *sql.Tx, creditID uint, rows []models.PaymentSchedule) error {
+	for i := range rows {
+		rows[i].CreditID = creditID
+		if err := s.paymentScheduleRepo.CreateTx(tx, &rows[i]); err != nil {
 			return err
 		}
 	}
-
 	return nil
 }
 
+// График платежей вместе с раскрываемой ПСК и начисленной неустойкой
+type CreditSchedule struct {
+	CreditID   uint                     `json:"credit_id"`
+	Status     string                   `json:"status"`
+	PSK        float64                  `json:"psk"`
+	PenaltyDue money.Amount             `json:"penalty_due"` // начислено и не погашено
+	Schedule   []models.PaymentSchedule `json:"schedule"`
+	Penalties  []models.PenaltyAccrual  `json:"penalties"`
+}
+
 // Получение графика платежей по кредиту
-func (s *CreditService) GetPaymentSchedule(userID, creditID uint) ([]models.PaymentSchedule, error) {
+func (s *CreditService) GetPaymentSchedule(userID, creditID uint) (*CreditSchedule, error) {
 	credit, err := s.creditRepo.GetByIDAndUser(creditID, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.paymentScheduleRepo.GetByCreditID(credit.ID)
-}
-
-// Обработка просроченных платежей (начисление штрафов, попытка списания)
-func (s *CreditService) ProcessOverduePayments() error {
-	now := time.Now()
-	overdueSchedules, err := s.paymentScheduleRepo.GetOverdueUnpaidSchedules(now)
+	schedule, err := s.paymentScheduleRepo.GetByCreditID(credit.ID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	for _, schedule := range overdueSchedules {
-		credit, err := s.creditRepo.GetByIDAndUser(schedule.CreditID, 0) // 0 - без проверки пользователя
-		if err != nil {
-			s.logger.WithError(err).Warnf("Credit not found for schedule %d", schedule.ID)
-			continue
-		}
-
-		account, err := s.accountService.GetAccount(credit.AccountID)
-		if err != nil {
-			s.logger.WithError(err).Warnf("Account not found for credit %d", credit.ID)
-			continue
-		}
-
-		// Штраф +10%
-		amountWithPenalty := schedule.Amount * 1.10
-
-		if account.Balance >= amountWithPenalty {
-			// Списываем средства
-			err = s.accountService.Transfer(credit.AccountID, 0, amountWithPenalty) // 0 - внешний счет (например банк)
-			if err != nil {
-				s.logger.WithError(err).Warnf("Failed to transfer payment for schedule %d", schedule.ID)
-				continue
-			}
-
-			// Отмечаем платеж как оплаченный
-			err = s.paymentScheduleRepo.MarkAsPaid(schedule.ID)
-			if err != nil {
-				s.logger.WithError(err).Warnf("Failed to mark schedule %d as paid", schedule.ID)
-				continue
-			}
-		} else {
-			s.logger.Infof("Insufficient funds for credit %d, schedule %d - penalty applied", credit.ID, schedule.ID)
-			if err == nil {
-				err = s.emailService.SendPaymentNotification(
-					s.userRepo,          // Передаем userRepo
-					credit.UserID,      // Передаем идентификатор пользователя
-					fmt.Sprintf("%.2f RUB", amountWithPenalty), // Передаем сумму
-				)
-				if err != nil {
-					// Обработка ошибки при отправке уведомления
-				}
-			}
-		}
+	penalties, err := s.penaltyRepo.GetByCreditID(credit.ID)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	result := &CreditSchedule{
+		CreditID:  credit.ID,
+		Status:    credit.Status,
+		PSK:       credit.PSK,
+		Schedule:  schedule,
+		Penalties: penalties,
+	}
+	for _, row := range schedule {
+		result.PenaltyDue += row.Penalty - row.PenaltyPaid
+	}
+	return result, nil
 }
 
 func (s *CreditService) GetCreditsByAccount(accountID uint) ([]models.Credit, error) {
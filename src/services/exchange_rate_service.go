@@ -0,0 +1,109 @@
+package services
+
+import (
+	"bank-service/src/models"
+	"bank-service/src/money"
+	"bank-service/src/repositories"
+	"errors"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	ErrAmountTooSmall      = errors.New("amount is too small to convert")
+)
+
+// Курс конвертации хранится с точностью до 6 знаков, суммы округляются до копеек
+const (
+	rateDecimals       = 6
+	conversionRounding = money.RoundHalfUp
+)
+
+type ExchangeRateService struct {
+	rateRepo   *repositories.ExchangeRateRepository
+	cbrService *CBRService
+	logger     *logrus.Logger
+}
+
+func NewExchangeRateService(rateRepo *repositories.ExchangeRateRepository, cbrService *CBRService, logger *logrus.Logger) *ExchangeRateService {
+	return &ExchangeRateService{
+		rateRepo:   rateRepo,
+		cbrService: cbrService,
+		logger:     logger,
+	}
+}
+
+// Загрузка официальных курсов ЦБ на дату в exchange_rates
+func (s *ExchangeRateService) RefreshRates(date time.Time) error {
+	rates, err := s.cbrService.GetCursOnDate(day(date))
+	if err != nil {
+		return err
+	}
+
+	saved := 0
+	for i := range rates {
+		if !models.IsSupportedCurrency(rates[i].Currency) {
+			continue
+		}
+		if err := s.rateRepo.Save(&rates[i]); err != nil {
+			return err
+		}
+		saved++
+	}
+	s.logger.Infof("Saved %d CBR exchange rates on %s", saved, day(date).Format("2006-01-02"))
+	return nil
+}
+
+// Курс from -> to на дату по сохраненным курсам ЦБ: сколько единиц to
+// стоит единица from. Округляется до rateDecimals знаков - именно этот
+// курс применяется к сумме и записывается в операцию.
+func (s *ExchangeRateService) GetRate(from, to string, date time.Time) (float64, error) {
+	if !models.IsSupportedCurrency(from) || !models.IsSupportedCurrency(to) {
+		return 0, ErrUnsupportedCurrency
+	}
+	if from == to {
+		return 1, nil
+	}
+
+	fromRub, err := s.rubPerUnit(from, date)
+	if err != nil {
+		return 0, err
+	}
+	toRub, err := s.rubPerUnit(to, date)
+	if err != nil {
+		return 0, err
+	}
+
+	f, _ := new(big.Rat).Quo(fromRub, toRub).Float64()
+	scale := math.Pow10(rateDecimals)
+	return math.Round(f*scale) / scale, nil
+}
+
+// Конвертация суммы по курсу GetRate
+func convert(amount money.Amount, rate float64) money.Amount {
+	return amount.Mul(money.Decimal(rate), conversionRounding)
+}
+
+// Рублей за единицу валюты. Если курса на дату еще нет, он загружается из ЦБ.
+func (s *ExchangeRateService) rubPerUnit(currency string, date time.Time) (*big.Rat, error) {
+	if currency == models.CurrencyRUB {
+		return big.NewRat(1, 1), nil
+	}
+
+	rate, err := s.rateRepo.GetOnDate(currency, date)
+	if errors.Is(err, repositories.ErrExchangeRateNotFound) {
+		if err := s.RefreshRates(date); err != nil {
+			return nil, err
+		}
+		rate, err = s.rateRepo.GetOnDate(currency, date)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Rat).Quo(money.Decimal(rate.Value), big.NewRat(int64(rate.Nominal), 1)), nil
+}
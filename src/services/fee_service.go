@@ -0,0 +1,151 @@
+package services
+
+import (
+	"bank-service/src/models"
+	"bank-service/src/money"
+	"bank-service/src/repositories"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var ErrUnknownFeeOperation = errors.New("unknown fee operation")
+
+// Комиссия округляется в пользу клиента
+const feeRounding = money.RoundDown
+
+type FeeService struct {
+	feeRepo  *repositories.FeeRepository
+	userRepo *repositories.UserRepository
+	rates    *ExchangeRateService
+	logger   *logrus.Logger
+}
+
+func NewFeeService(
+	feeRepo *repositories.FeeRepository,
+	userRepo *repositories.UserRepository,
+	rates *ExchangeRateService,
+	logger *logrus.Logger,
+) *FeeService {
+	return &FeeService{
+		feeRepo:  feeRepo,
+		userRepo: userRepo,
+		rates:    rates,
+		logger:   logger,
+	}
+}
+
+// Расчет комиссии для показа клиенту до операции. Фактическая комиссия
+// может отличаться, если до исполнения клиент успеет выполнить другие операции.
+func (s *FeeService) Preview(userID uint, operation string, amount money.Amount, currency string) (*models.FeeCharge, error) {
+	now := time.Now()
+	count, volume, err := s.feeRepo.MonthlyUsage(userID, operation, monthStart(now))
+	if err != nil {
+		return nil, err
+	}
+	return s.calculate(userID, operation, amount, currency, count, volume, now)
+}
+
+// Расчет комиссии в транзакции операции. Строка пользователя блокируется,
+// чтобы параллельные операции не использовали одну бесплатную квоту дважды.
+func (s *FeeService) CalculateTx(tx *sql.Tx, userID uint, operation string, amount money.Amount, currency string) (*models.FeeCharge, error) {
+	if err := s.userRepo.LockTx(tx, userID); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	count, volume, err := s.feeRepo.MonthlyUsageTx(tx, userID, operation, monthStart(now))
+	if err != nil {
+		return nil, err
+	}
+	return s.calculate(userID, operation, amount, currency, count, volume, now)
+}
+
+func (s *FeeService) RecordTx(tx *sql.Tx, charge *models.FeeCharge) error {
+	return s.feeRepo.CreateChargeTx(tx, charge)
+}
+
+func (s *FeeService) calculate(userID uint, operation string, amount money.Amount, currency string, count int, volume money.Amount, now time.Time) (*models.FeeCharge, error) {
+	rules, err := s.feeRepo.GetRules(operation)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, ErrUnknownFeeOperation
+	}
+
+	toRUB, err := s.rates.GetRate(currency, models.CurrencyRUB, now)
+	if err != nil {
+		return nil, err
+	}
+	base := convert(amount, toRUB)
+
+	rule := tierFor(rules, volume)
+	charge := &models.FeeCharge{
+		UserID:    userID,
+		Operation: operation,
+		RuleID:    rule.ID,
+		Amount:    amount,
+		Currency:  currency,
+		VolumeRUB: base,
+	}
+
+	feeRUB := ruleFee(rule, base, count, volume)
+	if feeRUB > 0 {
+		fromRUB, err := s.rates.GetRate(models.CurrencyRUB, currency, now)
+		if err != nil {
+			return nil, err
+		}
+		charge.Fee = convert(feeRUB, fromRUB)
+	}
+	return charge, nil
+}
+
+// Ступень тарифа по месячному обороту до текущей операции
+func tierFor(rules []models.FeeRule, volume money.Amount) models.FeeRule {
+	rule := rules[0]
+	for _, r := range rules[1:] {
+		if volume >= r.MinVolume {
+			rule = r
+		}
+	}
+	return rule
+}
+
+// Комиссия в рублях с операции base при уже совершенных за месяц count
+// операциях на сумму volume
+func ruleFee(rule models.FeeRule, base money.Amount, count int, volume money.Amount) money.Amount {
+	if count < rule.FreeCount {
+		return 0
+	}
+
+	// Бесплатный месячный оборот уменьшает облагаемую часть суммы
+	taxable := base
+	if rule.FreeAmount > 0 {
+		left := rule.FreeAmount - volume
+		if left >= taxable {
+			return 0
+		}
+		if left > 0 {
+			taxable -= left
+		}
+	}
+
+	if rule.Flat == 0 && (rule.Percent == 0 || taxable == 0) {
+		return 0
+	}
+	fee := rule.Flat + taxable.Mul(money.Percent(rule.Percent), feeRounding)
+	if fee < rule.MinFee {
+		fee = rule.MinFee
+	}
+	if rule.MaxFee > 0 && fee > rule.MaxFee {
+		fee = rule.MaxFee
+	}
+	return fee
+}
+
+func monthStart(t time.Time) time.Time {
+	y, m, _ := t.Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+}
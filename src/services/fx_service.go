@@ -0,0 +1,146 @@
+package services
+
+import (
+	"bank-service/src/models"
+	"bank-service/src/money"
+	"bank-service/src/repositories"
+	"database/sql"
+	"errors"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrSameCurrency     = errors.New("accounts must be in different currencies")
+	ErrQuoteExpired     = errors.New("fx quote has expired")
+	ErrQuoteAlreadyUsed = errors.New("fx quote has already been executed")
+)
+
+// Срок действия котировки, если он не задан в конфигурации
+const defaultQuoteTTL = time.Minute
+
+type FXService struct {
+	quoteRepo      *repositories.FXQuoteRepository
+	accountService *AccountService
+	rates          *ExchangeRateService
+	spread         float64       // спред банка к курсу ЦБ, %
+	quoteTTL       time.Duration // срок действия котировки
+	logger         *logrus.Logger
+}
+
+func NewFXService(
+	quoteRepo *repositories.FXQuoteRepository,
+	accountService *AccountService,
+	rates *ExchangeRateService,
+	spread float64,
+	quoteTTL time.Duration,
+	logger *logrus.Logger,
+) *FXService {
+	if quoteTTL <= 0 {
+		quoteTTL = defaultQuoteTTL
+	}
+	return &FXService{
+		quoteRepo:      quoteRepo,
+		accountService: accountService,
+		rates:          rates,
+		spread:         spread,
+		quoteTTL:       quoteTTL,
+		logger:         logger,
+	}
+}
+
+// Котировка обмена amount со счета fromAccountID на счет toAccountID.
+// Курс клиента хуже курса ЦБ на спред; разница остается на валютной позиции банка.
+func (s *FXService) Quote(userID, fromAccountID, toAccountID uint, amount money.Amount) (*models.FXQuote, error) {
+	if amount <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+
+	from, err := s.accountService.GetByIDAndUser(fromAccountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.accountService.GetByIDAndUser(toAccountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if from.Currency == to.Currency {
+		return nil, ErrSameCurrency
+	}
+
+	now := time.Now()
+	cbrRate, err := s.rates.GetRate(from.Currency, to.Currency, now)
+	if err != nil {
+		return nil, err
+	}
+
+	k := new(big.Rat).Sub(big.NewRat(1, 1), money.Percent(s.spread))
+	f, _ := new(big.Rat).Mul(money.Decimal(cbrRate), k).Float64()
+	scale := math.Pow10(rateDecimals)
+	rate := math.Floor(f*scale) / scale
+
+	quote := &models.FXQuote{
+		UserID:        userID,
+		FromAccountID: fromAccountID,
+		ToAccountID:   toAccountID,
+		FromCurrency:  from.Currency,
+		ToCurrency:    to.Currency,
+		Amount:        amount,
+		ToAmount:      convert(amount, rate),
+		CBRRate:       cbrRate,
+		Rate:          rate,
+		Spread:        s.spread,
+		ExpiresAt:     now.Add(s.quoteTTL),
+	}
+	if quote.ToAmount <= 0 {
+		return nil, ErrAmountTooSmall
+	}
+
+	if err := s.quoteRepo.Create(quote); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// Обмен по котировке в пределах срока ее действия. Суммы и курс берутся
+// из котировки, а не пересчитываются по текущему курсу.
+func (s *FXService) Exchange(userID uint, quoteID string) (*models.Transaction, error) {
+	var transaction *models.Transaction
+	err := s.accountService.RunInTx(func(tx *sql.Tx) error {
+		quote, err := s.quoteRepo.GetByIDForUpdateTx(tx, quoteID)
+		if err != nil {
+			return err
+		}
+		if quote.UserID != userID {
+			return repositories.ErrQuoteNotFound
+		}
+		if quote.ExecutedAt != nil {
+			return ErrQuoteAlreadyUsed
+		}
+		if time.Now().After(quote.ExpiresAt) {
+			return ErrQuoteExpired
+		}
+
+		transaction = &models.Transaction{
+			FromAccountID: quote.FromAccountID,
+			ToAccountID:   quote.ToAccountID,
+			Amount:        quote.Amount,
+			Currency:      quote.FromCurrency,
+			ToAmount:      quote.ToAmount,
+			ToCurrency:    quote.ToCurrency,
+			ExchangeRate:  quote.Rate,
+			Type:          models.TransactionExchange,
+		}
+		if err := s.accountService.TransferTx(tx, transaction); err != nil {
+			return err
+		}
+		return s.quoteRepo.MarkExecutedTx(tx, quote, transaction.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"bank-service/src/crypto"
+	"bank-service/src/models"
+	"bank-service/src/repositories"
+	"fmt"
+	"sync"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/sirupsen/logrus"
+)
+
+// Версия ключа для списка у сотрудников: сколько карт еще на ней зашифровано
+type PGPKeyInfo struct {
+	models.PGPKey
+	Cards int `json:"cards"`
+}
+
+// Хранилище ключей PGP для данных карт. Закрытые ключи лежат в pgp_keys,
+// зашифрованные мастер-ключом, и расшифровываются в память при загрузке.
+type KeyService struct {
+	keyRepo   *repositories.PGPKeyRepository
+	masterKey []byte
+	logger    *logrus.Logger
+
+	mu        sync.RWMutex
+	keys      map[uint]*openpgp.Entity
+	currentID uint
+}
+
+func NewKeyService(keyRepo *repositories.PGPKeyRepository, masterKey []byte, logger *logrus.Logger) *KeyService {
+	return &KeyService{
+		keyRepo:   keyRepo,
+		masterKey: masterKey,
+		logger:    logger,
+		keys:      make(map[uint]*openpgp.Entity),
+	}
+}
+
+// Загрузка всех версий ключа. При пустом хранилище создается первый ключ.
+// Повторный вызов подхватывает ротацию, выполненную другим экземпляром.
+func (s *KeyService) Load() error {
+	stored, err := s.keyRepo.GetAll()
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[uint]*openpgp.Entity, len(stored))
+	var currentID uint
+	for _, key := range stored {
+		entity, err := s.unseal(&key)
+		if err != nil {
+			return err
+		}
+		keys[key.ID] = entity
+		if key.Status == models.PGPKeyActive {
+			currentID = key.ID
+		}
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.currentID = currentID
+	s.mu.Unlock()
+
+	if currentID == 0 {
+		_, err := s.Rotate()
+		return err
+	}
+	return nil
+}
+
+// Активный ключ для шифрования
+func (s *KeyService) Current() (uint, *openpgp.Entity) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.currentID, s.keys[s.currentID]
+}
+
+// Ключ по id из cards.key_id
+func (s *KeyService) Get(id uint) (*openpgp.Entity, error) {
+	s.mu.RLock()
+	entity, ok := s.keys[id]
+	s.mu.RUnlock()
+	if ok {
+		return entity, nil
+	}
+
+	// Ключ мог появиться после загрузки, если ротацию выполнил другой экземпляр
+	key, err := s.keyRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	entity, err = s.unseal(key)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.keys[id] = entity
+	s.mu.Unlock()
+	return entity, nil
+}
+
+// Новая версия ключа. Старые версии остаются для расшифровки, пока карты
+// не перешифрованы (CardService.ReencryptCards).
+func (s *KeyService) Rotate() (*models.PGPKey, error) {
+	entity, err := crypto.InitPGP()
+	if err != nil {
+		return nil, err
+	}
+	serialized, err := crypto.SerializePrivateKey(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	keyID := entity.PrimaryKey.KeyIdString()
+	sealed, err := crypto.Seal(s.masterKey, serialized, []byte(keyID))
+	if err != nil {
+		return nil, err
+	}
+
+	key := &models.PGPKey{KeyID: keyID, SealedKey: sealed}
+	if err := s.keyRepo.Activate(key); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.keys[key.ID] = entity
+	s.currentID = key.ID
+	s.mu.Unlock()
+
+	s.logger.Infof("PGP key rotated: key %d (%s) is now active", key.ID, keyID)
+	return key, nil
+}
+
+func (s *KeyService) ListKeys() ([]PGPKeyInfo, error) {
+	keys, err := s.keyRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.keyRepo.CardCounts()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]PGPKeyInfo, 0, len(keys))
+	for _, key := range keys {
+		infos = append(infos, PGPKeyInfo{PGPKey: key, Cards: counts[key.ID]})
+	}
+	return infos, nil
+}
+
+func (s *KeyService) unseal(key *models.PGPKey) (*openpgp.Entity, error) {
+	serialized, err := crypto.Open(s.masterKey, key.SealedKey, []byte(key.KeyID))
+	if err != nil {
+		return nil, fmt.Errorf("pgp key %d: %w", key.ID, err)
+	}
+	entity, err := crypto.ReadPrivateKey(serialized)
+	if err != nil {
+		return nil, fmt.Errorf("pgp key %d: %w", key.ID, err)
+	}
+	return entity, nil
+}
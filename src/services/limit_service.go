@@ -0,0 +1,238 @@
+package services
+
+import (
+	"bank-service/src/models"
+	"bank-service/src/money"
+	"bank-service/src/repositories"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrLimitExceeded     = errors.New("limit exceeded")
+	ErrLimitAboveMaximum = errors.New("limit cannot exceed the product maximum")
+)
+
+type LimitService struct {
+	limitRepo *repositories.LimitRepository
+	userRepo  *repositories.UserRepository
+	cardRepo  *repositories.CardRepository
+	rates     *ExchangeRateService
+	logger    *logrus.Logger
+}
+
+func NewLimitService(
+	limitRepo *repositories.LimitRepository,
+	userRepo *repositories.UserRepository,
+	cardRepo *repositories.CardRepository,
+	rates *ExchangeRateService,
+	logger *logrus.Logger,
+) *LimitService {
+	return &LimitService{
+		limitRepo: limitRepo,
+		userRepo:  userRepo,
+		cardRepo:  cardRepo,
+		rates:     rates,
+		logger:    logger,
+	}
+}
+
+// Лимиты одной области вместе с максимумом продукта и расходом
+type LimitsView struct {
+	Scope   string            `json:"scope"`
+	CardID  uint              `json:"card_id,omitempty"`
+	Limits  models.Limits     `json:"limits"`
+	Maximum models.Limits     `json:"maximum"`
+	Usage   models.LimitUsage `json:"usage"`
+}
+
+// Изменение лимитов; незаданные поля не меняются
+type LimitsUpdate struct {
+	CardID     uint          `json:"card_id"`
+	SingleMax  *money.Amount `json:"single_max"`
+	DailyMax   *money.Amount `json:"daily_max"`
+	MonthlyMax *money.Amount `json:"monthly_max"`
+	DailyCount *int          `json:"daily_count"`
+}
+
+// Общие лимиты клиента и лимиты каждой его карты
+func (s *LimitService) GetLimits(userID uint) ([]LimitsView, error) {
+	cards, err := s.cardRepo.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]LimitsView, 0, len(cards)+1)
+	ids := []uint{0}
+	for _, card := range cards {
+		ids = append(ids, card.ID)
+	}
+
+	now := time.Now()
+	for _, cardID := range ids {
+		maximum, limits, err := s.effective(userID, cardID)
+		if err != nil {
+			return nil, err
+		}
+		usage, err := s.limitRepo.Usage(userID, cardID, dayStart(now), monthStart(now))
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, LimitsView{
+			Scope:   limitScope(cardID),
+			CardID:  cardID,
+			Limits:  *limits,
+			Maximum: *maximum,
+			Usage:   *usage,
+		})
+	}
+	return views, nil
+}
+
+// Понижение (или возврат к максимуму) лимитов клиентом
+func (s *LimitService) UpdateLimits(userID uint, update LimitsUpdate) (*models.Limits, error) {
+	if update.CardID != 0 {
+		if _, err := s.cardRepo.GetByIDAndUser(update.CardID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	maximum, limits, err := s.effective(userID, update.CardID)
+	if err != nil {
+		return nil, err
+	}
+
+	amounts := []struct {
+		value    *money.Amount
+		target   *money.Amount
+		maxValue money.Amount
+	}{
+		{update.SingleMax, &limits.SingleMax, maximum.SingleMax},
+		{update.DailyMax, &limits.DailyMax, maximum.DailyMax},
+		{update.MonthlyMax, &limits.MonthlyMax, maximum.MonthlyMax},
+	}
+	for _, a := range amounts {
+		if a.value == nil {
+			continue
+		}
+		if *a.value < 0 || *a.value > a.maxValue {
+			return nil, ErrLimitAboveMaximum
+		}
+		*a.target = *a.value
+	}
+	if update.DailyCount != nil {
+		if *update.DailyCount < 0 || *update.DailyCount > maximum.DailyCount {
+			return nil, ErrLimitAboveMaximum
+		}
+		limits.DailyCount = *update.DailyCount
+	}
+
+	if err := s.limitRepo.SaveUserLimits(userID, update.CardID, limits); err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+// Проверка операции на сумму amount по лимитам клиента и, если операция
+// по карте, лимитам карты. Строка клиента блокируется до конца транзакции,
+// поэтому параллельные операции видят расход друг друга. Возвращает сумму
+// в рублях для RecordTx.
+func (s *LimitService) CheckTx(tx *sql.Tx, userID, cardID uint, amount money.Amount, currency string) (money.Amount, error) {
+	if err := s.userRepo.LockTx(tx, userID); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	rate, err := s.rates.GetRate(currency, models.CurrencyRUB, now)
+	if err != nil {
+		return 0, err
+	}
+	amountRUB := convert(amount, rate)
+
+	scopes := []uint{0}
+	if cardID != 0 {
+		scopes = append(scopes, cardID)
+	}
+	for _, id := range scopes {
+		_, limits, err := s.effective(userID, id)
+		if err != nil {
+			return 0, err
+		}
+		usage, err := s.limitRepo.UsageTx(tx, userID, id, dayStart(now), monthStart(now))
+		if err != nil {
+			return 0, err
+		}
+
+		scope := limitScope(id)
+		switch {
+		case amountRUB > limits.SingleMax:
+			return 0, fmt.Errorf("%w: single operation %s limit is %s RUB", ErrLimitExceeded, scope, limits.SingleMax)
+		case usage.DailyCount+1 > limits.DailyCount:
+			return 0, fmt.Errorf("%w: daily %s limit is %d operations", ErrLimitExceeded, scope, limits.DailyCount)
+		case usage.DailyTotal+amountRUB > limits.DailyMax:
+			return 0, fmt.Errorf("%w: daily %s limit is %s RUB", ErrLimitExceeded, scope, limits.DailyMax)
+		case usage.MonthlyTotal+amountRUB > limits.MonthlyMax:
+			return 0, fmt.Errorf("%w: monthly %s limit is %s RUB", ErrLimitExceeded, scope, limits.MonthlyMax)
+		}
+	}
+	return amountRUB, nil
+}
+
+// Учет операции в расходе лимитов клиента (и карты)
+func (s *LimitService) RecordTx(tx *sql.Tx, userID, cardID uint, amountRUB money.Amount, transactionID uint) error {
+	return s.limitRepo.RecordUsageTx(tx, userID, cardID, amountRUB, transactionID)
+}
+
+// Учет авторизации по карте; до списания расход привязан к авторизации
+func (s *LimitService) RecordHoldTx(tx *sql.Tx, userID, cardID uint, amountRUB money.Amount, holdID uint) error {
+	return s.limitRepo.RecordHoldUsageTx(tx, userID, cardID, amountRUB, holdID)
+}
+
+func (s *LimitService) SettleHoldTx(tx *sql.Tx, holdID uint, captured, held money.Amount, transactionID uint) error {
+	return s.limitRepo.SettleHoldUsageTx(tx, holdID, captured, held, transactionID)
+}
+
+func (s *LimitService) ReleaseHoldTx(tx *sql.Tx, holdID uint) error {
+	return s.limitRepo.ReleaseHoldUsageTx(tx, holdID)
+}
+
+// Максимум продукта и действующие лимиты. Если максимум понизили после
+// того, как клиент задал свои лимиты, действует меньшее значение.
+func (s *LimitService) effective(userID, cardID uint) (maximum, limits *models.Limits, err error) {
+	maximum, err = s.limitRepo.GetDefaults(limitScope(cardID))
+	if err != nil {
+		return nil, nil, err
+	}
+	own, err := s.limitRepo.GetUserLimits(userID, cardID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	limits = &models.Limits{}
+	*limits = *maximum
+	if own != nil {
+		limits.SingleMax = min(own.SingleMax, maximum.SingleMax)
+		limits.DailyMax = min(own.DailyMax, maximum.DailyMax)
+		limits.MonthlyMax = min(own.MonthlyMax, maximum.MonthlyMax)
+		if own.DailyCount < maximum.DailyCount {
+			limits.DailyCount = own.DailyCount
+		}
+	}
+	return maximum, limits, nil
+}
+
+func limitScope(cardID uint) string {
+	if cardID != 0 {
+		return models.LimitScopeCard
+	}
+	return models.LimitScopeUser
+}
+
+func dayStart(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"bank-service/src/models"
+	"bank-service/src/repositories"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrRecipientNotFound = errors.New("recipient not found")
+	ErrInvalidRecipient  = errors.New("exactly one of to_account_id, to_card_number, to_phone or to_email must be provided")
+	ErrInvalidPhone      = errors.New("invalid phone number")
+)
+
+// Способ указать получателя перевода; заполняется ровно одно поле
+type RecipientQuery struct {
+	AccountID  uint   `json:"to_account_id"`
+	CardNumber string `json:"to_card_number"`
+	Phone      string `json:"to_phone"`
+	Email      string `json:"to_email"`
+}
+
+// Найденный получатель. Счет наружу не отдается: отправитель видит
+// только маскированное имя и валюту зачисления.
+type Recipient struct {
+	AccountID  uint   `json:"-"`
+	MaskedName string `json:"masked_name"`
+	Currency   string `json:"currency"`
+}
+
+type RecipientService struct {
+	userRepo    *repositories.UserRepository
+	accountRepo *repositories.AccountRepository
+	cardService *CardService
+	logger      *logrus.Logger
+}
+
+func NewRecipientService(
+	userRepo *repositories.UserRepository,
+	accountRepo *repositories.AccountRepository,
+	cardService *CardService,
+	logger *logrus.Logger,
+) *RecipientService {
+	return &RecipientService{
+		userRepo:    userRepo,
+		accountRepo: accountRepo,
+		cardService: cardService,
+		logger:      logger,
+	}
+}
+
+// Определение счета получателя. По телефону и email выбирается счет
+// получателя в валюте currency (валюте отправителя), а при его отсутствии -
+// первый открытый счет.
+func (s *RecipientService) Resolve(q RecipientQuery, currency string) (*Recipient, error) {
+	set := 0
+	for _, filled := range []bool{q.AccountID != 0, q.CardNumber != "", q.Phone != "", q.Email != ""} {
+		if filled {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, ErrInvalidRecipient
+	}
+
+	var account *models.Account
+	var user *models.User
+	var err error
+	switch {
+	case q.AccountID != 0:
+		account, err = s.accountRepo.GetByID(q.AccountID)
+	case q.CardNumber != "":
+		account, err = s.byCard(q.CardNumber)
+	case q.Phone != "":
+		var phone string
+		if phone, err = NormalizePhone(q.Phone); err != nil {
+			return nil, err
+		}
+		if user, err = s.userRepo.GetByPhone(phone); err == nil {
+			account, err = s.userAccount(user.ID, currency)
+		}
+	default:
+		if user, err = s.userRepo.GetByEmail(strings.TrimSpace(q.Email)); err == nil {
+			account, err = s.userAccount(user.ID, currency)
+		}
+	}
+	if errors.Is(err, repositories.ErrAccountNotFound) ||
+		errors.Is(err, repositories.ErrCardNotFound) ||
+		errors.Is(err, repositories.ErrUserNotFound) {
+		return nil, ErrRecipientNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		if user, err = s.userRepo.GetByID(account.UserID); err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, ErrRecipientNotFound
+		}
+	}
+
+	return &Recipient{
+		AccountID:  account.ID,
+		MaskedName: maskName(user.Username),
+		Currency:   account.Currency,
+	}, nil
+}
+
+func (s *RecipientService) byCard(number string) (*models.Account, error) {
+	number = strings.Join(strings.Fields(number), "")
+	if len(number) < 13 || len(number) > 19 || strings.Trim(number, "0123456789") != "" ||
+		!(&models.Card{}).ValidateLuhn(number) {
+		return nil, ErrInvalidRecipient
+	}
+
+	card, err := s.cardService.FindByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	// Статус чужой карты отправителю не раскрывается
+	if card.Status != models.CardActive {
+		return nil, repositories.ErrCardNotFound
+	}
+	return s.accountRepo.GetByID(card.AccountID)
+}
+
+func (s *RecipientService) userAccount(userID uint, currency string) (*models.Account, error) {
+	accounts, err := s.accountRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var fallback *models.Account
+	for i := range accounts {
+		if accounts[i].Status != models.AccountActive {
+			continue
+		}
+		if accounts[i].Currency == currency {
+			return &accounts[i], nil
+		}
+		if fallback == nil {
+			fallback = &accounts[i]
+		}
+	}
+	if fallback == nil {
+		return nil, repositories.ErrAccountNotFound
+	}
+	return fallback, nil
+}
+
+// Приведение телефона к E.164. Российские номера допускаются
+// в формате 8XXXXXXXXXX и без кода страны.
+func NormalizePhone(phone string) (string, error) {
+	var digits strings.Builder
+	for i, r := range strings.TrimSpace(phone) {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0, r == ' ', r == '-', r == '(', r == ')':
+		default:
+			return "", ErrInvalidPhone
+		}
+	}
+
+	d := digits.String()
+	international := strings.HasPrefix(strings.TrimSpace(phone), "+")
+	switch {
+	case !international && len(d) == 11 && d[0] == '8':
+		d = "7" + d[1:]
+	case !international && len(d) == 10:
+		d = "7" + d
+	}
+	if len(d) < 10 || len(d) > 15 || d[0] == '0' {
+		return "", ErrInvalidPhone
+	}
+	return "+" + d, nil
+}
+
+// Маскированное имя для подтверждения получателя: "alexander" -> "al***r"
+func maskName(name string) string {
+	runes := []rune(name)
+	switch n := utf8.RuneCountInString(name); {
+	case n == 0:
+		return ""
+	case n <= 3:
+		return string(runes[:1]) + "***"
+	default:
+		return string(runes[:2]) + "***" + string(runes[n-1:])
+	}
+}
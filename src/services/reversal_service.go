@@ -0,0 +1,119 @@
+package services
+
+import (
+	"bank-service/src/models"
+	"bank-service/src/money"
+	"bank-service/src/repositories"
+	"database/sql"
+	"errors"
+	"math"
+	"math/big"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrNotReversible             = errors.New("only transfers between client accounts can be reversed")
+	ErrAlreadyReversed           = errors.New("transaction has already been fully reversed")
+	ErrReversalExceedsAmount     = errors.New("refund exceeds the amount left to reverse")
+	ErrReversalInsufficientFunds = errors.New("recipient has insufficient funds for the reversal")
+)
+
+type ReversalService struct {
+	accountService  *AccountService
+	transactionRepo *repositories.TransactionRepository
+	logger          *logrus.Logger
+}
+
+func NewReversalService(
+	accountService *AccountService,
+	transactionRepo *repositories.TransactionRepository,
+	logger *logrus.Logger,
+) *ReversalService {
+	return &ReversalService{
+		accountService:  accountService,
+		transactionRepo: transactionRepo,
+		logger:          logger,
+	}
+}
+
+// Возврат перевода (полный или частичный) компенсирующей операцией
+// от получателя к отправителю. amount задается в валюте исходного перевода,
+// 0 - вернуть весь остаток. Для перевода между валютами с получателя
+// списывается пропорциональная часть зачисленной суммы, поэтому курсовой
+// разницы при возврате не возникает.
+func (s *ReversalService) Reverse(staffID, transactionID uint, amount money.Amount, reason string) (*models.Transaction, error) {
+	if amount < 0 {
+		return nil, errors.New("amount must be positive")
+	}
+
+	var reversal *models.Transaction
+	err := s.accountService.RunInTx(func(tx *sql.Tx) error {
+		original, err := s.transactionRepo.GetByIDForUpdateTx(tx, transactionID)
+		if err != nil {
+			return err
+		}
+		if (original.Type != models.TransactionTransfer && original.Type != models.TransactionExchange) ||
+			original.FromAccountID == 0 || original.ToAccountID == 0 {
+			return ErrNotReversible
+		}
+
+		debited, refunded, err := s.transactionRepo.SumReversalsTx(tx, original.ID)
+		if err != nil {
+			return err
+		}
+		remaining := original.Amount - refunded
+		if remaining <= 0 {
+			return ErrAlreadyReversed
+		}
+		refund := amount
+		if refund == 0 {
+			refund = remaining
+		}
+		if refund > remaining {
+			return ErrReversalExceedsAmount
+		}
+
+		credited, currency := original.ToAmount, original.ToCurrency
+		if currency == "" {
+			credited, currency = original.Amount, original.Currency
+		}
+		// Последний возврат забирает остаток зачисления без округления
+		debit := credited - debited
+		if refund < remaining {
+			debit = credited.Mul(big.NewRat(int64(refund), int64(original.Amount)), money.RoundHalfUp)
+		}
+		if debit <= 0 {
+			return ErrAmountTooSmall
+		}
+
+		reversal = &models.Transaction{
+			FromAccountID: original.ToAccountID,
+			ToAccountID:   original.FromAccountID,
+			Amount:        debit,
+			Currency:      currency,
+			Type:          models.TransactionReversal,
+			ReversalOf:    original.ID,
+		}
+		if currency != original.Currency {
+			scale := math.Pow10(rateDecimals)
+			reversal.ToAmount = refund
+			reversal.ToCurrency = original.Currency
+			reversal.ExchangeRate = math.Round(refund.Float64()/debit.Float64()*scale) / scale
+		}
+
+		if err := s.accountService.TransferTx(tx, reversal); err != nil {
+			if errors.Is(err, ErrInsufficientFunds) {
+				return ErrReversalInsufficientFunds
+			}
+			return err
+		}
+		return s.transactionRepo.RecordReversalTx(tx, reversal, staffID, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Transaction %d reversed by staff %d: %s", transactionID, staffID, reversal.Amount)
+	return reversal, nil
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"bank-service/src/models"
+	"bank-service/src/repositories"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrInvalidFrequency = errors.New("frequency must be once, weekly or monthly")
+	ErrInvalidSchedule  = errors.New("invalid standing order schedule")
+)
+
+const (
+	// Попытки исполнения одного перевода при нехватке средств; интервал
+	// между ними удваивается начиная с standingOrderRetryBase
+	standingOrderMaxAttempts = 5
+	standingOrderRetryBase   = time.Hour
+
+	// На это время поручение захватывается исполнителем
+	standingOrderClaimTimeout = 15 * time.Minute
+	standingOrderBatchSize    = 100
+)
+
+type StandingOrderService struct {
+	orderRepo      *repositories.StandingOrderRepository
+	accountService *AccountService
+	logger         *logrus.Logger
+}
+
+func NewStandingOrderService(
+	orderRepo *repositories.StandingOrderRepository,
+	accountService *AccountService,
+	logger *logrus.Logger,
+) *StandingOrderService {
+	return &StandingOrderService{
+		orderRepo:      orderRepo,
+		accountService: accountService,
+		logger:         logger,
+	}
+}
+
+// Создание поручения. Для ежемесячного перевода без DayOfMonth берется
+// день StartDate; если в месяце меньше дней, перевод выполняется в последний день.
+func (s *StandingOrderService) Create(order *models.StandingOrder) error {
+	if order.Amount <= 0 || order.FromAccountID == order.ToAccountID {
+		return ErrInvalidSchedule
+	}
+	if _, err := s.accountService.GetByIDAndUser(order.FromAccountID, order.UserID); err != nil {
+		return err
+	}
+	if _, err := s.accountService.GetAccount(order.ToAccountID); err != nil {
+		return err
+	}
+
+	order.StartDate = day(order.StartDate)
+	if order.EndDate != nil {
+		end := day(*order.EndDate)
+		order.EndDate = &end
+	}
+	if order.StartDate.Before(day(time.Now())) || order.MaxRuns < 0 {
+		return ErrInvalidSchedule
+	}
+
+	switch order.Frequency {
+	case models.FrequencyOnce, models.FrequencyWeekly:
+		if order.DayOfMonth != 0 {
+			return ErrInvalidSchedule
+		}
+	case models.FrequencyMonthly:
+		if order.DayOfMonth == 0 {
+			order.DayOfMonth = order.StartDate.Day()
+		}
+		if order.DayOfMonth < 1 || order.DayOfMonth > 31 {
+			return ErrInvalidSchedule
+		}
+	default:
+		return ErrInvalidFrequency
+	}
+
+	first := firstOccurrence(order)
+	if order.EndDate != nil && first.After(*order.EndDate) {
+		return ErrInvalidSchedule
+	}
+	order.NextRunDate = &first
+	order.NextAttemptAt = &first
+	order.Status = models.StandingOrderActive
+
+	return s.orderRepo.Create(order)
+}
+
+func (s *StandingOrderService) GetOrders(userID uint) ([]models.StandingOrder, error) {
+	return s.orderRepo.GetByUserID(userID)
+}
+
+// Поручение вместе с историей попыток исполнения
+func (s *StandingOrderService) GetOrder(userID, orderID uint) (*models.StandingOrder, []models.StandingOrderRun, error) {
+	order, err := s.orderRepo.GetByIDAndUser(orderID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	runs, err := s.orderRepo.GetRuns(order.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return order, runs, nil
+}
+
+func (s *StandingOrderService) Cancel(userID, orderID uint) error {
+	return s.orderRepo.Cancel(orderID, userID)
+}
+
+// Исполнение наступивших поручений; вызывается периодически из main
+func (s *StandingOrderService) ExecuteDue() error {
+	now := time.Now()
+	orders, err := s.orderRepo.GetDue(now, standingOrderBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for i := range orders {
+		if err := s.execute(&orders[i], now); err != nil {
+			s.logger.WithError(err).Errorf("Standing order %d execution failed", orders[i].ID)
+		}
+	}
+	return nil
+}
+
+func (s *StandingOrderService) execute(order *models.StandingOrder, now time.Time) error {
+	// Время захвата сверяется при записи успешного перевода, поэтому
+	// усекается до точности TIMESTAMP
+	claimedUntil := now.Add(standingOrderClaimTimeout).Truncate(time.Microsecond)
+	claimed, err := s.orderRepo.Claim(order.ID, now, claimedUntil)
+	if err != nil || !claimed {
+		return err
+	}
+
+	order.Attempt++
+	run := &models.StandingOrderRun{
+		OrderID:      order.ID,
+		ScheduledFor: *order.NextRunDate,
+		Attempt:      order.Attempt,
+		Status:       models.RunSucceeded,
+	}
+
+	// Перевод и запись об исполнении фиксируются одной транзакцией: иначе
+	// сбой между ними оставил бы next_run_date прежним и после истечения
+	// захвата деньги ушли бы повторно
+	succeeded := *order
+	advance(&succeeded)
+	_, err = s.accountService.TransferWith(order.FromAccountID, order.ToAccountID, order.Amount,
+		func(tx *sql.Tx, transaction *models.Transaction) error {
+			run.TransactionID = transaction.ID
+			return s.orderRepo.SaveRunTx(tx, &succeeded, run, &claimedUntil)
+		})
+	run.TransactionID = 0
+	switch {
+	case err == nil:
+		*order = succeeded
+		return nil
+	case errors.Is(err, repositories.ErrStandingOrderClaimLost):
+		// Поручение перехватил другой исполнитель; перевод откатан
+		return err
+	case errors.Is(err, ErrInsufficientFunds):
+		run.Status = models.RunFailed
+		run.Error = err.Error()
+		retryAt := now.Add(standingOrderRetryBase << (order.Attempt - 1))
+		next, ok := nextOccurrence(order, *order.NextRunDate)
+		if order.Attempt >= standingOrderMaxAttempts || (ok && !retryAt.Before(next)) {
+			advance(order)
+		} else {
+			order.NextAttemptAt = &retryAt
+		}
+	default:
+		// Прочие ошибки (закрытый счет, нет курса) повторами не исправить
+		run.Status = models.RunFailed
+		run.Error = err.Error()
+		advance(order)
+	}
+
+	return s.orderRepo.SaveRun(order, run)
+}
+
+// Переход к следующему исполнению после успеха или исчерпания попыток
+func advance(order *models.StandingOrder) {
+	order.RunsCount++
+	order.Attempt = 0
+
+	next, ok := nextOccurrence(order, *order.NextRunDate)
+	if !ok || (order.MaxRuns > 0 && order.RunsCount >= order.MaxRuns) ||
+		(order.EndDate != nil && next.After(*order.EndDate)) {
+		order.Status = models.StandingOrderCompleted
+		order.NextRunDate = nil
+		order.NextAttemptAt = nil
+		return
+	}
+	order.NextRunDate = &next
+	order.NextAttemptAt = &next
+}
+
+func firstOccurrence(order *models.StandingOrder) time.Time {
+	if order.Frequency != models.FrequencyMonthly {
+		return order.StartDate
+	}
+	y, m, _ := order.StartDate.Date()
+	first := monthlyDate(y, m, order.DayOfMonth)
+	if first.Before(order.StartDate) {
+		first = monthlyDate(y, m+1, order.DayOfMonth)
+	}
+	return first
+}
+
+// Дата исполнения, следующая за current; false для однократного поручения
+func nextOccurrence(order *models.StandingOrder, current time.Time) (time.Time, bool) {
+	switch order.Frequency {
+	case models.FrequencyWeekly:
+		return current.AddDate(0, 0, 7), true
+	case models.FrequencyMonthly:
+		y, m, _ := current.Date()
+		return monthlyDate(y, m+1, order.DayOfMonth), true
+	}
+	return time.Time{}, false
+}
+
+// День dayOfMonth месяца, не позже его последнего дня
+func monthlyDate(year int, month time.Month, dayOfMonth int) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if dayOfMonth > last {
+		dayOfMonth = last
+	}
+	return time.Date(year, month, dayOfMonth, 0, 0, 0, 0, time.UTC)
+}
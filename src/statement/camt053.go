@@ -0,0 +1,137 @@
+package statement
+
+import (
+	"bank-service/src/money"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/beevik/etree"
+)
+
+const camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+// Выписка в формате ISO 20022 camt.053 (BankToCustomerStatement)
+func WriteCamt053(w io.Writer, st *Statement) error {
+	doc := etree.NewDocument()
+	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
+
+	root := doc.CreateElement("Document")
+	root.CreateAttr("xmlns", camt053Namespace)
+	msg := root.CreateElement("BkToCstmrStmt")
+
+	id := fmt.Sprintf("STMT-%d-%s-%s", st.Account.ID, st.From.Format("20060102"), st.LastDay().Format("20060102"))
+	created := st.GeneratedAt.Format("2006-01-02T15:04:05")
+
+	hdr := msg.CreateElement("GrpHdr")
+	hdr.CreateElement("MsgId").SetText(id)
+	hdr.CreateElement("CreDtTm").SetText(created)
+
+	stmt := msg.CreateElement("Stmt")
+	stmt.CreateElement("Id").SetText(id)
+	stmt.CreateElement("CreDtTm").SetText(created)
+	period := stmt.CreateElement("FrToDt")
+	period.CreateElement("FrDtTm").SetText(st.From.Format("2006-01-02T15:04:05"))
+	period.CreateElement("ToDtTm").SetText(st.LastDay().Format("2006-01-02T15:04:05"))
+
+	acct := stmt.CreateElement("Acct")
+	acct.CreateElement("Id").CreateElement("Othr").CreateElement("Id").SetText(strconv.FormatUint(uint64(st.Account.ID), 10))
+	acct.CreateElement("Ccy").SetText(st.Account.Currency)
+	// Банк идентифицируется БИК через клиринговую систему ЦБ (код RUCBC)
+	clr := acct.CreateElement("Svcr").CreateElement("FinInstnId").CreateElement("ClrSysMmbId")
+	clr.CreateElement("ClrSysId").CreateElement("Cd").SetText("RUCBC")
+	clr.CreateElement("MmbId").SetText(BankBIK)
+
+	// OPBD - входящий остаток, CLBD - исходящий
+	addBalance(stmt, "OPBD", st.Opening, st.Account.Currency, st.From.Format(dateLayout))
+	addBalance(stmt, "CLBD", st.Closing, st.Account.Currency, st.LastDay().Format(dateLayout))
+
+	var credits, debits int
+	for _, l := range st.Lines {
+		if l.Direction == Credit {
+			credits++
+		} else {
+			debits++
+		}
+	}
+	summary := stmt.CreateElement("TxsSummry")
+	total := summary.CreateElement("TtlNtries")
+	total.CreateElement("NbOfNtries").SetText(strconv.Itoa(len(st.Lines)))
+	totalCredit := summary.CreateElement("TtlCdtNtries")
+	totalCredit.CreateElement("NbOfNtries").SetText(strconv.Itoa(credits))
+	totalCredit.CreateElement("Sum").SetText(st.TotalCredit.String())
+	totalDebit := summary.CreateElement("TtlDbtNtries")
+	totalDebit.CreateElement("NbOfNtries").SetText(strconv.Itoa(debits))
+	totalDebit.CreateElement("Sum").SetText(st.TotalDebit.String())
+
+	for _, l := range st.Lines {
+		ref := strconv.FormatUint(uint64(l.Transaction.ID), 10)
+
+		ntry := stmt.CreateElement("Ntry")
+		ntry.CreateElement("NtryRef").SetText(ref)
+		amt := ntry.CreateElement("Amt")
+		amt.CreateAttr("Ccy", st.Account.Currency)
+		amt.SetText(l.Amount.String())
+		ntry.CreateElement("CdtDbtInd").SetText(l.Direction)
+		ntry.CreateElement("Sts").SetText("BOOK")
+		ntry.CreateElement("BookgDt").CreateElement("DtTm").SetText(l.Transaction.CreatedAt.Format("2006-01-02T15:04:05"))
+		ntry.CreateElement("ValDt").CreateElement("Dt").SetText(l.Transaction.CreatedAt.Format(dateLayout))
+		ntry.CreateElement("AcctSvcrRef").SetText(ref)
+		ntry.CreateElement("BkTxCd").CreateElement("Prtry").CreateElement("Cd").SetText(l.Transaction.Type)
+
+		tx := ntry.CreateElement("NtryDtls").CreateElement("TxDtls")
+		refs := tx.CreateElement("Refs")
+		refs.CreateElement("AcctSvcrRef").SetText(ref)
+		refs.CreateElement("EndToEndId").SetText("NOTPROVIDED")
+		if l.Transaction.ToCurrency != "" && l.Transaction.ToCurrency != l.Transaction.Currency {
+			addCurrencyExchange(tx, l)
+		}
+		if l.CounterpartyID != 0 {
+			party := "DbtrAcct"
+			if l.Direction == Debit {
+				party = "CdtrAcct"
+			}
+			tx.CreateElement("RltdPties").CreateElement(party).CreateElement("Id").CreateElement("Othr").CreateElement("Id").
+				SetText(strconv.FormatUint(uint64(l.CounterpartyID), 10))
+		}
+	}
+
+	doc.Indent(2)
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+func addBalance(stmt *etree.Element, code string, amount money.Amount, currency, date string) {
+	bal := stmt.CreateElement("Bal")
+	bal.CreateElement("Tp").CreateElement("CdOrPrtry").CreateElement("Cd").SetText(code)
+
+	indicator := Credit
+	if amount < 0 {
+		indicator = Debit
+		amount = -amount
+	}
+	amt := bal.CreateElement("Amt")
+	amt.CreateAttr("Ccy", currency)
+	amt.SetText(amount.String())
+	bal.CreateElement("CdtDbtInd").SetText(indicator)
+	bal.CreateElement("Dt").CreateElement("Dt").SetText(date)
+}
+
+// Суммы операции в обеих валютах и курс конвертации
+func addCurrencyExchange(tx *etree.Element, l Line) {
+	t := l.Transaction
+	amounts := tx.CreateElement("AmtDtls")
+
+	instd := amounts.CreateElement("InstdAmt").CreateElement("Amt")
+	instd.CreateAttr("Ccy", t.Currency)
+	instd.SetText(t.Amount.String())
+
+	cntrVal := amounts.CreateElement("CntrValAmt")
+	amt := cntrVal.CreateElement("Amt")
+	amt.CreateAttr("Ccy", t.ToCurrency)
+	amt.SetText(t.ToAmount.String())
+	xchg := cntrVal.CreateElement("CcyXchg")
+	xchg.CreateElement("SrcCcy").SetText(t.Currency)
+	xchg.CreateElement("TrgtCcy").SetText(t.ToCurrency)
+	xchg.CreateElement("XchgRate").SetText(strconv.FormatFloat(t.ExchangeRate, 'f', -1, 64))
+}
@@ -0,0 +1,55 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+func WriteCSV(w io.Writer, st *Statement) error {
+	cw := csv.NewWriter(w)
+
+	records := [][]string{
+		{"bank", BankName},
+		{"account", strconv.FormatUint(uint64(st.Account.ID), 10)},
+		{"currency", st.Account.Currency},
+		{"period_from", st.From.Format(dateLayout)},
+		{"period_to", st.LastDay().Format(dateLayout)},
+		{"opening_balance", st.Opening.String()},
+		{},
+		{"transaction_id", "date", "type", "direction", "amount", "counterparty_account", "balance"},
+	}
+	for _, l := range st.Lines {
+		records = append(records, []string{
+			strconv.FormatUint(uint64(l.Transaction.ID), 10),
+			l.Transaction.CreatedAt.Format(time.RFC3339),
+			l.Transaction.Type,
+			l.Direction,
+			l.Amount.String(),
+			counterparty(l.CounterpartyID),
+			l.Balance.String(),
+		})
+	}
+	records = append(records,
+		[]string{},
+		[]string{"total_credit", st.TotalCredit.String()},
+		[]string{"total_debit", st.TotalDebit.String()},
+		[]string{"closing_balance", st.Closing.String()},
+	)
+
+	if err := cw.WriteAll(records); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+const dateLayout = "2006-01-02"
+
+// Пустой контрагент - внешний источник или системный счет банка
+func counterparty(id uint) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(id), 10)
+}
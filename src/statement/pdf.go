@@ -0,0 +1,245 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Разметка страницы A4 в пунктах
+const (
+	pageWidth    = 595
+	pageHeight   = 842
+	marginLeft   = 40
+	marginRight  = 40
+	marginTop    = 60
+	marginBottom = 60
+	rowHeight    = 14
+	fontSize     = 9
+)
+
+// Колонки таблицы операций: левая граница; суммы выравниваются по правой
+var pdfColumns = []struct {
+	title string
+	x     float64
+	right bool
+}{
+	{"ID", marginLeft, false},
+	{"Date", 90, false},
+	{"Type", 200, false},
+	{"Dir", 300, false},
+	{"Amount", 420, true},
+	{"Counterparty", 430, false},
+	{"Balance", pageWidth - marginRight, true},
+}
+
+// Выписка в PDF 1.4 без внешних зависимостей. Используются стандартные
+// шрифты Helvetica, поэтому текст выписки - латиницей.
+func WritePDF(w io.Writer, st *Statement) error {
+	pages := layoutPages(st)
+
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 1 - каталог, 2 - дерево страниц, 3-4 - шрифты, далее пары страница/содержимое
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, content := range pages {
+		object(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 6+2*i,
+		))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// Содержимое страниц: шапка банка и реквизиты на первой, итоги на последней
+func layoutPages(st *Statement) []string {
+	var pages []string
+	var page *pdfPage
+
+	newPage := func() {
+		if page != nil {
+			pages = append(pages, page.String())
+		}
+		page = &pdfPage{y: pageHeight - marginTop}
+	}
+	ensure := func(rows int) {
+		if page.y-float64(rows*rowHeight) < marginBottom {
+			newPage()
+			page.tableHeader()
+		}
+	}
+
+	newPage()
+	page.text("F2", 16, marginLeft, page.y, BankName)
+	page.text("F1", fontSize, marginLeft, page.y-16, "BIK "+BankBIK)
+	page.y -= 44
+	page.text("F2", 13, marginLeft, page.y, "Account statement")
+	page.y -= 22
+
+	details := [][2]string{
+		{"Account", strconv.FormatUint(uint64(st.Account.ID), 10)},
+		{"Currency", st.Account.Currency},
+		{"Period", st.From.Format(dateLayout) + " - " + st.LastDay().Format(dateLayout)},
+		{"Generated", st.GeneratedAt.Format("2006-01-02 15:04:05")},
+		{"Opening balance", st.Opening.String()},
+	}
+	for _, d := range details {
+		page.text("F2", fontSize+1, marginLeft, page.y, d[0]+":")
+		page.text("F1", fontSize+1, 150, page.y, d[1])
+		page.y -= rowHeight
+	}
+	page.y -= rowHeight
+	page.tableHeader()
+
+	for _, l := range st.Lines {
+		ensure(1)
+		page.row([]string{
+			strconv.FormatUint(uint64(l.Transaction.ID), 10),
+			l.Transaction.CreatedAt.Format("2006-01-02 15:04"),
+			l.Transaction.Type,
+			l.Direction,
+			signed(l),
+			counterparty(l.CounterpartyID),
+			l.Balance.String(),
+		})
+	}
+
+	ensure(5)
+	page.y -= rowHeight / 2
+	page.line(page.y + rowHeight - 4)
+	totals := [][2]string{
+		{"Total credit", st.TotalCredit.String()},
+		{"Total debit", st.TotalDebit.String()},
+		{"Closing balance", st.Closing.String()},
+	}
+	for _, t := range totals {
+		page.text("F2", fontSize+1, marginLeft, page.y, t[0]+":")
+		page.textRight("F1", fontSize+1, pageWidth-marginRight, page.y, t[1]+" "+st.Account.Currency)
+		page.y -= rowHeight
+	}
+	pages = append(pages, page.String())
+
+	// Нумерация страниц известна только после раскладки
+	for i := range pages {
+		footer := &pdfPage{}
+		footer.textRight("F1", fontSize-1, pageWidth-marginRight, marginBottom/2, fmt.Sprintf("Page %d of %d", i+1, len(pages)))
+		pages[i] += footer.String()
+	}
+	return pages
+}
+
+func signed(l Line) string {
+	if l.Direction == Debit {
+		return "-" + l.Amount.String()
+	}
+	return l.Amount.String()
+}
+
+type pdfPage struct {
+	ops strings.Builder
+	y   float64
+}
+
+func (p *pdfPage) String() string {
+	return p.ops.String()
+}
+
+func (p *pdfPage) text(font string, size, x, y float64, s string) {
+	fmt.Fprintf(&p.ops, "BT /%s %g Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfEscape(s))
+}
+
+func (p *pdfPage) textRight(font string, size, right, y float64, s string) {
+	p.text(font, size, right-textWidth(s, size), y, s)
+}
+
+func (p *pdfPage) line(y float64) {
+	fmt.Fprintf(&p.ops, "%d %.2f m %d %.2f l S\n", marginLeft, y, pageWidth-marginRight, y)
+}
+
+func (p *pdfPage) tableHeader() {
+	for _, c := range pdfColumns {
+		if c.right {
+			p.textRight("F2", fontSize, c.x, p.y, c.title)
+		} else {
+			p.text("F2", fontSize, c.x, p.y, c.title)
+		}
+	}
+	p.line(p.y - 4)
+	p.y -= rowHeight + 2
+}
+
+func (p *pdfPage) row(cells []string) {
+	for i, c := range pdfColumns {
+		if c.right {
+			p.textRight("F1", fontSize, c.x, p.y, cells[i])
+		} else {
+			p.text("F1", fontSize, c.x, p.y, cells[i])
+		}
+	}
+	p.y -= rowHeight
+}
+
+// Экранирование строки PDF; символы вне ASCII заменяются на '?'
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Ширина строки Helvetica в пунктах (метрики AFM, 1/1000 кегля)
+// для выравнивания чисел по правому краю
+func textWidth(s string, size float64) float64 {
+	var units int
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			units += 556
+		case r == '.' || r == ' ':
+			units += 278
+		case r == '-':
+			units += 333
+		case r >= 'A' && r <= 'Z':
+			units += 667
+		default:
+			units += 556
+		}
+	}
+	return float64(units) * size / 1000
+}
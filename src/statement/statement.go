@@ -0,0 +1,79 @@
+package statement
+
+import (
+	"bank-service/src/models"
+	"bank-service/src/money"
+	"time"
+)
+
+// Реквизиты банка в шапке выписки
+const (
+	BankName = "Bank Service"
+	BankBIK  = "044525000" // БИК в справочнике ЦБ
+)
+
+// Направление движения по счету
+const (
+	Credit = "CRDT" // зачисление
+	Debit  = "DBIT" // списание
+)
+
+// Выписка по счету за период [From, To). Суммы - в валюте счета.
+type Statement struct {
+	Account     models.Account
+	From, To    time.Time
+	Opening     money.Amount
+	Closing     money.Amount
+	TotalCredit money.Amount
+	TotalDebit  money.Amount
+	Lines       []Line
+	GeneratedAt time.Time
+}
+
+type Line struct {
+	Transaction    models.Transaction
+	Direction      string
+	Amount         money.Amount
+	CounterpartyID uint
+	Balance        money.Amount // остаток после операции
+}
+
+// Сборка выписки по входящему остатку и операциям периода в порядке проведения
+func Build(account models.Account, from, to time.Time, opening money.Amount, transactions []models.Transaction) *Statement {
+	st := &Statement{
+		Account:     account,
+		From:        from,
+		To:          to,
+		Opening:     opening,
+		Closing:     opening,
+		GeneratedAt: time.Now(),
+	}
+
+	for _, t := range transactions {
+		line := Line{Transaction: t}
+		if t.ToAccountID == account.ID {
+			line.Direction = Credit
+			line.Amount = t.ToAmount
+			if line.Amount == 0 {
+				line.Amount = t.Amount
+			}
+			line.CounterpartyID = t.FromAccountID
+			st.Closing += line.Amount
+			st.TotalCredit += line.Amount
+		} else {
+			line.Direction = Debit
+			line.Amount = t.Amount
+			line.CounterpartyID = t.ToAccountID
+			st.Closing -= line.Amount
+			st.TotalDebit += line.Amount
+		}
+		line.Balance = st.Closing
+		st.Lines = append(st.Lines, line)
+	}
+	return st
+}
+
+// Последний день периода для отображения: To не включается в выписку
+func (st *Statement) LastDay() time.Time {
+	return st.To.Add(-time.Nanosecond)
+}